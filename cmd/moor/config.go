@@ -0,0 +1,386 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/walles/moor/v2/internal"
+)
+
+// Settings from the config file. Example config file:
+//
+//	# Any command line option, without the leading dashes
+//	style = "monokai"
+//	no-linenumbers = true
+//
+//	# Extra options for files with names matching a pattern. With multiple
+//	# files, sections apply only if they match all of them.
+//	[files."*.md"]
+//	wrap = true
+//
+//	# Make the key on the left act like the key on the right, or bind key
+//	# sequences to actions listed on the help screen
+//	[keys]
+//	j = "down"
+//	k = "up"
+//	gg = "scroll-to-top"
+//
+// Precedence, highest first: command line, the MOOR environment variable,
+// [files] sections in the config file, the rest of the config file.
+type config struct {
+	// Where we read this config from. Empty if we don't have a config file.
+	fileName string
+
+	// Command line option names without dashes, mapped to their values
+	options map[string]any
+
+	// File name patterns mapped to options for matching files
+	files map[string]map[string]any
+
+	keys map[string]string
+}
+
+// Where we look for the config file, following the XDG Base Directory
+// Specification:
+// https://specifications.freedesktop.org/basedir-spec/latest/#variables
+//
+// Returns an empty string if we can't figure out a good location.
+func configFileName() string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" || !filepath.IsAbs(configHome) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(configHome, "moor", "config.toml")
+}
+
+// A missing config file is not an error, it just means an empty config
+func loadConfig(fileName string) (*config, error) {
+	returnMe := &config{
+		options: map[string]any{},
+		files:   map[string]map[string]any{},
+		keys:    map[string]string{},
+	}
+	if fileName == "" {
+		return returnMe, nil
+	}
+
+	contents, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return returnMe, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	returnMe.fileName = fileName
+
+	var parsed map[string]any
+	_, err = toml.Decode(string(contents), &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	for name, value := range parsed {
+		switch name {
+		case "files":
+			files, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: [files] must be a table, like [files.\"*.md\"]", fileName)
+			}
+			for pattern, options := range files {
+				_, err := filepath.Match(pattern, "")
+				if err != nil {
+					return nil, fmt.Errorf("%s: [files.%q]: %w", fileName, pattern, err)
+				}
+
+				optionsTable, ok := options.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("%s: [files.%q] must be a table", fileName, pattern)
+				}
+				returnMe.files[pattern] = optionsTable
+			}
+
+		case "keys":
+			keys, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: [keys] must be a table", fileName)
+			}
+			for key, actsLike := range keys {
+				actsLikeString, ok := actsLike.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s: [keys]: %s must be a string, like %s = \"down\"", fileName, key, key)
+				}
+				returnMe.keys[key] = actsLikeString
+			}
+
+		default:
+			returnMe.options[name] = value
+		}
+	}
+
+	return returnMe, nil
+}
+
+// Turns config file options into command line arguments. Returns an error if
+// there are options that our flagSet doesn't know about.
+func optionsToArgs(options map[string]any, flagSet *flag.FlagSet, where string) ([]string, error) {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := make([]string, 0, len(options))
+	for _, name := range names {
+		if flagSet.Lookup(name) == nil {
+			return nil, fmt.Errorf("%s: unknown option %q, run \"moor --help\" for a list", where, name)
+		}
+
+		var valueString string
+		switch value := options[name].(type) {
+		case bool:
+			valueString = strconv.FormatBool(value)
+		case int64:
+			valueString = strconv.FormatInt(value, 10)
+		case string:
+			valueString = value
+		default:
+			return nil, fmt.Errorf("%s: %s must be a boolean, a number or a string", where, name)
+		}
+
+		args = append(args, "--"+name+"="+valueString)
+	}
+
+	return args, nil
+}
+
+// Command line arguments for all options from the top level of the config file
+func (c *config) optionArgs(flagSet *flag.FlagSet) ([]string, error) {
+	return optionsToArgs(c.options, flagSet, c.fileName)
+}
+
+// Command line arguments from [files."pattern"] sections matching the input
+// file names. Patterns are matched against file names without their
+// directories.
+//
+// Options are decided once at startup, so with multiple input files, only
+// sections matching all of them apply. Sections matching only some of the
+// files are returned as the third value, so that the user can be warned.
+//
+// The second return value describes which sections matched, for
+// --print-config.
+func (c *config) fileArgs(flagSet *flag.FlagSet, inputFileNames []string) ([]string, string, []string, error) {
+	if len(inputFileNames) == 0 {
+		return nil, "", nil, nil
+	}
+
+	patterns := make([]string, 0, len(c.files))
+	for pattern := range c.files {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	args := []string{}
+	matching := []string{}
+	skipped := []string{}
+	for _, pattern := range patterns {
+		matchCount := 0
+		for _, inputFileName := range inputFileNames {
+			matches, _ := filepath.Match(pattern, filepath.Base(inputFileName))
+			if matches {
+				matchCount++
+			}
+		}
+
+		section := fmt.Sprintf("[files.%q]", pattern)
+		if matchCount == 0 {
+			continue
+		}
+		if matchCount < len(inputFileNames) {
+			skipped = append(skipped, section)
+			continue
+		}
+
+		where := fmt.Sprintf("%s: %s", c.fileName, section)
+		patternArgs, err := optionsToArgs(c.files[pattern], flagSet, where)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		args = append(args, patternArgs...)
+		matching = append(matching, section)
+	}
+
+	return args, strings.Join(matching, ", "), skipped, nil
+}
+
+// Key remaps like j = "down", and key bindings like gg = "scroll-to-top".
+//
+// Key bindings are returned with their keys formatted by KeySequence.String().
+func (c *config) keyBindings() (map[internal.KeyPress]internal.KeyPress, map[string]string, error) {
+	keyRemaps := map[internal.KeyPress]internal.KeyPress{}
+	keyBindings := map[string]string{}
+	for keys, value := range c.keys {
+		if internal.IsAction(value) {
+			sequence, err := internal.ParseKeySequence(keys)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: [keys]: %w", c.fileName, err)
+			}
+
+			keyBindings[sequence.String()] = value
+			continue
+		}
+
+		from, err := internal.ParseKeyPress(keys)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: [keys]: %w", c.fileName, err)
+		}
+
+		to, err := internal.ParseKeyPress(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: [keys]: %q is not an action from the help screen (press 'h' in moor), and %w", c.fileName, value, err)
+		}
+
+		keyRemaps[from] = to
+	}
+
+	return keyRemaps, keyBindings, nil
+}
+
+// Some command line arguments, and where they came from
+type configLayer struct {
+	source string
+	args   []string
+}
+
+// Parse all layers in order, so that later layers override earlier ones
+func parseLayers(flagSet *flag.FlagSet, layers []configLayer) error {
+	allArgs := []string{}
+	for _, layer := range layers {
+		allArgs = append(allArgs, layer.args...)
+	}
+
+	return flagSet.Parse(allArgs)
+}
+
+// Find the values of all options set in some command line arguments. Option
+// parsing stops at the first non-option, just like in the flag package.
+func optionValues(flagSet *flag.FlagSet, args []string) map[string]string {
+	values := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") || arg == "-" {
+			break
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		value, hasValue := "", false
+		if equals := strings.Index(name, "="); equals >= 0 {
+			name, value, hasValue = name[:equals], name[equals+1:], true
+		}
+
+		option := flagSet.Lookup(name)
+		if option == nil {
+			continue
+		}
+
+		if !hasValue {
+			boolFlag, isBoolFlag := option.Value.(interface{ IsBoolFlag() bool })
+			if isBoolFlag && boolFlag.IsBoolFlag() {
+				value = "true"
+			} else if i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+		}
+
+		values[name] = value
+	}
+
+	return values
+}
+
+// Print the effective configuration in config file format, annotated with
+// where each value came from.
+func printConfig(output io.Writer, flagSet *flag.FlagSet, cfg *config, layers []configLayer) {
+	if cfg.fileName == "" {
+		fmt.Fprintf(output, "# No config file, create one at %s\n", configFileName()) //nolint:errcheck
+	} else {
+		fmt.Fprintf(output, "# Config file: %s\n", cfg.fileName) //nolint:errcheck
+	}
+	fmt.Fprintln(output, "#")                                                                             //nolint:errcheck
+	fmt.Fprintln(output, "# Precedence, highest first: command line, environment, [files], config file.") //nolint:errcheck
+	fmt.Fprintln(output)                                                                                  //nolint:errcheck
+
+	values := map[string]string{}
+	sources := map[string]string{}
+	for _, layer := range layers {
+		for name, value := range optionValues(flagSet, layer.args) {
+			values[name] = value
+			sources[name] = layer.source
+		}
+	}
+
+	flagSet.VisitAll(func(option *flag.Flag) {
+		switch option.Name {
+		case "print-config", "version", "no-reformat":
+			// Not configuration
+			return
+		}
+
+		value, isSet := values[option.Name]
+		if !isSet {
+			if option.DefValue == "" {
+				fmt.Fprintf(output, "# %s: default\n", option.Name) //nolint:errcheck
+				return
+			}
+
+			fmt.Fprintf(output, "%s = %s  # default\n", option.Name, tomlValue(option.DefValue)) //nolint:errcheck
+			return
+		}
+
+		fmt.Fprintf(output, "%s = %s  # %s\n", option.Name, tomlValue(value), sources[option.Name]) //nolint:errcheck
+	})
+
+	if len(cfg.keys) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(cfg.keys))
+	for key := range cfg.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintln(output)           //nolint:errcheck
+	fmt.Fprintln(output, "[keys]") //nolint:errcheck
+	for _, key := range keys {
+		fmt.Fprintf(output, "%s = %s\n", strconv.Quote(key), strconv.Quote(cfg.keys[key])) //nolint:errcheck
+	}
+}
+
+// Render a value for a config file. Numbers and booleans are left unquoted.
+func tomlValue(value string) string {
+	if value == "true" || value == "false" {
+		return value
+	}
+
+	_, err := strconv.ParseInt(value, 10, 64)
+	if err == nil {
+		return value
+	}
+
+	return strconv.Quote(value)
+}
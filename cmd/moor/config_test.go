@@ -0,0 +1,220 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/walles/moor/v2/internal"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+// Write a config file into a temporary XDG_CONFIG_HOME
+func writeConfigForTesting(t *testing.T, contents string) string {
+	t.Helper()
+
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("MOOR", "")
+	t.Setenv("MOAR", "")
+
+	fileName := filepath.Join(configHome, "moor", "config.toml")
+	assert.NilError(t, os.MkdirAll(filepath.Dir(fileName), 0o700))
+	assert.NilError(t, os.WriteFile(fileName, []byte(contents), 0o600))
+
+	assert.Equal(t, fileName, configFileName())
+	return fileName
+}
+
+func pagerFromArgsForTesting(t *testing.T, args ...string) *internal.Pager {
+	t.Helper()
+
+	pager, _, _, _, _, err := pagerFromArgs(
+		append([]string{""}, args...),
+		func(_ twin.MouseMode, _ twin.ColorCount) (twin.Screen, error) {
+			return twin.NewFakeScreen(80, 24), nil
+		},
+		false, // stdin is redirected
+		false, // stdout is redirected
+	)
+	assert.NilError(t, err)
+	assert.Assert(t, pager != nil)
+
+	return pager
+}
+
+func TestLoadMissingConfig(t *testing.T) {
+	cfg, err := loadConfig(filepath.Join(t.TempDir(), "does-not-exist.toml"))
+	assert.NilError(t, err)
+	assert.Equal(t, "", cfg.fileName)
+	assert.Equal(t, 0, len(cfg.options))
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	writeConfigForTesting(t, `
+wrap = true
+shift = 4
+no-statusbar = true
+
+[files."*.go"]
+shift = 8
+`)
+
+	// Config file settings, with the *.go section on top
+	pager := pagerFromArgsForTesting(t, "moor.go")
+	assert.Equal(t, true, pager.WrapLongLines)
+	assert.Equal(t, false, pager.ShowStatusBar)
+	assert.Equal(t, 8, pager.SideScrollAmount)
+
+	// *.go section doesn't apply to other files
+	pager = pagerFromArgsForTesting(t, "../../README.md")
+	assert.Equal(t, 4, pager.SideScrollAmount)
+
+	// With multiple files, sections apply only if they match all of them
+	pager = pagerFromArgsForTesting(t, "moor.go", "config.go")
+	assert.Equal(t, 8, pager.SideScrollAmount)
+	pager = pagerFromArgsForTesting(t, "moor.go", "../../README.md")
+	assert.Equal(t, 4, pager.SideScrollAmount)
+	pager = pagerFromArgsForTesting(t, "../../README.md", "moor.go")
+	assert.Equal(t, 4, pager.SideScrollAmount)
+
+	// The environment beats the config file
+	t.Setenv("MOOR", "--shift=5 --wrap=false")
+	pager = pagerFromArgsForTesting(t, "moor.go")
+	assert.Equal(t, false, pager.WrapLongLines)
+	assert.Equal(t, 5, pager.SideScrollAmount)
+
+	// The command line beats the environment
+	pager = pagerFromArgsForTesting(t, "--shift", "6", "moor.go")
+	assert.Equal(t, 6, pager.SideScrollAmount)
+	assert.Equal(t, false, pager.ShowStatusBar)
+}
+
+func TestConfigUnknownOption(t *testing.T) {
+	writeConfigForTesting(t, "wrap = true\nno-such-option = true\n")
+
+	// Broken config files should be ignored rather than stopping moor
+	pager := pagerFromArgsForTesting(t, "moor.go")
+	assert.Equal(t, false, pager.WrapLongLines)
+}
+
+func TestConfigBadValue(t *testing.T) {
+	writeConfigForTesting(t, "wrap = true\nshift = 0\n")
+
+	// Nothing from the broken config file should apply
+	pager := pagerFromArgsForTesting(t, "moor.go")
+	assert.Equal(t, false, pager.WrapLongLines)
+	assert.Equal(t, 16, pager.SideScrollAmount)
+}
+
+func TestConfigUnparsable(t *testing.T) {
+	writeConfigForTesting(t, "this is not TOML\n")
+
+	pager := pagerFromArgsForTesting(t, "--shift=5", "moor.go")
+	assert.Equal(t, 5, pager.SideScrollAmount)
+}
+
+func TestConfigKeyRemaps(t *testing.T) {
+	writeConfigForTesting(t, `
+[keys]
+j = "down"
+"ctrl-f" = "pagedown"
+x = "q"
+`)
+
+	pager := pagerFromArgsForTesting(t, "moor.go")
+	assert.DeepEqual(t, map[internal.KeyPress]internal.KeyPress{
+		{Rune: 'j'}:    {KeyCode: twin.KeyDown},
+		{Rune: '\x06'}: {KeyCode: twin.KeyPgDown},
+		{Rune: 'x'}:    {Rune: 'q'},
+	}, pager.KeyRemaps)
+}
+
+func TestConfigKeyBindings(t *testing.T) {
+	writeConfigForTesting(t, `
+[keys]
+j = "scroll-up"
+"z z" = "toggle-wrap"
+`)
+
+	pager := pagerFromArgsForTesting(t, "moor.go")
+	assert.Equal(t, "scroll-up", pager.BoundAction("j"))
+	assert.Equal(t, "toggle-wrap", pager.BoundAction("zz"))
+	assert.Equal(t, 0, len(pager.KeyRemaps))
+
+	// Default bindings should still be there
+	assert.Equal(t, "scroll-to-top", pager.BoundAction("gg"))
+}
+
+func TestConfigKeyBindings_UnknownAction(t *testing.T) {
+	writeConfigForTesting(t, `
+[keys]
+j = "scrol-down"
+`)
+
+	cfg, err := loadConfig(configFileName())
+	assert.NilError(t, err)
+	_, _, err = cfg.keyBindings()
+	assert.ErrorContains(t, err, `"scrol-down" is not an action`)
+
+	// The broken config file should be ignored
+	pager := pagerFromArgsForTesting(t, "moor.go")
+	assert.Equal(t, 0, len(pager.KeyRemaps))
+}
+
+func TestOptionValues(t *testing.T) {
+	flagSet := flag.NewFlagSet("", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	flagSet.Bool("wrap", false, "")
+	flagSet.String("style", "", "")
+
+	assert.DeepEqual(t,
+		map[string]string{"wrap": "true", "style": "monokai"},
+		optionValues(flagSet, []string{"--wrap", "-style", "monokai", "file.txt", "--style=native"}))
+	assert.DeepEqual(t,
+		map[string]string{"wrap": "false", "style": "native"},
+		optionValues(flagSet, []string{"--wrap=false", "--style=native"}))
+}
+
+func TestPrintConfig(t *testing.T) {
+	configFile := writeConfigForTesting(t, `
+wrap = true
+[keys]
+j = "down"
+`)
+	t.Setenv("MOOR", "--style=monokai")
+
+	cfg, err := loadConfig(configFile)
+	assert.NilError(t, err)
+
+	flagSet := flag.NewFlagSet("", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	flagSet.Bool("wrap", false, "")
+	flagSet.String("style", "", "")
+	flagSet.Int("shift", 16, "")
+
+	configArgs, err := cfg.optionArgs(flagSet)
+	assert.NilError(t, err)
+
+	output := strings.Builder{}
+	printConfig(&output, flagSet, cfg, []configLayer{
+		{source: "config file", args: configArgs},
+		{source: "MOOR environment variable", args: []string{"--style=monokai"}},
+	})
+
+	assert.Equal(t, output.String(), ""+
+		"# Config file: "+configFile+"\n"+
+		"#\n"+
+		"# Precedence, highest first: command line, environment, [files], config file.\n"+
+		"\n"+
+		"shift = 16  # default\n"+
+		"style = \"monokai\"  # MOOR environment variable\n"+
+		"wrap = true  # config file\n"+
+		"\n"+
+		"[keys]\n"+
+		"\"j\" = \"down\"\n")
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -152,7 +153,7 @@ func parseUnprintableStyle(styleOption string) (textstyles.UnprintableStyleT, er
 func parseScrollHint(scrollHint string) (twin.StyledRune, error) {
 	scrollHint = strings.ReplaceAll(scrollHint, "ESC", "\x1b")
 	hintAsLine := reader.NewLine(scrollHint)
-	parsedTokens := hintAsLine.HighlightedTokens(twin.StyleDefault, nil, nil, nil).StyledRunes
+	parsedTokens := hintAsLine.HighlightedTokens(twin.StyleDefault, nil, nil, nil, nil).StyledRunes
 	if len(parsedTokens) == 1 {
 		return parsedTokens[0], nil
 	}
@@ -188,12 +189,22 @@ func parseMouseMode(mouseMode string) (twin.MouseMode, error) {
 	return twin.MouseModeAuto, fmt.Errorf("Valid modes are auto, select and scroll")
 }
 
-func pumpToStdout(inputFilenames ...string) error {
+// Archive members are looked up in archiveMembers, by input file name
+func pumpToStdout(archiveMembers map[string]reader.ArchiveMemberPath, inputFilenames ...string) error {
 	if len(inputFilenames) > 0 {
 		// If we get both redirected stdin and an input filenames, should only
 		// copy the files and ignore stdin, because that's how less works.
 		for _, inputFilename := range inputFilenames {
-			inputFile, _, err := reader.ZOpen(inputFilename)
+			var inputFile io.Reader
+			var err error
+			if member, isMember := archiveMembers[inputFilename]; isMember {
+				inputFile, err = reader.OpenArchiveMember(member)
+				if err == nil {
+					inputFile, err = reader.ZReader(inputFile)
+				}
+			} else {
+				inputFile, _, err = reader.ZOpen(inputFilename)
+			}
 			if err != nil {
 				return fmt.Errorf("Failed to open %s: %w", inputFilename, err)
 			}
@@ -316,6 +327,28 @@ func pagerFromArgs(
 ) (
 	*internal.Pager, twin.Screen, chroma.Style, *chroma.Formatter, bool, error,
 ) {
+	return pagerFromArgsAndConfig(args, newScreen, stdinIsRedirected, stdoutIsRedirected, configFileName())
+}
+
+// Like pagerFromArgs(), but with a config file name. An empty config file name
+// means no config file.
+func pagerFromArgsAndConfig(
+	args []string,
+	newScreen func(mouseMode twin.MouseMode, terminalColorCount twin.ColorCount) (twin.Screen, error),
+	stdinIsRedirected bool,
+	stdoutIsRedirected bool,
+	configFileName string,
+) (
+	*internal.Pager, twin.Screen, chroma.Style, *chroma.Formatter, bool, error,
+) {
+	// A broken config file shouldn't make moor unusable, not even for --help.
+	// Start over without it, on a fresh flag set so that nothing from the
+	// config file sticks.
+	withoutConfig := func(err error) (*internal.Pager, twin.Screen, chroma.Style, *chroma.Formatter, bool, error) {
+		fmt.Fprintln(os.Stderr, "WARNING: Ignoring config file:", err)
+		return pagerFromArgsAndConfig(args, newScreen, stdinIsRedirected, stdoutIsRedirected, "")
+	}
+
 	// FIXME: If we get a CTRL-C, get terminal back into a useful state before terminating
 
 	flagSet := flag.NewFlagSet("",
@@ -324,17 +357,19 @@ func pagerFromArgs(
 	flagSet.SetOutput(io.Discard) // We want to do our own printing
 
 	printVersion := flagSet.Bool("version", false, "Prints the moor version number")
+	printConfigFlag := flagSet.Bool("print-config", false, "Prints the effective configuration, from the config file, the environment and the command line")
 	debug := flagSet.Bool("debug", false, "Print debug logs after exiting")
 	trace := flagSet.Bool("trace", false, "Print trace logs after exiting")
 
 	wrap := flagSet.Bool("wrap", false, "Wrap long lines")
 	follow := flagSet.Bool("follow", false, "Follow piped input just like \"tail -f\"")
+	followName := flagSet.Bool("follow-name", false, "Like --follow, but keep following files that get truncated or replaced, just like \"tail -F\"")
 	styleOption := flagSetFunc(flagSet,
 		"style", nil,
 		"Highlighting `style` from https://xyproto.github.io/splash/docs/longer/all.html", parseStyleOption)
 	lexer := flagSetFunc(flagSet,
 		"lang", nil,
-		"File contents, used for highlighting. Mime type or file extension (\"html\"). Default is to guess by filename.", parseLexerOption)
+		"File contents, used for highlighting. Mime type or file extension (\"html\"). Default is to guess by filename, or by the first line of input.", parseLexerOption)
 	terminalFg := flagSet.Bool("terminal-fg", false, "Use terminal foreground color rather than style foreground for plain text")
 
 	defaultFormatter, err := parseColorsOption("auto")
@@ -346,8 +381,11 @@ func pagerFromArgs(
 
 	noLineNumbers := flagSet.Bool("no-linenumbers", noLineNumbersDefault(), "Hide line numbers on startup, press left arrow key to show")
 	noStatusBar := flagSet.Bool("no-statusbar", false, "Hide the status bar, toggle with '='")
-	reFormat := flagSet.Bool("reformat", false, "Reformat some input files (JSON)")
+	scrollbar := flagSet.Bool("scrollbar", false, "Show a scrollbar with search hits marked, toggle with '|'")
+	reFormat := flagSet.Bool("reformat", false, "Reformat some input files (JSON, NDJSON, XML, YAML)")
 	flagSet.Bool("no-reformat", true, "No effect, kept for compatibility. See --reformat")
+	logTemplate := flagSet.String("log-template", reader.DEFAULT_LOG_TEMPLATE, "How to show JSON log lines, {field} is replaced by that field of each JSON object")
+	noLogMode := flagSet.Bool("no-log-mode", false, "Show JSON log lines as-is rather than using --log-template")
 	quitIfOneScreen := flagSet.Bool("quit-if-one-screen", false, "Don't page if contents fits on one screen. Affected by --no-clear-on-exit-margin.")
 	noClearOnExit := flagSet.Bool("no-clear-on-exit", false, "Retain screen contents when exiting moor")
 	noClearOnExitMargin := flagSet.Int("no-clear-on-exit-margin", 1,
@@ -362,6 +400,8 @@ func pagerFromArgs(
 	scrollRightHint := flagSetFunc(flagSet, "scroll-right-hint",
 		twin.NewStyledRune('>', twin.StyleDefault.WithAttr(twin.AttrReverse)),
 		"Shown when view can scroll right. One character with optional ANSI highlighting.", parseScrollHint)
+	filterContext := flagSet.Int("filter-context", 0, "Show this many lines around each filter match, like grep -C")
+	watchBell := flagSet.Bool("watch-bell", false, "Ring the terminal bell when watching (press 'W') finds a new search hit")
 	shift := flagSetFunc(flagSet, "shift", 16, "Horizontal scroll `amount` >=1, defaults to 16", parseShiftAmount)
 	mouseMode := flagSetFunc(
 		flagSet,
@@ -371,18 +411,69 @@ func pagerFromArgs(
 		parseMouseMode,
 	)
 
+	cfg, err := loadConfig(configFileName)
+	if err != nil {
+		return withoutConfig(err)
+	}
+	configArgs, err := cfg.optionArgs(flagSet)
+	if err != nil {
+		return withoutConfig(err)
+	}
+	keyRemaps, keyBindings, err := cfg.keyBindings()
+	if err != nil {
+		return withoutConfig(err)
+	}
+
+	// Check the config file options on their own, so that problems with them
+	// aren't blamed on the command line
+	err = flagSet.Parse(configArgs)
+	if err != nil {
+		return withoutConfig(fmt.Errorf("%s: %w", configFileName, err))
+	}
+
 	// Combine flags from environment and from command line
 	flags := args[1:]
+	targetLine, cliArgs := getTargetLine(flags)
 	moorEnv := strings.TrimSpace(os.Getenv(moorEnvVarName()))
+	envArgs := strings.Fields(moorEnv)
 	if len(moorEnv) > 0 {
 		// FIXME: It would be nice if we could debug log that we're doing this,
 		// but logging is not yet set up and depends on command line parameters.
-		flags = append(strings.Fields(moorEnv), flags...)
+		envTargetLine, remainingEnvArgs := getTargetLine(envArgs)
+		envArgs = remainingEnvArgs
+		if targetLine == nil {
+			targetLine = envTargetLine
+		}
+	}
+
+	// Lowest precedence first
+	layers := []configLayer{
+		{source: "config file", args: configArgs},
+		{source: moorEnvVarName() + " environment variable", args: envArgs},
+		{source: "command line", args: cliArgs},
 	}
+	err = parseLayers(flagSet, layers)
 
-	targetLine, remainingArgs := getTargetLine(flags)
+	if err == nil {
+		// Now that we know the file names, add options for those from the
+		// config file
+		fileArgs, fileSections, skippedSections, err := cfg.fileArgs(flagSet, flagSet.Args())
+		if err != nil {
+			return withoutConfig(err)
+		}
+		for _, skipped := range skippedSections {
+			fmt.Fprintf(os.Stderr, "WARNING: %s: %s matches only some of the files, ignoring it\n", configFileName, skipped)
+		}
 
-	err = flagSet.Parse(remainingArgs)
+		if len(fileArgs) > 0 {
+			layers = slices.Insert(layers, 1, configLayer{source: "config file " + fileSections, args: fileArgs})
+			err = parseLayers(flagSet, layers)
+			if err != nil {
+				// Everything parsed fine before adding the [files] options
+				return withoutConfig(fmt.Errorf("%s: %s: %w", configFileName, fileSections, err))
+			}
+		}
+	}
 
 	if err == nil {
 		if *noClearOnExitMargin < 0 {
@@ -417,6 +508,11 @@ func pagerFromArgs(
 		return nil, nil, chroma.Style{}, nil, logsRequested, nil
 	}
 
+	if *printConfigFlag {
+		printConfig(os.Stdout, flagSet, cfg, layers)
+		return nil, nil, chroma.Style{}, nil, logsRequested, nil
+	}
+
 	log.SetLevel(log.InfoLevel)
 	if *trace {
 		log.SetLevel(log.TraceLevel)
@@ -428,19 +524,24 @@ func pagerFromArgs(
 		TimestampFormat: time.StampMicro,
 	})
 
-	if len(flagSet.Args()) > 1 && !stdoutIsRedirected {
-		fmt.Fprintln(os.Stderr, "ERROR: Expected exactly one filename, or data piped from stdin")
-		fmt.Fprintln(os.Stderr)
-		printCommandline(os.Stderr)
-		fmt.Fprintln(os.Stderr, "For help, run: \x1b[1mmoor --help\x1b[m")
-
-		os.Exit(1)
-	}
-
+	// Command line arguments like "bundle.tar.gz:logs/app.log", split once
+	// here since that means looking inside of the archive
+	archiveMembers := map[string]reader.ArchiveMemberPath{}
 	for _, inputFilename := range flagSet.Args() {
+		fileToCheck := inputFilename
+		if member, isMember := reader.SplitArchiveMemberName(inputFilename); isMember {
+			archiveMembers[inputFilename] = member
+
+			// Finding the member means walking the archive, so that's checked
+			// only when opening it. For the first file that happens before
+			// newScreen(), for the others the pager shows any error when the
+			// user switches to them.
+			fileToCheck = member.ArchiveName
+		}
+
 		// Need to check before newScreen() below, otherwise the screen
 		// will be cleared before we print the "No such file" error.
-		err := reader.TryOpen(inputFilename)
+		err := reader.TryOpen(fileToCheck)
 		if err != nil {
 			return nil, nil, chroma.Style{}, nil, logsRequested, err
 		}
@@ -455,7 +556,7 @@ func pagerFromArgs(
 	}
 
 	if stdoutIsRedirected {
-		err := pumpToStdout(flagSet.Args()...)
+		err := pumpToStdout(archiveMembers, flagSet.Args()...)
 		if err != nil {
 			return nil, nil, chroma.Style{}, nil, logsRequested, err
 		}
@@ -469,15 +570,6 @@ func pagerFromArgs(
 		panic("Invariant broken: stdout is not a terminal")
 	}
 
-	if len(flagSet.Args()) > 1 {
-		fmt.Fprintln(os.Stderr, "ERROR: Expected exactly one filename, or data piped from stdin")
-		fmt.Fprintln(os.Stderr)
-		printCommandline(os.Stderr)
-		fmt.Fprintln(os.Stderr, "For help, run: \x1b[1mmoor --help\x1b[m")
-
-		os.Exit(1)
-	}
-
 	formatter := formatters.TTY256
 	switch *terminalColorsCount {
 	case twin.ColorCount8:
@@ -488,28 +580,42 @@ func pagerFromArgs(
 		formatter = formatters.TTY16m
 	}
 
-	var readerImpl *reader.ReaderImpl
+	var firstReader *reader.ReaderImpl
 	shouldFormat := *reFormat
+	fileOptions := reader.ReaderOptions{Lexer: *lexer, ShouldFormat: shouldFormat, FollowName: *followName, LogTemplate: *logTemplate, NoLogMode: *noLogMode}
+	openFile := func(inputFilename string, options reader.ReaderOptions) (*reader.ReaderImpl, error) {
+		if member, isMember := archiveMembers[inputFilename]; isMember {
+			return reader.NewFromArchiveMember(member, formatter, options)
+		}
+		return reader.NewFromFilename(inputFilename, formatter, options)
+	}
+
+	// Files after the first one are opened when the user switches to them
+	var moreFilenames []string
 	if stdinIsRedirected {
 		// Display input pipe contents
-		readerImpl, err = reader.NewFromStream("", os.Stdin, formatter, reader.ReaderOptions{Lexer: *lexer, ShouldFormat: shouldFormat})
+		readerImpl, err := reader.NewFromStream("", os.Stdin, formatter, reader.ReaderOptions{Lexer: *lexer, ShouldFormat: shouldFormat, LogTemplate: *logTemplate, NoLogMode: *noLogMode})
 		if err != nil {
 			return nil, nil, chroma.Style{}, nil, logsRequested, err
 		}
+		firstReader = readerImpl
 	} else {
 		// Display the input file contents
-		if len(flagSet.Args()) != 1 {
-			panic("Invariant broken: Expected exactly one filename")
+		if len(flagSet.Args()) < 1 {
+			panic("Invariant broken: Expected at least one filename")
 		}
-		readerImpl, err = reader.NewFromFilename(flagSet.Args()[0], formatter, reader.ReaderOptions{Lexer: *lexer, ShouldFormat: shouldFormat})
+
+		readerImpl, err := openFile(flagSet.Args()[0], fileOptions)
 		if err != nil {
 			return nil, nil, chroma.Style{}, nil, logsRequested, err
 		}
+		firstReader = readerImpl
+		moreFilenames = flagSet.Args()[1:]
 	}
 
 	// If the user is doing "sudo something | moor" we can't show the UI until
 	// we start getting data, otherwise we'll mess up sudo's password prompt.
-	readerImpl.AwaitFirstByte()
+	firstReader.AwaitFirstByte()
 
 	// We got the first byte, this means sudo is done (if it was used) and we
 	// can set up the UI.
@@ -518,7 +624,14 @@ func pagerFromArgs(
 		// Ref: https://github.com/walles/moor/issues/149
 		log.Info("Failed to set up screen for paging, pumping to stdout instead: ", err)
 
-		readerImpl.PumpToStdout()
+		firstReader.PumpToStdout()
+		for _, inputFilename := range moreFilenames {
+			readerImpl, err := openFile(inputFilename, fileOptions)
+			if err != nil {
+				return nil, nil, chroma.Style{}, nil, logsRequested, err
+			}
+			readerImpl.PumpToStdout()
+		}
 
 		return nil, nil, chroma.Style{}, nil, logsRequested, nil
 	}
@@ -530,12 +643,26 @@ func pagerFromArgs(
 		style = **styleOption
 	}
 	log.Debug("Using style <", style.Name, ">")
-	readerImpl.SetStyleForHighlighting(style)
-
-	pager := internal.NewPager(readerImpl)
+	firstReader.SetStyleForHighlighting(style)
+
+	lazyReaders := make([]internal.LazyReader, 0, len(moreFilenames))
+	for _, inputFilename := range moreFilenames {
+		lazyReaders = append(lazyReaders, internal.LazyReader{
+			Name: inputFilename,
+			Open: func() (*reader.ReaderImpl, error) {
+				options := fileOptions
+				options.Style = &style
+				return openFile(inputFilename, options)
+			},
+		})
+	}
+
+	pager := internal.NewPagerForLazyReaders(firstReader, lazyReaders)
+	pager.LoadSearchHistory()
 	pager.WrapLongLines = *wrap
 	pager.ShowLineNumbers = !*noLineNumbers
 	pager.ShowStatusBar = !*noStatusBar
+	pager.ShowScrollbar = *scrollbar
 	pager.DeInit = !*noClearOnExit
 	pager.DeInitFalseMargin = *noClearOnExitMargin
 	pager.QuitIfOneScreen = *quitIfOneScreen
@@ -545,9 +672,18 @@ func pagerFromArgs(
 	pager.ScrollLeftHint = *scrollLeftHint
 	pager.ScrollRightHint = *scrollRightHint
 	pager.SideScrollAmount = int(*shift)
+	pager.FilterContext = max(0, *filterContext)
+	pager.WatchBell = *watchBell
+	pager.KeyRemaps = keyRemaps
+	for keys, actionName := range keyBindings {
+		err = pager.BindKeys(keys, actionName)
+		if err != nil {
+			return nil, nil, chroma.Style{}, nil, logsRequested, err
+		}
+	}
 
 	pager.TargetLine = targetLine
-	if *follow && pager.TargetLine == nil {
+	if (*follow || *followName) && pager.TargetLine == nil {
 		reallyHigh := linemetadata.IndexMax()
 		pager.TargetLine = &reallyHigh
 	}
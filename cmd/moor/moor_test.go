@@ -31,3 +31,19 @@ func TestPageOneInputFile(t *testing.T) {
 	assert.Assert(t, screen != nil)
 	assert.Assert(t, formatter != nil)
 }
+
+func TestPageTwoInputFiles(t *testing.T) {
+	pager, screen, _, formatter, _, err := pagerFromArgs(
+		[]string{"", "moor_test.go", "moor.go"},
+		func(_ twin.MouseMode, _ twin.ColorCount) (twin.Screen, error) {
+			return twin.NewFakeScreen(80, 24), nil
+		},
+		false, // stdin is redirected
+		false, // stdout is redirected
+	)
+
+	assert.NilError(t, err)
+	assert.Assert(t, pager != nil)
+	assert.Assert(t, screen != nil)
+	assert.Assert(t, formatter != nil)
+}
@@ -112,7 +112,10 @@ func printCommandline(output io.Writer) {
 
 	fmt.Fprintln(output, "Commandline: moor", strings.Join(os.Args[1:], " "))                 //nolint:errcheck
 	fmt.Fprintf(output, "Environment: %s=\"%v\"\n", envVarDescription, os.Getenv(envVarName)) //nolint:errcheck
-	fmt.Fprintln(output)                                                                      //nolint:errcheck
+	if _, err := os.Stat(configFileName()); err == nil {
+		fmt.Fprintln(output, "Config file:", configFileName()) //nolint:errcheck
+	}
+	fmt.Fprintln(output) //nolint:errcheck
 }
 
 func heading(text string, colors twin.ColorCount) string {
@@ -129,7 +132,7 @@ func printUsage(flagSet *flag.FlagSet, colors twin.ColorCount) {
 	// FIXME: Log if any printouts fail?
 
 	fmt.Println(heading("Usage", colors))
-	fmt.Println("  moor [options] <file>")
+	fmt.Println("  moor [options] <file> ...")
 	fmt.Println("  ... | moor")
 	fmt.Println("  moor < file")
 	fmt.Println()
@@ -140,6 +143,10 @@ func printUsage(flagSet *flag.FlagSet, colors twin.ColorCount) {
 	fmt.Println("More information + source code:")
 	fmt.Println("  <https://github.com/walles/moor#readme>")
 	fmt.Println()
+	fmt.Println(heading("Config File", colors))
+	fmt.Printf("  Options and key remappings are read from %s if it exists.\n", configFileName())
+	fmt.Println("  Run \"moor --print-config\" to see the effective configuration.")
+	fmt.Println()
 	fmt.Println(heading("Environment", colors))
 
 	envVarName := moorEnvVarName()
@@ -5,15 +5,20 @@ go 1.22
 toolchain go1.24.4
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/alecthomas/chroma/v2 v2.19.1-0.20250723141813-02ff9d482061
+	github.com/andybalholm/brotli v1.2.6
 	github.com/google/go-cmp v0.5.9
 	github.com/klauspost/compress v1.17.4
+	github.com/pierrec/lz4/v4 v4.1.31
 	github.com/rivo/uniseg v0.4.7
 	github.com/sirupsen/logrus v1.8.1
+	github.com/sorairolake/lzip-go v0.3.5
 	github.com/ulikunitz/xz v0.5.15
 	golang.org/x/exp v0.0.0-20240103183307-be819d1f06fc
 	golang.org/x/sys v0.1.0
 	golang.org/x/term v0.0.0-20210503060354-a79de5458b56
+	gopkg.in/yaml.v3 v3.0.1
 	gotest.tools/v3 v3.3.0
 )
 
@@ -0,0 +1,631 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Something the user can do in viewing mode, bound to one or more key
+// sequences. Users can bind more keys to actions in the [keys] section of the
+// config file.
+type action struct {
+	// Used for binding keys to this action in the config file
+	name string
+
+	// Shown on the help screen
+	description string
+
+	// Which help screen section this action should be listed in
+	section string
+
+	// Default key sequences, in ParseKeySequence() format
+	keys []string
+
+	// Most actions leave not-found mode, but finding the next or previous hit
+	// should wrap around to the other end of the document.
+	keepsNotFound bool
+
+	run func(p *Pager)
+}
+
+// Help screen sections, in the order they should be shown
+const (
+	helpSectionMiscellaneous = "Miscellaneous"
+	helpSectionMovingAround  = "Moving around"
+	helpSectionMultipleFiles = "Multiple files"
+	helpSectionFiltering     = "Filtering"
+	helpSectionSearching     = "Searching"
+	helpSectionSelecting     = "Selecting and copying"
+)
+
+// In help screen order
+var actions []action
+
+// Set up in init() since the help action refers back to this list
+func init() {
+	actions = []action{
+		{
+			name:        "quit",
+			description: "Quit, or leave the help screen",
+			section:     helpSectionMiscellaneous,
+			keys:        []string{"q", "escape"},
+			run:         (*Pager).Quit,
+		},
+		{
+			name:        "help",
+			description: "Show this help",
+			section:     helpSectionMiscellaneous,
+			keys:        []string{"h"},
+			run:         (*Pager).showHelp,
+		},
+		{
+			name:        "toggle-wrap",
+			description: "Toggle wrapping of long lines",
+			section:     helpSectionMiscellaneous,
+			keys:        []string{"w"},
+			run: func(p *Pager) {
+				p.WrapLongLines = !p.WrapLongLines
+			},
+		},
+		{
+			name:        "toggle-status-bar",
+			description: "Toggle showing the status bar at the bottom",
+			section:     helpSectionMiscellaneous,
+			keys:        []string{"="},
+			run: func(p *Pager) {
+				p.ShowStatusBar = !p.ShowStatusBar
+			},
+		},
+		{
+			name:        "toggle-scrollbar",
+			description: "Toggle showing the scrollbar on the right",
+			section:     helpSectionMiscellaneous,
+			keys:        []string{"|"},
+			run: func(p *Pager) {
+				p.ShowScrollbar = !p.ShowScrollbar
+			},
+		},
+		{
+			name:        "toggle-log-object",
+			description: "Toggle showing the full JSON object of a JSON log line",
+			section:     helpSectionMiscellaneous,
+			keys:        []string{"x"},
+			run:         (*Pager).toggleLogObject,
+		},
+		{
+			name:        "cycle-language",
+			description: "Switch highlighting to the next likely language, or to no highlighting",
+			section:     helpSectionMiscellaneous,
+			keys:        []string{"L"},
+			run: func(p *Pager) {
+				if p.isShowingHelp {
+					return
+				}
+				p.reader.CycleLanguage()
+			},
+		},
+		{
+			name:        "set-language",
+			description: "Set the language to highlight as, like with --lang",
+			section:     helpSectionMiscellaneous,
+			keys:        []string{":l"},
+			run: func(p *Pager) {
+				if p.isShowingHelp {
+					return
+				}
+				p.mode = &PagerModeSetLanguage{pager: p}
+			},
+		},
+		{
+			name:        "edit",
+			description: "Edit the file in your favorite editor",
+			section:     helpSectionMiscellaneous,
+			keys:        []string{"v"},
+			run:         handleEditingRequest,
+		},
+
+		{
+			name:        "scroll-up",
+			description: "Move up one line",
+			section:     helpSectionMovingAround,
+			// ctrl-p: https://github.com/walles/moor/issues/107#issuecomment-1328354080
+			keys: []string{"up", "k", "y", "ctrl-p"},
+			run: func(p *Pager) {
+				// Clipping is done in _Redraw()
+				p.scrollPosition = p.scrollPosition.PreviousLine(1)
+				p.handleScrolledUp()
+			},
+		},
+		{
+			name:        "scroll-down",
+			description: "Move down one line",
+			section:     helpSectionMovingAround,
+			// ctrl-n: https://github.com/walles/moor/issues/107#issuecomment-1328354080
+			keys: []string{"down", "enter", "j", "e", "ctrl-n"},
+			run: func(p *Pager) {
+				// Clipping is done in _Redraw()
+				p.scrollPosition = p.scrollPosition.NextLine(1)
+				p.handleScrolledDown()
+			},
+		},
+		{
+			name:        "page-up",
+			description: "Move up one screen",
+			section:     helpSectionMovingAround,
+			keys:        []string{"pageup", "b"},
+			run: func(p *Pager) {
+				p.scrollPosition = p.scrollPosition.PreviousLine(p.visibleHeight())
+				p.handleScrolledUp()
+			},
+		},
+		{
+			name:        "page-down",
+			description: "Move down one screen",
+			section:     helpSectionMovingAround,
+			keys:        []string{"pagedown", "f", "space"},
+			run: func(p *Pager) {
+				p.scrollPosition = p.scrollPosition.NextLine(p.visibleHeight())
+				p.handleScrolledDown()
+			},
+		},
+		{
+			name:        "half-page-up",
+			description: "Move up half a screen",
+			section:     helpSectionMovingAround,
+			// ctrl-u: https://github.com/walles/moor/issues/90
+			keys: []string{"u", "ctrl-u"},
+			run: func(p *Pager) {
+				p.scrollPosition = p.scrollPosition.PreviousLine(p.visibleHeight() / 2)
+				p.handleScrolledUp()
+			},
+		},
+		{
+			name:        "half-page-down",
+			description: "Move down half a screen",
+			section:     helpSectionMovingAround,
+			// ctrl-d: https://github.com/walles/moor/issues/90
+			keys: []string{"d", "ctrl-d"},
+			run: func(p *Pager) {
+				p.scrollPosition = p.scrollPosition.NextLine(p.visibleHeight() / 2)
+				p.handleScrolledDown()
+			},
+		},
+		{
+			name:        "scroll-to-top",
+			description: "Go to the start of the document",
+			section:     helpSectionMovingAround,
+			keys:        []string{"home", "<", "gg"},
+			run: func(p *Pager) {
+				p.scrollPosition = newScrollPosition("Pager scroll position")
+				p.handleScrolledUp()
+			},
+		},
+		{
+			name:        "scroll-to-end",
+			description: "Go to the end of the document",
+			section:     helpSectionMovingAround,
+			keys:        []string{"end", ">", "G"},
+			run:         (*Pager).scrollToEnd,
+		},
+		{
+			name:        "scroll-left",
+			description: "Scroll left, or show line numbers",
+			section:     helpSectionMovingAround,
+			keys:        []string{"left"},
+			run: func(p *Pager) {
+				p.moveRight(-p.SideScrollAmount)
+			},
+		},
+		{
+			name:        "scroll-right",
+			description: "Scroll right, or hide line numbers",
+			section:     helpSectionMovingAround,
+			keys:        []string{"right"},
+			run: func(p *Pager) {
+				p.moveRight(p.SideScrollAmount)
+			},
+		},
+		{
+			name:        "scroll-left-one",
+			description: "Scroll left one column",
+			section:     helpSectionMovingAround,
+			keys:        []string{"alt-left"},
+			run: func(p *Pager) {
+				p.moveRight(-1)
+			},
+		},
+		{
+			name:        "scroll-right-one",
+			description: "Scroll right one column",
+			section:     helpSectionMovingAround,
+			keys:        []string{"alt-right"},
+			run: func(p *Pager) {
+				p.moveRight(1)
+			},
+		},
+		{
+			name:        "go-to-line",
+			description: "Go to a specific line number",
+			section:     helpSectionMovingAround,
+			keys:        []string{"g"},
+			run: func(p *Pager) {
+				p.mode = &PagerModeGotoLine{pager: p}
+				p.setTargetLine(nil)
+			},
+		},
+		{
+			name:        "set-mark",
+			description: "Set a mark, you will be asked for a letter to label it with",
+			section:     helpSectionMovingAround,
+			keys:        []string{"m"},
+			run: func(p *Pager) {
+				p.mode = PagerModeMark{pager: p}
+				p.setTargetLine(nil)
+			},
+		},
+		{
+			name:        "jump-to-mark",
+			description: "Jump to a mark",
+			section:     helpSectionMovingAround,
+			keys:        []string{"'"},
+			run: func(p *Pager) {
+				p.mode = PagerModeJumpToMark{pager: p}
+				p.setTargetLine(nil)
+			},
+		},
+
+		{
+			name:        "next-file",
+			description: "Go to the next file",
+			section:     helpSectionMultipleFiles,
+			keys:        []string{":n"},
+			run:         (*Pager).nextFile,
+		},
+		{
+			name:        "previous-file",
+			description: "Go to the previous file",
+			section:     helpSectionMultipleFiles,
+			keys:        []string{":p"},
+			run:         (*Pager).previousFile,
+		},
+		{
+			name:        "open-member",
+			description: "Pick a member of an archive to open",
+			section:     helpSectionMultipleFiles,
+			keys:        []string{"o"},
+			run:         (*Pager).startOpeningMember,
+		},
+
+		{
+			name:        "filter",
+			description: "Start a new filter, replacing any current ones. Prefix with ! to invert",
+			section:     helpSectionFiltering,
+			keys:        []string{"&"},
+			run: func(p *Pager) {
+				if p.isShowingHelp {
+					// Filtering the help text is not supported. Feel free to work
+					// on that if you feel that's time well spent.
+					return
+				}
+
+				p.earlierFilters = nil
+				p.filterString = ""
+				p.filterPattern = nil
+				p.mode = &PagerModeFilter{pager: p}
+				p.highlightFilterMatches()
+			},
+		},
+
+		{
+			name:        "add-filter",
+			description: "Add another filter on top of the current ones. Prefix with ! to invert, | for OR",
+			section:     helpSectionFiltering,
+			keys:        []string{"+"},
+			run: func(p *Pager) {
+				if p.isShowingHelp {
+					return
+				}
+
+				// Any current filter stays, and the new one goes on top of it
+				p.pushFilter()
+				p.mode = &PagerModeFilter{pager: p}
+				p.highlightFilterMatches()
+			},
+		},
+
+		{
+			name:        "search-forward",
+			description: "Start searching",
+			section:     helpSectionSearching,
+			keys:        []string{"/"},
+			run: func(p *Pager) {
+				p.startSearch(SearchDirectionForward)
+			},
+		},
+		{
+			name:        "search-backward",
+			description: "Start searching backwards",
+			section:     helpSectionSearching,
+			keys:        []string{"?"},
+			run: func(p *Pager) {
+				p.startSearch(SearchDirectionBackward)
+			},
+		},
+		{
+			name:          "next-search-hit",
+			description:   "Find next",
+			section:       helpSectionSearching,
+			keys:          []string{"n"},
+			keepsNotFound: true,
+			run:           (*Pager).scrollToNextSearchHit,
+		},
+		{
+			name:          "previous-search-hit",
+			description:   "Find previous",
+			section:       helpSectionSearching,
+			keys:          []string{"p", "N"},
+			keepsNotFound: true,
+			run:           (*Pager).scrollToPreviousSearchHit,
+		},
+		{
+			name:        "pin-search",
+			description: "Keep highlighting the current search in a color of its own",
+			section:     helpSectionSearching,
+			keys:        []string{"*"},
+			run:         (*Pager).pinSearch,
+		},
+		{
+			name:        "pinned-highlights",
+			description: "List pinned highlights and remove them",
+			section:     helpSectionSearching,
+			keys:        []string{"H"},
+			run: func(p *Pager) {
+				p.mode = PagerModePins{pager: p}
+			},
+		},
+		{
+			name:        "watch",
+			description: "Follow the end of the input, stop at the first new search hit",
+			section:     helpSectionSearching,
+			keys:        []string{"W"},
+			run: func(p *Pager) {
+				if p.isWatching() {
+					// Keep following, just stop watching
+					p.watching = false
+					return
+				}
+				p.startWatching()
+			},
+		},
+
+		{
+			name:        "select",
+			description: "Select text to copy",
+			section:     helpSectionSelecting,
+			keys:        []string{"V"},
+			run:         (*Pager).startSelecting,
+		},
+	}
+}
+
+func findAction(name string) *action {
+	for i := range actions {
+		if actions[i].name == name {
+			return &actions[i]
+		}
+	}
+	return nil
+}
+
+// True if there is an action with this name. For validating config files.
+func IsAction(name string) bool {
+	return findAction(name) != nil
+}
+
+// A key sequence bound to an action. The sequence is parsed once when binding,
+// rather than on every key press.
+type keyBinding struct {
+	sequence   KeySequence
+	actionName string
+}
+
+// Key sequences, as formatted by KeySequence.String(), mapped to their bindings
+func defaultKeyBindings() map[string]keyBinding {
+	bindings := map[string]keyBinding{}
+	for _, action := range actions {
+		for _, keys := range action.keys {
+			sequence, err := ParseKeySequence(keys)
+			if err != nil {
+				panic(fmt.Sprintf("Bad default key sequence <%s> for %s: %v", keys, action.name, err))
+			}
+			bindings[sequence.String()] = keyBinding{sequence: sequence, actionName: action.name}
+		}
+	}
+	return bindings
+}
+
+// Bind a key sequence, in ParseKeySequence() format, to the named action.
+// Replaces any earlier binding of the same sequence.
+func (p *Pager) BindKeys(keys string, actionName string) error {
+	if !IsAction(actionName) {
+		return fmt.Errorf("unknown action <%s>", actionName)
+	}
+
+	sequence, err := ParseKeySequence(keys)
+	if err != nil {
+		return err
+	}
+
+	p.keyBindings[sequence.String()] = keyBinding{sequence: sequence, actionName: actionName}
+	return nil
+}
+
+// The name of the action bound to a key sequence in ParseKeySequence() format.
+// Empty if the sequence isn't bound.
+func (p *Pager) BoundAction(keys string) string {
+	sequence, err := ParseKeySequence(keys)
+	if err != nil {
+		return ""
+	}
+
+	return p.keyBindings[sequence.String()].actionName
+}
+
+// Handle a key press in viewing or not-found mode.
+//
+// Key presses can be the start of a multi key sequence like "gg", in which
+// case we wait for the rest of the sequence before doing anything.
+func (p *Pager) onActionKey(key KeyPress) {
+	// Mouse selections go away when you do something else
+	p.selection = nil
+
+	sequence := append(slices.Clone(p.pendingKeys), key)
+	p.pendingKeys = nil
+
+	if p.isPrefixOfBinding(sequence) {
+		p.leaveNotFound()
+		p.pendingKeys = sequence
+		return
+	}
+
+	binding, found := p.keyBindings[sequence.String()]
+	if found {
+		p.runAction(binding.actionName)
+		return
+	}
+
+	if len(sequence) == 1 {
+		p.leaveNotFound()
+		log.Debugf("Unhandled key <%s>", key)
+		return
+	}
+
+	prefixBinding, found := p.keyBindings[sequence[:len(sequence)-1].String()]
+	if !found {
+		log.Debugf("Unbound key sequence <%s>", sequence)
+		return
+	}
+
+	// Like "g" followed by "1". Go to line for the "g", and then let the
+	// go-to-line prompt have the "1".
+	p.runAction(prefixBinding.actionName)
+	p.sendToMode(key)
+}
+
+// True if there is a longer binding starting with this sequence
+func (p *Pager) isPrefixOfBinding(sequence KeySequence) bool {
+	for _, binding := range p.keyBindings {
+		bound := binding.sequence
+		if len(bound) > len(sequence) && slices.Equal(bound[:len(sequence)], sequence) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Pager) runAction(actionName string) {
+	action := findAction(actionName)
+	if action == nil {
+		// Config file bindings are validated before we get here
+		log.Warnf("Unknown action <%s>", actionName)
+		return
+	}
+
+	if !action.keepsNotFound {
+		p.leaveNotFound()
+	}
+
+	log.Tracef("Running action <%s>", action.name)
+	action.run(p)
+}
+
+func (p *Pager) leaveNotFound() {
+	if p.isNotFound() {
+		p.mode = PagerModeViewing{pager: p}
+	}
+}
+
+// What the user can type to complete the pending key sequence, for showing in
+// the footer
+func (p *Pager) pendingKeysFooter() string {
+	pending := p.pendingKeys.String()
+
+	completions := []string{}
+	for _, binding := range p.keyBindings {
+		bound := binding.sequence
+		if len(bound) != len(p.pendingKeys)+1 || !slices.Equal(bound[:len(p.pendingKeys)], p.pendingKeys) {
+			continue
+		}
+
+		completions = append(completions, fmt.Sprintf("'%s' %s", bound[len(bound)-1], binding.actionName))
+	}
+	sort.Strings(completions)
+
+	otherwise := "ESC to cancel"
+	binding, found := p.keyBindings[pending]
+	if found {
+		otherwise = "any other key " + binding.actionName
+	}
+
+	return pending + ": " + strings.Join(append(completions, otherwise), ", ")
+}
+
+// Key sequences bound to the given action. Default bindings come first, in
+// their default order.
+func (p *Pager) keysFor(action action) []string {
+	keys := []string{}
+	for boundKeys, binding := range p.keyBindings {
+		if binding.actionName == action.name {
+			keys = append(keys, boundKeys)
+		}
+	}
+
+	defaultIndices := map[string]int{}
+	for i, defaultKeys := range action.keys {
+		sequence, _ := ParseKeySequence(defaultKeys)
+		defaultIndices[sequence.String()] = i
+	}
+	defaultIndex := func(keys string) int {
+		index, isDefault := defaultIndices[keys]
+		if !isDefault {
+			return len(action.keys)
+		}
+		return index
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		iIndex := defaultIndex(keys[i])
+		jIndex := defaultIndex(keys[j])
+		if iIndex != jIndex {
+			return iIndex < jIndex
+		}
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
+
+// The keys bound to the named action, for mentioning in help texts, like "'q' /
+// ESC". Empty if no keys are bound to the action.
+func (p *Pager) keysText(actionName string) string {
+	action := findAction(actionName)
+	if action == nil {
+		return ""
+	}
+
+	quoted := []string{}
+	for _, keys := range p.keysFor(*action) {
+		if keys == "escape" {
+			// Like in the rest of the UI
+			quoted = append(quoted, "ESC")
+			continue
+		}
+		quoted = append(quoted, "'"+keys+"'")
+	}
+
+	return strings.Join(quoted, " / ")
+}
@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func newPagerForActionTesting(t *testing.T) *Pager {
+	t.Helper()
+
+	pager := NewPager(reader.NewFromTextForTesting("", strings.Repeat("line\n", 100)))
+	pager.screen = twin.NewFakeScreen(20, 10)
+
+	return pager
+}
+
+// Verify that all default bindings refer to existing actions, and that no two
+// actions share the same name
+func TestDefaultKeyBindings(t *testing.T) {
+	names := map[string]bool{}
+	for _, action := range actions {
+		assert.Assert(t, !names[action.name], "Duplicate action %s", action.name)
+		names[action.name] = true
+	}
+
+	for keys, binding := range defaultKeyBindings() {
+		assert.Assert(t, IsAction(binding.actionName), "Keys <%s> bound to unknown action %s", keys, binding.actionName)
+		assert.Equal(t, keys, binding.sequence.String())
+	}
+}
+
+func TestMultiKeySequence(t *testing.T) {
+	pager := newPagerForActionTesting(t)
+	pager.scrollPosition = pager.scrollPosition.NextLine(50)
+
+	pager.mode.onRune('g')
+	assert.Assert(t, pager.isViewing(), "Should wait for the rest of the sequence")
+	assert.Equal(t, 50, pager.lineIndex().Index())
+
+	pager.mode.onRune('g')
+	assert.Assert(t, pager.isViewing())
+	assert.Equal(t, 0, pager.lineIndex().Index())
+	assert.Equal(t, 0, len(pager.pendingKeys))
+}
+
+// A "g" followed by something else than another "g" should go to line
+func TestMultiKeySequence_PrefixAction(t *testing.T) {
+	pager := newPagerForActionTesting(t)
+
+	pager.mode.onRune('g')
+	pager.mode.onRune('4')
+	pager.mode.onRune('2')
+
+	gotoLine, isGotoLine := pager.mode.(*PagerModeGotoLine)
+	assert.Assert(t, isGotoLine)
+	assert.Equal(t, "42", gotoLine.gotoLineString)
+}
+
+// A ":" followed by something unbound should just be dropped
+func TestMultiKeySequence_Unbound(t *testing.T) {
+	pager := newPagerForActionTesting(t)
+
+	pager.mode.onRune(':')
+	assert.Assert(t, strings.Contains(pager.pendingKeysFooter(), "'n' next-file"), pager.pendingKeysFooter())
+
+	pager.mode.onKey(twin.KeyEscape)
+	assert.Assert(t, pager.isViewing())
+	assert.Assert(t, !pager.quit, "Escape should have cancelled the sequence, not quit")
+	assert.Equal(t, 0, len(pager.pendingKeys))
+}
+
+func TestRebindKeys(t *testing.T) {
+	pager := newPagerForActionTesting(t)
+	assert.NilError(t, pager.BindKeys("j", "scroll-up"))
+	assert.NilError(t, pager.BindKeys("zz", "toggle-wrap"))
+
+	pager.scrollPosition = pager.scrollPosition.NextLine(50)
+	pager.mode.onRune('j')
+	assert.Equal(t, 49, pager.lineIndex().Index())
+
+	pager.mode.onRune('z')
+	pager.mode.onRune('z')
+	assert.Assert(t, pager.WrapLongLines)
+}
+
+func TestHelpShowsLiveBindings(t *testing.T) {
+	pager := newPagerForActionTesting(t)
+	assert.NilError(t, pager.BindKeys("zz", "toggle-wrap"))
+
+	helpText := pager.helpText()
+	assert.Assert(t, strings.Contains(helpText, "  w, zz "), helpText)
+	assert.Assert(t, strings.Contains(helpText, "home, <, gg"), helpText)
+	assert.Assert(t, strings.Contains(helpText, ":n"), helpText)
+
+	pager.mode.onRune('h')
+	assert.Assert(t, pager.isShowingHelp)
+	assert.Equal(t, pager.Reader().GetLine(*pager.lineIndex()).Plain(), "Welcome to Moor, the nice pager!")
+}
+
+func TestHelpTextsShowLiveBindings(t *testing.T) {
+	pager := newPagerForActionTesting(t)
+	assert.Equal(t, "Press 'q' / ESC to exit, '/' to search, '&' to filter, 'h' for help", pager.footerHelpText())
+
+	assert.NilError(t, pager.BindKeys("?", "help"))
+	assert.NilError(t, pager.BindKeys("x", "scroll-down"))
+	assert.Equal(t, "Press 'q' / ESC to exit, '/' to search, '&' to filter, 'h' / '?' for help", pager.footerHelpText())
+
+	helpText := pager.helpText()
+	assert.Assert(t, strings.Contains(helpText, "Press (toggle-log-object) to show"), helpText)
+	assert.Assert(t, strings.Contains(helpText, "press 'o' and pick one"), helpText)
+}
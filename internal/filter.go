@@ -0,0 +1,233 @@
+package internal
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/walles/moor/v2/twin"
+)
+
+// One of possibly several stacked filters
+type filter struct {
+	// As typed by the user, including any "|" and "!" prefixes
+	filterString string
+
+	pattern *regexp.Regexp
+
+	// Show lines not matching the pattern, like "&!" in less
+	inverted bool
+
+	// Show lines matching either the earlier filters or this one, rather than
+	// lines matching both
+	or bool
+}
+
+// Parse a filter string. A "|" prefix means OR with the earlier filters, and a
+// "!" prefix means show non-matching lines. Both prefixes can be combined as
+// "|!".
+func newFilter(filterString string) filter {
+	returnMe := filter{filterString: filterString}
+
+	patternString := filterString
+	if strings.HasPrefix(patternString, "|") {
+		returnMe.or = true
+		patternString = patternString[1:]
+	}
+	if strings.HasPrefix(patternString, "!") {
+		returnMe.inverted = true
+		patternString = patternString[1:]
+	}
+
+	returnMe.pattern = toPattern(patternString)
+	return returnMe
+}
+
+// Empty filters let everything through, and don't need to be applied
+func (f filter) isActive() bool {
+	return f.pattern != nil && len(f.pattern.String()) > 0
+}
+
+func (f filter) accepts(line string) bool {
+	if !f.isActive() {
+		return true
+	}
+
+	return f.pattern.MatchString(line) != f.inverted
+}
+
+// True if all lines accepted by this filter are also accepted by the other
+// one. This happens for example when typing more characters into the filter
+// prompt.
+func (f filter) refines(other filter) bool {
+	if !f.isActive() || !other.isActive() || f.inverted != other.inverted {
+		return false
+	}
+
+	literal, foldCase, ok := asLiteral(f.pattern)
+	if !ok {
+		return false
+	}
+	otherLiteral, otherFoldCase, ok := asLiteral(other.pattern)
+	if !ok || foldCase != otherFoldCase {
+		return false
+	}
+
+	if foldCase {
+		literal = strings.ToLower(literal)
+		otherLiteral = strings.ToLower(otherLiteral)
+	}
+
+	if f.inverted {
+		// Hiding lines containing "ab" hides more than hiding lines
+		// containing "abc"
+		return strings.Contains(otherLiteral, literal)
+	}
+
+	// Lines containing "abc" also contain "ab"
+	return strings.Contains(literal, otherLiteral)
+}
+
+// If the pattern matches only one exact string, return that string, and
+// whether it matches case insensitively
+func asLiteral(pattern *regexp.Regexp) (string, bool, bool) {
+	parsed, err := syntax.Parse(pattern.String(), syntax.Perl)
+	if err != nil {
+		return "", false, false
+	}
+
+	if parsed.Op != syntax.OpLiteral {
+		return "", false, false
+	}
+
+	return string(parsed.Rune), parsed.Flags&syntax.FoldCase != 0, true
+}
+
+// For telling whether some filters have changed
+func filtersKey(filters []filter) string {
+	key := strings.Builder{}
+	for _, filter := range filters {
+		if !filter.isActive() {
+			continue
+		}
+
+		if filter.or {
+			key.WriteString("|")
+		}
+		if filter.inverted {
+			key.WriteString("!")
+		}
+		key.WriteString(filter.pattern.String())
+
+		// Not a valid regexp, so it can't be confused with any pattern
+		key.WriteString("\x00(")
+	}
+	return key.String()
+}
+
+// All filters in order, the last one being the one typed into the filter
+// prompt most recently
+func (p *Pager) filters() []filter {
+	current := newFilter(p.filterString)
+
+	// The filter prompt keeps these two in sync, but tests might set only the
+	// pattern
+	current.pattern = p.filterPattern
+
+	return append(p.earlierFilters[:len(p.earlierFilters):len(p.earlierFilters)], current)
+}
+
+// Make room for another filter, keeping the current one as a chip in the
+// footer
+func (p *Pager) pushFilter() {
+	current := p.filters()[len(p.earlierFilters)]
+	if current.isActive() {
+		p.earlierFilters = append(p.earlierFilters, current)
+	}
+
+	p.filterString = ""
+	p.filterPattern = nil
+}
+
+// Undo pushFilter(), making the most recently pushed filter the current one
+// again
+func (p *Pager) popFilter() {
+	if len(p.earlierFilters) == 0 {
+		p.filterString = ""
+		p.filterPattern = nil
+		return
+	}
+
+	previous := p.earlierFilters[len(p.earlierFilters)-1]
+	p.earlierFilters = p.earlierFilters[:len(p.earlierFilters)-1]
+	p.filterString = previous.filterString
+	p.filterPattern = previous.pattern
+}
+
+// Highlight matches of all non-inverted filters. Inverted filters have nothing
+// to highlight in the lines they let through.
+func (p *Pager) highlightFilterMatches() {
+	var patterns []*regexp.Regexp
+	var texts []string
+	for _, filter := range p.filters() {
+		if !filter.isActive() || filter.inverted {
+			continue
+		}
+
+		patterns = append(patterns, filter.pattern)
+
+		text := strings.TrimPrefix(filter.filterString, "|")
+		if text == "" {
+			text = filter.pattern.String()
+		}
+		texts = append(texts, text)
+	}
+
+	p.searchString = strings.Join(texts, "|")
+	switch len(patterns) {
+	case 0:
+		p.searchPattern = nil
+	case 1:
+		p.searchPattern = patterns[0]
+	default:
+		alternatives := make([]string, 0, len(patterns))
+		for _, pattern := range patterns {
+			// Groups keep any case insensitivity flag local to each pattern
+			alternatives = append(alternatives, "(?:"+pattern.String()+")")
+		}
+		p.searchPattern = regexp.MustCompile(strings.Join(alternatives, "|"))
+	}
+}
+
+// Stand out from the status bar style, whatever that is
+func filterChipStyle() twin.Style {
+	if statusbarStyle.HasAttr(twin.AttrReverse) {
+		return statusbarStyle.WithoutAttr(twin.AttrReverse)
+	}
+	return statusbarStyle.WithAttr(twin.AttrReverse)
+}
+
+// Draw one chip for each active filter on the bottom line of the screen.
+// Returns the position after the last chip.
+func (p *Pager) drawFilterChips(filters []filter, pos int, background twin.Style) int {
+	_, height := p.screen.Size()
+
+	chipStyle := filterChipStyle()
+	for _, filter := range filters {
+		if !filter.isActive() {
+			continue
+		}
+
+		text := filter.filterString
+		if text == "" {
+			text = filter.pattern.String()
+		}
+
+		for _, token := range " " + text + " " {
+			pos += p.screen.SetCell(pos, height-1, twin.NewStyledRune(token, chipStyle))
+		}
+		pos += p.screen.SetCell(pos, height-1, twin.NewStyledRune(' ', background))
+	}
+
+	return pos
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"regexp"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,6 +15,11 @@ import (
 
 // Filters lines based on the search query from the pager.
 
+// Backing reader lines are filtered this many at a time, so that we never have
+// all lines of a large file backed input in memory at once. Only the accepted
+// lines are kept.
+const filterChunkLineCount = 10_000
+
 type FilteringReader struct {
 	BackingReader reader.Reader
 
@@ -21,8 +27,19 @@ type FilteringReader struct {
 	// original pattern, including if it is set to nil.
 	FilterPattern **regexp.Regexp
 
-	// Protects filteredLinesCache, unfilteredLineCountWhenCaching, and
-	// filterPatternWhenCaching.
+	// Optional. The current filter as typed, for its "|" and "!" prefixes. Its
+	// pattern is in FilterPattern.
+	filterString *string
+
+	// Optional. Filters to apply before the current one.
+	earlierFilters *[]filter
+
+	// Optional. Also show this many lines before and after each accepted
+	// line, like "grep -C".
+	context *int
+
+	// Protects filteredLinesCache and everything we need for keeping it up to
+	// date.
 	lock sync.Mutex
 
 	// nil means no filtering has happened yet
@@ -33,70 +50,328 @@ type FilteringReader struct {
 	// rebuilt.
 	unfilteredLineCountWhenCaching int
 
-	// This is the pattern that was used when we cached the lines. If it
-	// doesn't match the current pattern, then our cache needs to be rebuilt.
-	filterPatternWhenCaching *regexp.Regexp
+	// This is what the filters were when we cached the lines. If they don't
+	// match the current filters, then our cache needs to be rebuilt.
+	filtersKeyWhenCaching string
+
+	// This is what the context line count was when we cached the lines
+	contextWhenCaching int
+
+	// This is what the last line looked like when we cached the lines. If it
+	// has changed, more text has been added to it since.
+	lastLineWhenCaching string
+
+	// The lines in filteredLinesCache, numbered as in the backing reader
+	acceptedLines []*reader.NumberedLine
+
+	// The lines passing each filter and all filters before it. When only the
+	// last filter changes, the earlier stages can be reused. When lines are
+	// added, only the new lines need filtering.
+	stages []filterStage
+}
+
+type filterStage struct {
+	// filtersKey() for this filter and all filters before it
+	key string
+
+	filter filter
+
+	// Numbered as in the backing reader
+	lines []*reader.NumberedLine
+}
+
+// The filters to apply, in order
+func (f *FilteringReader) activeFilters() []filter {
+	current := filter{pattern: *f.FilterPattern}
+	if f.filterString != nil {
+		current = newFilter(*f.filterString)
+		current.pattern = *f.FilterPattern
+	}
+
+	var earlierFilters []filter
+	if f.earlierFilters != nil {
+		earlierFilters = *f.earlierFilters
+	}
+
+	active := make([]filter, 0, len(earlierFilters)+1)
+	for _, filter := range append(earlierFilters[:len(earlierFilters):len(earlierFilters)], current) {
+		if filter.isActive() {
+			active = append(active, filter)
+		}
+	}
+
+	return active
 }
 
 // Please hold the lock when calling this method.
-func (f *FilteringReader) rebuildCache() {
+func (f *FilteringReader) rebuildCache(filters []filter, unfilteredLineCount int, context int) {
 	t0 := time.Now()
 
-	cache := make([]*reader.NumberedLine, 0)
-	filterPattern := *f.FilterPattern
+	// Lines before this index are the same as when we last cached, so we can
+	// keep our results for them
+	unchangedLineCount := 0
+	lastLineChanged := false
+	if f.filteredLinesCache != nil && unfilteredLineCount >= f.unfilteredLineCountWhenCaching {
+		unchangedLineCount = f.unfilteredLineCountWhenCaching
+		if unchangedLineCount > 0 {
+			lastLine := f.BackingReader.GetLine(linemetadata.IndexFromZeroBased(unchangedLineCount - 1))
+			if lastLine == nil || lastLine.Plain() != f.lastLineWhenCaching {
+				// More text has been added to the last line
+				unchangedLineCount--
+				lastLineChanged = true
+			}
+		}
+	}
 
-	// Mark cache base conditions
-	f.unfilteredLineCountWhenCaching = f.BackingReader.GetLineCount()
-	f.filterPatternWhenCaching = filterPattern
-
-	// Repopulate the cache
-	allBaseLines := f.BackingReader.GetLines(linemetadata.Index{}, math.MaxInt)
-	resultIndex := 0
-	for _, line := range allBaseLines.Lines {
-		if filterPattern != nil && len(filterPattern.String()) > 0 && !filterPattern.MatchString(line.Line.Plain(&line.Index)) {
-			// We have a pattern but it doesn't match
+	// Fetching lines can be expensive. Different stages and context lines
+	// often want the same lines, so we keep the most recently fetched chunk.
+	var lastChunk []*reader.NumberedLine
+	baseLines := func(first int, count int, keep bool) []*reader.NumberedLine {
+		if len(lastChunk) > 0 {
+			chunkFirst := lastChunk[0].Index.Index()
+			if first >= chunkFirst && first+count <= chunkFirst+len(lastChunk) {
+				return lastChunk[first-chunkFirst : first-chunkFirst+count]
+			}
+		}
+
+		lines := f.BackingReader.GetLines(linemetadata.IndexFromZeroBased(first), count).Lines
+		if keep {
+			lastChunk = lines
+		}
+		return lines
+	}
+
+	// Call handleChunk with the backing reader lines in order, starting at
+	// the given index, a chunk at a time
+	forEachChunk := func(from int, handleChunk func(chunk []*reader.NumberedLine)) {
+		for chunkStart := from; chunkStart < unfilteredLineCount; chunkStart += filterChunkLineCount {
+			chunk := baseLines(chunkStart, min(filterChunkLineCount, unfilteredLineCount-chunkStart), true)
+			if len(chunk) == 0 {
+				// Input got shorter
+				return
+			}
+			handleChunk(chunk)
+		}
+	}
+
+	// Backing reader lines accepted by the filter, fetched and filtered in
+	// chunks
+	filterBaseLinesFrom := func(from int, filter filter) []*reader.NumberedLine {
+		accepted := make([]*reader.NumberedLine, 0)
+		forEachChunk(from, func(chunk []*reader.NumberedLine) {
+			accepted = append(accepted, filterLines(chunk, filter)...)
+		})
+		return accepted
+	}
+
+	// Like filterBaseLinesFrom(), but also accepting the already accepted
+	// lines
+	filterBaseLinesOrFrom := func(from int, alreadyAccepted []*reader.NumberedLine, filter filter) []*reader.NumberedLine {
+		accepted := make([]*reader.NumberedLine, 0, len(alreadyAccepted))
+		forEachChunk(from, func(chunk []*reader.NumberedLine) {
+			chunkEnd := chunk[len(chunk)-1].Index.Index() + 1
+			chunkAccepted := linesBefore(alreadyAccepted, chunkEnd)
+			alreadyAccepted = alreadyAccepted[len(chunkAccepted):]
+			accepted = append(accepted, filterLinesOr(chunk, chunkAccepted, filter)...)
+		})
+		return accepted
+	}
+
+	// Refresh the stages. As long as the filters are the same as last time,
+	// only the new lines need filtering.
+	unchangedSoFar := true
+	for i, filter := range filters {
+		key := filtersKey(filters[:i+1])
+		var previous *filterStage
+		if i < len(f.stages) {
+			previous = &f.stages[i]
+		}
+
+		var lines []*reader.NumberedLine
+		if unchangedSoFar && previous != nil && previous.key == key {
+			lines = linesBefore(previous.lines, unchangedLineCount)
+			if i == 0 {
+				lines = append(lines, filterBaseLinesFrom(unchangedLineCount, filter)...)
+			} else {
+				newAccepted := linesFrom(f.stages[i-1].lines, unchangedLineCount)
+				if filter.or {
+					lines = append(lines, filterBaseLinesOrFrom(unchangedLineCount, newAccepted, filter)...)
+				} else {
+					lines = append(lines, filterLines(newAccepted, filter)...)
+				}
+			}
+		} else if unchangedSoFar && previous != nil && unchangedLineCount == unfilteredLineCount &&
+			!filter.or && !previous.filter.or && filter.refines(previous.filter) {
+			// Everything we want is in what we had
+			lines = filterLines(previous.lines, filter)
+			unchangedSoFar = false
+		} else if i == 0 {
+			lines = filterBaseLinesFrom(0, filter)
+			unchangedSoFar = false
+		} else if filter.or {
+			lines = filterBaseLinesOrFrom(0, f.stages[i-1].lines, filter)
+			unchangedSoFar = false
+		} else {
+			lines = filterLines(f.stages[i-1].lines, filter)
+			unchangedSoFar = false
+		}
+
+		stage := filterStage{
+			key:    key,
+			filter: filter,
+			lines:  lines,
+		}
+		if previous != nil {
+			*previous = stage
+		} else {
+			f.stages = append(f.stages, stage)
+		}
+	}
+	f.stages = f.stages[:len(filters)]
+
+	var accepted []*reader.NumberedLine
+	if len(f.stages) > 0 {
+		accepted = f.stages[len(f.stages)-1].lines
+	}
+
+	// Since this cache is handed out to others, we only ever append to it. If
+	// anything else changed, we make a new one.
+	var cache []*reader.NumberedLine
+	var acceptedLines []*reader.NumberedLine
+	if unchangedSoFar && f.filteredLinesCache != nil &&
+		f.filtersKeyWhenCaching == filtersKey(filters) &&
+		f.contextWhenCaching == context {
+		keepBefore := unchangedLineCount
+		if lastLineChanged {
+			// The lines before the changed one might have been there only as
+			// context for it
+			keepBefore -= context
+		}
+		acceptedLines = linesBefore(f.acceptedLines, keepBefore)
+		cache = *f.filteredLinesCache
+		if len(acceptedLines) < len(cache) {
+			cache = append(make([]*reader.NumberedLine, 0, len(accepted)), cache[:len(acceptedLines)]...)
+		}
+	} else {
+		cache = make([]*reader.NumberedLine, 0, len(accepted))
+		acceptedLines = make([]*reader.NumberedLine, 0, len(accepted))
+	}
+
+	// Add the lines after the ones we already have, with any context lines
+	// around them
+	next := 0
+	if len(acceptedLines) > 0 {
+		next = acceptedLines[len(acceptedLines)-1].Index.Index() + 1
+	}
+	for _, line := range linesFrom(accepted, next-context) {
+		if context == 0 {
+			acceptedLines = append(acceptedLines, line)
+			continue
+		}
+
+		first := max(next, line.Index.Index()-context)
+		if first > line.Index.Index()+context {
 			continue
 		}
 
+		last := min(unfilteredLineCount-1, line.Index.Index()+context)
+		contextLines := baseLines(first, last-first+1, false)
+		acceptedLines = append(acceptedLines, contextLines...)
+		next = first + len(contextLines)
+	}
+
+	// Number the new lines
+	for _, line := range acceptedLines[len(cache):] {
 		cache = append(cache, &reader.NumberedLine{
 			Line:   line.Line,
-			Index:  linemetadata.IndexFromZeroBased(resultIndex),
+			Index:  linemetadata.IndexFromZeroBased(len(cache)),
 			Number: line.Number,
 		})
-		resultIndex++
 	}
 
+	// Mark cache base conditions
 	f.filteredLinesCache = &cache
+	f.acceptedLines = acceptedLines
+	f.unfilteredLineCountWhenCaching = unfilteredLineCount
+	f.filtersKeyWhenCaching = filtersKey(filters)
+	f.contextWhenCaching = context
+	f.lastLineWhenCaching = ""
+	if unfilteredLineCount > 0 {
+		lastLine := f.BackingReader.GetLine(linemetadata.IndexFromZeroBased(unfilteredLineCount - 1))
+		if lastLine != nil {
+			f.lastLineWhenCaching = lastLine.Plain()
+		}
+	}
 
-	log.Debugf("Filtered out %d/%d lines in %s",
-		len(allBaseLines.Lines)-len(cache), len(allBaseLines.Lines), time.Since(t0))
+	log.Debugf("Filtered out %d/%d lines using %d filter(s) in %s, %d lines were already filtered",
+		unfilteredLineCount-len(cache), unfilteredLineCount, len(filters), time.Since(t0), unchangedLineCount)
 }
 
-func (f *FilteringReader) getAllLines() []*reader.NumberedLine {
-	f.lock.Lock()
-	defer f.lock.Unlock()
+// The lines with backing reader indices before the given one. The lines must
+// be in backing reader order.
+func linesBefore(lines []*reader.NumberedLine, index int) []*reader.NumberedLine {
+	return lines[:sort.Search(len(lines), func(i int) bool {
+		return lines[i].Index.Index() >= index
+	})]
+}
 
-	if f.filteredLinesCache == nil {
-		f.rebuildCache()
-		return *f.filteredLinesCache
-	}
+// The lines with backing reader indices from the given one and on. The lines
+// must be in backing reader order.
+func linesFrom(lines []*reader.NumberedLine, index int) []*reader.NumberedLine {
+	return lines[len(linesBefore(lines, index)):]
+}
+
+// Lines accepted by the filter
+func filterLines(lines []*reader.NumberedLine, filter filter) []*reader.NumberedLine {
+	accepted := make([]*reader.NumberedLine, 0)
+	for _, line := range lines {
+		if !filter.accepts(line.Line.Plain(&line.Index)) {
+			continue
+		}
 
-	if f.unfilteredLineCountWhenCaching != f.BackingReader.GetLineCount() {
-		f.rebuildCache()
-		return *f.filteredLinesCache
+		accepted = append(accepted, line)
 	}
 
-	var currentFilterPattern string
-	if *f.FilterPattern != nil {
-		currentFilterPattern = (*f.FilterPattern).String()
+	return accepted
+}
+
+// Lines that have already been accepted, together with any lines accepted by
+// the filter. Both lists must be in backing reader order.
+func filterLinesOr(allLines []*reader.NumberedLine, alreadyAccepted []*reader.NumberedLine, filter filter) []*reader.NumberedLine {
+	accepted := make([]*reader.NumberedLine, 0, len(alreadyAccepted))
+	for _, line := range allLines {
+		for len(alreadyAccepted) > 0 && alreadyAccepted[0].Index.IsBefore(line.Index) {
+			alreadyAccepted = alreadyAccepted[1:]
+		}
+
+		if len(alreadyAccepted) > 0 && alreadyAccepted[0].Index == line.Index {
+			accepted = append(accepted, line)
+			continue
+		}
+
+		if filter.accepts(line.Line.Plain(&line.Index)) {
+			accepted = append(accepted, line)
+		}
 	}
-	var cacheFilterPattern string
-	if f.filterPatternWhenCaching != nil {
-		cacheFilterPattern = f.filterPatternWhenCaching.String()
+
+	return accepted
+}
+
+func (f *FilteringReader) getAllLines() []*reader.NumberedLine {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	filters := f.activeFilters()
+	unfilteredLineCount := f.BackingReader.GetLineCount()
+	context := 0
+	if f.context != nil {
+		context = max(0, *f.context)
 	}
-	if currentFilterPattern != cacheFilterPattern {
-		f.rebuildCache()
-		return *f.filteredLinesCache
+	if f.filteredLinesCache == nil ||
+		f.unfilteredLineCountWhenCaching != unfilteredLineCount ||
+		f.filtersKeyWhenCaching != filtersKey(filters) ||
+		f.contextWhenCaching != context {
+		f.rebuildCache(filters, unfilteredLineCount, context)
 	}
 
 	return *f.filteredLinesCache
@@ -106,9 +381,10 @@ func (f *FilteringReader) shouldPassThrough() bool {
 	f.lock.Lock()
 	defer f.lock.Unlock()
 
-	if *f.FilterPattern == nil || len((*f.FilterPattern).String()) == 0 {
+	if len(f.activeFilters()) == 0 {
 		// Cache is not needed
 		f.filteredLinesCache = nil
+		f.stages = nil
 
 		// No filtering, so pass through all
 		return true
@@ -117,6 +393,34 @@ func (f *FilteringReader) shouldPassThrough() bool {
 	return false
 }
 
+// The index of the first line with the given line number or a later one.
+// Filtered lines keep their line numbers from the backing reader.
+func (f *FilteringReader) indexOfLineNumber(number linemetadata.Number) linemetadata.Index {
+	if f.shouldPassThrough() {
+		return linemetadata.IndexFromZeroBased(number.AsZeroBased())
+	}
+
+	allLines := f.getAllLines()
+	index := sort.Search(len(allLines), func(i int) bool {
+		return !allLines[i].Number.IsBefore(number)
+	})
+	return linemetadata.IndexFromZeroBased(index)
+}
+
+// True if the line after this one isn't the next line in the backing reader
+func (f *FilteringReader) hasGapAfter(line *reader.NumberedLine) bool {
+	if f.shouldPassThrough() {
+		return false
+	}
+
+	next := f.GetLine(line.Index.NonWrappingAdd(1))
+	if next == nil {
+		return false
+	}
+
+	return next.Number != line.Number.NonWrappingAdd(1)
+}
+
 func (f *FilteringReader) GetLineCount() int {
 	if f.shouldPassThrough() {
 		return f.BackingReader.GetLineCount()
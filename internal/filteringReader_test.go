@@ -0,0 +1,393 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func filteredLines(reader reader.Reader) []string {
+	returnMe := []string{}
+	for _, line := range reader.GetLines(linemetadata.Index{}, reader.GetLineCount()).Lines {
+		returnMe = append(returnMe, line.Plain())
+	}
+	return returnMe
+}
+
+func newTestFilteringReader(text string, earlierFilters []string, filterString string) *FilteringReader {
+	filters := []filter{}
+	for _, filterString := range earlierFilters {
+		filters = append(filters, newFilter(filterString))
+	}
+	pattern := newFilter(filterString).pattern
+
+	return &FilteringReader{
+		BackingReader:  reader.NewFromTextForTesting("", text),
+		FilterPattern:  &pattern,
+		filterString:   &filterString,
+		earlierFilters: &filters,
+	}
+}
+
+func TestFilterInverted(t *testing.T) {
+	testMe := newTestFilteringReader("apa\nbepa\ncepa\n", nil, "!bepa")
+	assert.DeepEqual(t, []string{"apa", "cepa"}, filteredLines(testMe))
+
+	// Line numbers should be kept
+	assert.Equal(t, 3, testMe.GetLine(linemetadata.IndexFromZeroBased(1)).Number.AsOneBased())
+}
+
+func TestFilterStacked(t *testing.T) {
+	text := "ERROR req-1\nINFO req-1\nERROR req-2\nDEBUG req-1\nWARN req-3\n"
+
+	testMe := newTestFilteringReader(text, []string{"req-1"}, "!INFO")
+	assert.DeepEqual(t, []string{"ERROR req-1", "DEBUG req-1"}, filteredLines(testMe))
+
+	testMe = newTestFilteringReader(text, []string{"ERROR"}, "|WARN")
+	assert.DeepEqual(t, []string{"ERROR req-1", "ERROR req-2", "WARN req-3"}, filteredLines(testMe))
+
+	// (ERROR or WARN) and not req-2
+	testMe = newTestFilteringReader(text, []string{"ERROR", "|WARN"}, "!req-2")
+	assert.DeepEqual(t, []string{"ERROR req-1", "WARN req-3"}, filteredLines(testMe))
+
+	// Not INFO, or req-1
+	testMe = newTestFilteringReader(text, []string{"!INFO"}, "|req-1")
+	assert.DeepEqual(t, strings.Split(strings.TrimSpace(text), "\n"), filteredLines(testMe))
+}
+
+// Changing the last filter should reuse the lines from the earlier stages
+func TestFilterStagesCached(t *testing.T) {
+	text := strings.Repeat("ERROR apa\nINFO apa\nERROR bepa\n", 100)
+	testMe := newTestFilteringReader(text, []string{"ERROR"}, "a")
+	assert.Equal(t, 200, testMe.GetLineCount())
+	firstStage := testMe.stages[0].lines
+
+	*testMe.filterString = "ap"
+	*testMe.FilterPattern = regexp.MustCompile("ap")
+	assert.Equal(t, 100, testMe.GetLineCount())
+	assert.Equal(t, &firstStage[0], &testMe.stages[0].lines[0])
+}
+
+// A reader that we can add lines to, keeping track of how many lines have been
+// fetched from it
+type growingReader struct {
+	lines        []*reader.Line
+	fetchedLines int
+
+	// The most lines fetched in one GetLines() call
+	largestFetch int
+}
+
+func (r *growingReader) add(lines ...string) {
+	for _, line := range lines {
+		newLine := reader.NewLine(line)
+		r.lines = append(r.lines, &newLine)
+	}
+}
+
+func (r *growingReader) GetLineCount() int {
+	return len(r.lines)
+}
+
+func (r *growingReader) GetLine(index linemetadata.Index) *reader.NumberedLine {
+	if !index.IsWithinLength(len(r.lines)) {
+		return nil
+	}
+	return &reader.NumberedLine{
+		Index:  index,
+		Number: linemetadata.NumberFromZeroBased(index.Index()),
+		Line:   r.lines[index.Index()],
+	}
+}
+
+func (r *growingReader) GetLines(firstLine linemetadata.Index, wantedLineCount int) *reader.InputLines {
+	lines := []*reader.NumberedLine{}
+	for i := firstLine.Index(); i < min(len(r.lines), firstLine.Index()+wantedLineCount); i++ {
+		lines = append(lines, r.GetLine(linemetadata.IndexFromZeroBased(i)))
+	}
+	r.fetchedLines += len(lines)
+	r.largestFetch = max(r.largestFetch, len(lines))
+	return &reader.InputLines{Lines: lines}
+}
+
+func (r *growingReader) ShouldShowLineCount() bool {
+	return true
+}
+
+func newGrowingFilteringReader(earlierFilters []string, filterString string, context int) (*FilteringReader, *growingReader) {
+	filters := []filter{}
+	for _, filterString := range earlierFilters {
+		filters = append(filters, newFilter(filterString))
+	}
+	pattern := newFilter(filterString).pattern
+	backing := &growingReader{}
+
+	return &FilteringReader{
+		BackingReader:  backing,
+		FilterPattern:  &pattern,
+		filterString:   &filterString,
+		earlierFilters: &filters,
+		context:        &context,
+	}, backing
+}
+
+// Verify that filtering incrementally gives the same result as filtering
+// everything at once
+func assertSameAsRebuilt(t *testing.T, testMe *FilteringReader) {
+	t.Helper()
+
+	rebuilt := FilteringReader{
+		BackingReader:  testMe.BackingReader,
+		FilterPattern:  testMe.FilterPattern,
+		filterString:   testMe.filterString,
+		earlierFilters: testMe.earlierFilters,
+		context:        testMe.context,
+	}
+
+	expected := rebuilt.GetLines(linemetadata.Index{}, rebuilt.GetLineCount()).Lines
+	actual := testMe.GetLines(linemetadata.Index{}, testMe.GetLineCount()).Lines
+	assert.Equal(t, len(expected), len(actual))
+	for i := range expected {
+		assert.Equal(t, expected[i].Index, actual[i].Index)
+		assert.Equal(t, expected[i].Number, actual[i].Number)
+		assert.Equal(t, expected[i].Plain(), actual[i].Plain())
+	}
+}
+
+func TestFilterAppendedLines(t *testing.T) {
+	for _, context := range []int{0, 2} {
+		testMe, backing := newGrowingFilteringReader([]string{"ERROR", "|WARN"}, "!ignore", context)
+		for i := 0; i < 1000; i++ {
+			backing.add(fmt.Sprintf("INFO %d", i), fmt.Sprintf("ERROR %d", i), fmt.Sprintf("WARN %d ignore", i))
+		}
+		assertSameAsRebuilt(t, testMe)
+
+		backing.fetchedLines = 0
+		backing.add("ERROR new", "INFO new", "INFO new", "INFO new", "WARN new")
+		testMe.GetLineCount()
+		assert.Equal(t, 5, backing.fetchedLines, "Only the new lines should have been fetched")
+		assertSameAsRebuilt(t, testMe)
+
+		// Text added to the last line should be noticed
+		newLine := reader.NewLine("WARN new ignore")
+		backing.lines[len(backing.lines)-1] = &newLine
+		backing.add("INFO newest")
+		assertSameAsRebuilt(t, testMe)
+	}
+}
+
+// Large inputs should be filtered a chunk at a time, rather than fetching all
+// lines at once
+func TestFilterInChunks(t *testing.T) {
+	const lineCount = 10_000
+	testMe, backing := newGrowingFilteringReader([]string{"ERROR"}, "|WARN 1", 0)
+	for i := 0; i < lineCount; i++ {
+		backing.add(fmt.Sprintf("INFO %d", i), fmt.Sprintf("ERROR %d", i), fmt.Sprintf("WARN %d", i))
+	}
+
+	// All ERROR lines, and WARN lines for 1, 10-19, 100-199 and 1000-1999
+	assert.Equal(t, lineCount+1+10+100+1000, testMe.GetLineCount())
+	assert.Equal(t, filterChunkLineCount, backing.largestFetch)
+	assertSameAsRebuilt(t, testMe)
+}
+
+func TestFilterRefined(t *testing.T) {
+	testMe, backing := newGrowingFilteringReader(nil, "ERR", 0)
+	for i := 0; i < 1000; i++ {
+		backing.add(fmt.Sprintf("INFO %d", i), fmt.Sprintf("ERROR %d", i), fmt.Sprintf("ERR %d", i))
+	}
+	assert.Equal(t, 2000, testMe.GetLineCount())
+
+	backing.fetchedLines = 0
+	*testMe.filterString = "ERRO"
+	*testMe.FilterPattern = newFilter("ERRO").pattern
+	assert.Equal(t, 1000, testMe.GetLineCount())
+	assert.Equal(t, 0, backing.fetchedLines, "The previous result should have been reused")
+	assertSameAsRebuilt(t, testMe)
+}
+
+func TestFilterRefines(t *testing.T) {
+	refines := func(newFilterString string, oldFilterString string) bool {
+		return newFilter(newFilterString).refines(newFilter(oldFilterString))
+	}
+
+	assert.Assert(t, refines("abc", "ab"))
+	assert.Assert(t, refines("xabc", "ab"))
+	assert.Assert(t, refines("ABC", "AB"))
+	assert.Assert(t, refines("!ab", "!abc"))
+
+	assert.Assert(t, !refines("ab", "abc"))
+	assert.Assert(t, !refines("!abc", "!ab"))
+	assert.Assert(t, !refines("!abc", "ab"))
+
+	// Case insensitive "ab" matches more than case sensitive "aB"
+	assert.Assert(t, !refines("aBc", "ab"))
+
+	// Regexps could match anything
+	assert.Assert(t, !refines("ab|c", "ab"))
+	assert.Assert(t, !refines("abc", "a.c"))
+}
+
+func BenchmarkFilterFollow(b *testing.B) {
+	testMe, backing := newGrowingFilteringReader(nil, "ERROR", 0)
+	for i := 0; i < 1_000_000; i++ {
+		backing.add(fmt.Sprintf("INFO %d", i), fmt.Sprintf("ERROR %d", i))
+	}
+	testMe.GetLineCount()
+
+	b.ResetTimer()
+	for range b.N {
+		backing.add("INFO new", "ERROR new")
+		testMe.GetLineCount()
+	}
+}
+
+func BenchmarkFilterRefine(b *testing.B) {
+	testMe, backing := newGrowingFilteringReader(nil, "ERR", 0)
+	for i := 0; i < 1_000_000; i++ {
+		backing.add(fmt.Sprintf("INFO %d", i), fmt.Sprintf("ERROR %d", i))
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		b.StopTimer()
+		*testMe.filterString = "ERR"
+		*testMe.FilterPattern = newFilter("ERR").pattern
+		testMe.GetLineCount()
+		b.StartTimer()
+
+		*testMe.filterString = "ERROR"
+		*testMe.FilterPattern = newFilter("ERROR").pattern
+		testMe.GetLineCount()
+	}
+}
+
+func TestFilterPrompt(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "ERROR req-1\nINFO req-1\nERROR req-2\n"))
+	screen := twin.NewFakeScreen(80, 10)
+	pager.screen = screen
+
+	pager.mode.onRune('&')
+	for _, char := range "ERROR" {
+		pager.mode.onRune(char)
+	}
+	pager.mode.onKey(twin.KeyEnter)
+	assert.Equal(t, 2, pager.Reader().GetLineCount())
+
+	// Adding a filter should stack the new filter on top of the first one
+	pager.mode.onRune('+')
+	for _, char := range "!req-2" {
+		pager.mode.onRune(char)
+	}
+	assert.DeepEqual(t, []string{"ERROR req-1"}, filteredLines(pager.Reader()))
+	assert.Equal(t, 1, len(pager.earlierFilters))
+
+	// The first filter should be shown as a chip
+	pager.mode.drawFooter("", "")
+	footer := screen.GetRow(9)
+	assert.Equal(t, "Filter:  ERROR  !req-2", rowToString(footer))
+	assert.Equal(t, footer[9].Style, filterChipStyle())
+	assert.Equal(t, footer[16].Style, twin.StyleDefault)
+
+	// Backspacing past the start should go back to editing the first filter
+	for range "!req-2" {
+		pager.mode.onKey(twin.KeyBackspace)
+	}
+	pager.mode.onKey(twin.KeyBackspace)
+	assert.Equal(t, 0, len(pager.earlierFilters))
+	assert.Equal(t, "ERROR", pager.filterString)
+	assert.Equal(t, 2, pager.Reader().GetLineCount())
+	pager.mode.onKey(twin.KeyEnter)
+
+	// Matches of all non-inverted filters should be highlighted
+	pager.mode.onRune('+')
+	for _, char := range "|INFO" {
+		pager.mode.onRune(char)
+	}
+	assert.Equal(t, 3, pager.Reader().GetLineCount())
+	assert.Assert(t, pager.searchPattern.MatchString("ERROR"))
+	assert.Assert(t, pager.searchPattern.MatchString("INFO"))
+
+	// Escape should drop only the filter being typed
+	pager.mode.onKey(twin.KeyEscape)
+	assert.Assert(t, pager.isViewing())
+	assert.Equal(t, 0, len(pager.earlierFilters))
+	assert.Equal(t, "ERROR", pager.filterString)
+	assert.Equal(t, 2, pager.Reader().GetLineCount())
+	assert.Equal(t, "ERROR", pager.searchPattern.String())
+
+	// Starting a new filter should replace the current ones
+	pager.mode.onRune('&')
+	assert.Equal(t, 3, pager.Reader().GetLineCount())
+	for _, char := range "INFO" {
+		pager.mode.onRune(char)
+	}
+	assert.DeepEqual(t, []string{"INFO req-1"}, filteredLines(pager.Reader()))
+
+	// Escape after starting a new filter should leave no filters
+	pager.mode.onKey(twin.KeyEscape)
+	assert.Equal(t, 3, pager.Reader().GetLineCount())
+}
+
+func TestFilterContext(t *testing.T) {
+	lines := []string{}
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	lines[4] = "match 5"
+	lines[6] = "match 7"
+	lines[15] = "match 16"
+	lines[19] = "match 20"
+
+	context := 1
+	testMe := newTestFilteringReader(strings.Join(lines, "\n"), nil, "match")
+	testMe.context = &context
+	assert.DeepEqual(t, []string{
+		"line 4", "match 5", "line 6", "match 7", "line 8",
+		"line 15", "match 16", "line 17",
+		"line 19", "match 20",
+	}, filteredLines(testMe))
+
+	// Line numbers should be the original ones
+	assert.Equal(t, 15, testMe.GetLine(linemetadata.IndexFromZeroBased(5)).Number.AsOneBased())
+
+	assert.Assert(t, !testMe.hasGapAfter(testMe.GetLine(linemetadata.IndexFromZeroBased(3))))
+	assert.Assert(t, testMe.hasGapAfter(testMe.GetLine(linemetadata.IndexFromZeroBased(4))))
+	assert.Assert(t, !testMe.hasGapAfter(testMe.GetLine(linemetadata.IndexFromZeroBased(9))))
+
+	// Going to line 10 should end up on the next line after that
+	assert.Equal(t, 5, testMe.indexOfLineNumber(linemetadata.NumberFromOneBased(10)).Index())
+	assert.Equal(t, 5, testMe.indexOfLineNumber(linemetadata.NumberFromOneBased(15)).Index())
+}
+
+// Marks should stay on the same line when the filter changes
+func TestMarkWhileFiltering(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "INFO 1\nERROR 2\nINFO 3\nERROR 4\n"))
+	pager.screen = twin.NewFakeScreen(80, 2)
+	pager.marks = map[rune]linemetadata.Number{}
+
+	pager.mode.onRune('&')
+	for _, char := range "ERROR" {
+		pager.mode.onRune(char)
+	}
+	pager.mode.onKey(twin.KeyEnter)
+
+	// Mark "ERROR 4", which is the second filtered line
+	pager.scrollPosition = pager.scrollPosition.NextLine(1)
+	pager.mode = PagerModeMark{pager: pager}
+	pager.mode.onRune('a')
+
+	// Stop filtering and jump to the mark
+	pager.mode.onRune('&')
+	pager.mode.onKey(twin.KeyEscape)
+	assert.Equal(t, 4, pager.Reader().GetLineCount())
+	pager.mode = PagerModeJumpToMark{pager: pager}
+	pager.mode.onRune('a')
+	assert.Equal(t, "ERROR 4", pager.Reader().GetLine(*pager.lineIndex()).Plain())
+}
@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/walles/moor/v2/internal/reader"
+)
+
+// Help screen sections, with text to show around the list of key bindings
+var helpSections = []struct {
+	name  string
+	intro string
+	outro string
+}{
+	{
+		name: helpSectionMiscellaneous,
+		outro: `
+Click the status bar to show or leave this help.
+
+Input starting with a JSON log line is shown as one "{ts} {level} {msg}" line
+per JSON object, configurable using --log-template. Searching and filtering
+work on what is shown. Press {toggle-log-object} to show the full JSON object of
+the line at the top of the screen, or of the selected line.`,
+	},
+	{
+		name: helpSectionMovingAround,
+		outro: `
+With the mouse, scroll with the wheel or drag the line numbers up or down. Click
+a line number to set a mark on that line. Click a hyperlink to open it. Click or
+drag the scrollbar to jump.
+
+The scrollbar shows search hits as tick marks.`,
+	},
+	{
+		name: helpSectionMultipleFiles,
+		intro: `
+When paging more than one file, the status bar will say "file 2 of 5".`,
+		outro: `
+Each file remembers its own position, marks and search.
+
+Archives (tar, compressed tar and zip) are shown as a listing of their members.
+Click a member, or press {open-member} and pick one, to open it as a new file. View a
+member directly using "moor bundle.tar.gz:path/in/archive.log".`,
+	},
+	{
+		name: helpSectionFiltering,
+		outro: `
+After starting to filter, type your filter expression.
+
+Press {add-filter} to add another filter, showing only lines matching all filters.
+Start the added filter with | to show lines matching any of them instead.
+Backspacing past the start of a filter goes back to editing the previous one.
+
+While filtering, left / right arrows, PageUp, PageDown, Home and End work as
+usual. Up / down arrows recall earlier filters and searches.
+
+Press RETURN to exit filtering mode, or 'ESC' to drop the filter being typed.`,
+	},
+	{
+		name: helpSectionSearching,
+		outro: `
+* Type RETURN to stop searching, or ESC to skip back to where the search started
+* Up / down arrows recall earlier searches starting with what you have typed
+* Search is case sensitive if it contains any UPPER CASE CHARACTERS
+* Search is interpreted as a regexp if it is a valid one
+* While searching, Alt-r toggles regexp matching, Alt-c switches between smart
+  case, case sensitive and case insensitive, and Alt-w matches whole words only`,
+	},
+	{
+		name: helpSectionSelecting,
+		outro: `
+While selecting, arrow keys or 'h' / 'j' / 'k' / 'l' change the selection.
+Moving sideways selects characters rather than whole lines, and 'v' switches
+between the two. Press 'y' or RETURN to copy, or ESC to cancel.
+
+You can also select text by dragging the mouse over it. Dragging from the line
+numbers scrolls instead.
+
+Copying works by asking your terminal to update the clipboard, which works over
+SSH as well. Some terminals need to be configured to allow this.`,
+	},
+}
+
+const helpIntro = `
+Welcome to Moor, the nice pager!
+`
+
+const helpOutro = `
+Changing key bindings
+---------------------
+Bind keys to the actions listed in (parentheses) above in the [keys] section of
+~/.config/moor/config.toml:
+  [keys]
+  gg = "scroll-to-top"
+  "ctrl-x ctrl-s" = "edit"
+
+Reporting bugs
+--------------
+File issues at https://github.com/walles/moor/issues, or post
+questions to johan.walles@gmail.com.
+
+Installing Moor as your default pager
+-------------------------------------
+Put the following line in your ~/.bashrc, ~/.bash_profile or ~/.zshrc:
+  export PAGER=moor
+
+Source Code
+-----------
+Available at https://github.com/walles/moor/.
+`
+
+// The help screen contents, listing the current key bindings
+func (p *Pager) helpText() string {
+	text := strings.Builder{}
+	text.WriteString(helpIntro)
+
+	for _, section := range helpSections {
+		text.WriteString("\n" + section.name + "\n")
+		text.WriteString(strings.Repeat("-", len(section.name)) + "\n")
+		if section.intro != "" {
+			text.WriteString(strings.TrimPrefix(section.intro, "\n") + "\n\n")
+		}
+
+		type helpLine struct {
+			keys        string
+			description string
+		}
+		lines := []helpLine{}
+		keysWidth := 0
+		for _, action := range actions {
+			if action.section != section.name {
+				continue
+			}
+
+			keys := p.keysFor(action)
+			if len(keys) == 0 {
+				continue
+			}
+
+			line := helpLine{
+				keys:        strings.Join(keys, ", "),
+				description: action.description + " (" + action.name + ")",
+			}
+			keysWidth = max(keysWidth, utf8.RuneCountInString(line.keys))
+			lines = append(lines, line)
+		}
+
+		for _, line := range lines {
+			text.WriteString("  " + line.keys + strings.Repeat(" ", keysWidth-utf8.RuneCountInString(line.keys)) + "  " + line.description + "\n")
+		}
+
+		if section.outro != "" {
+			text.WriteString(p.withBoundKeys(section.outro) + "\n")
+		}
+	}
+
+	text.WriteString(helpOutro)
+
+	return text.String()
+}
+
+// Replace action names in braces, like "{open-member}", with the keys bound to
+// those actions
+func (p *Pager) withBoundKeys(text string) string {
+	for _, action := range actions {
+		keys := p.keysText(action.name)
+		if keys == "" {
+			// Refer to the action the same way as the list of key bindings does
+			keys = "(" + action.name + ")"
+		}
+		text = strings.ReplaceAll(text, "{"+action.name+"}", keys)
+	}
+	return text
+}
+
+func (p *Pager) showHelp() {
+	if p.isShowingHelp {
+		return
+	}
+
+	p.preHelpState = &_PreHelpState{
+		scrollPosition:      p.scrollPosition,
+		leftColumnZeroBased: p.leftColumnZeroBased,
+		targetLine:          p.TargetLine,
+	}
+	p.scrollPosition = newScrollPosition("Pager scroll position")
+	p.leftColumnZeroBased = 0
+	p.setTargetLine(nil)
+
+	// Generate this every time, to show the current key bindings
+	p.helpReader = reader.NewFromTextForTesting("Help", p.helpText())
+	p.isShowingHelp = true
+}
@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/twin"
+)
+
+// A key press, either a character or a special key
+type KeyPress struct {
+	// If this is zero, KeyCode is what was pressed
+	Rune    rune
+	KeyCode twin.KeyCode
+}
+
+// Names of special keys, for use in config files
+var keyNames = map[string]KeyPress{
+	"escape":    {KeyCode: twin.KeyEscape},
+	"enter":     {KeyCode: twin.KeyEnter},
+	"backspace": {KeyCode: twin.KeyBackspace},
+	"delete":    {KeyCode: twin.KeyDelete},
+	"up":        {KeyCode: twin.KeyUp},
+	"down":      {KeyCode: twin.KeyDown},
+	"right":     {KeyCode: twin.KeyRight},
+	"left":      {KeyCode: twin.KeyLeft},
+	"alt-up":    {KeyCode: twin.KeyAltUp},
+	"alt-down":  {KeyCode: twin.KeyAltDown},
+	"alt-right": {KeyCode: twin.KeyAltRight},
+	"alt-left":  {KeyCode: twin.KeyAltLeft},
+	"alt-c":     {KeyCode: twin.KeyAltC},
+	"alt-r":     {KeyCode: twin.KeyAltR},
+	"alt-w":     {KeyCode: twin.KeyAltW},
+	"home":      {KeyCode: twin.KeyHome},
+	"end":       {KeyCode: twin.KeyEnd},
+	"pageup":    {KeyCode: twin.KeyPgUp},
+	"pagedown":  {KeyCode: twin.KeyPgDown},
+	"space":     {Rune: ' '},
+}
+
+// Parses a key name like "j", "space", "pagedown" or "ctrl-f"
+func ParseKeyPress(name string) (KeyPress, error) {
+	if utf8.RuneCountInString(name) == 1 {
+		char, _ := utf8.DecodeRuneInString(name)
+		return KeyPress{Rune: char}, nil
+	}
+
+	lowerName := strings.ToLower(name)
+	keyPress, found := keyNames[lowerName]
+	if found {
+		return keyPress, nil
+	}
+
+	if strings.HasPrefix(lowerName, "ctrl-") && len(lowerName) == len("ctrl-x") {
+		char := lowerName[len("ctrl-")]
+		if char >= 'a' && char <= 'z' {
+			return KeyPress{Rune: rune(char-'a') + 1}, nil
+		}
+	}
+
+	return KeyPress{}, fmt.Errorf("unknown key <%s>, try a single character, ctrl-x or one of: %s",
+		name, strings.Join(sortedKeyNames(), ", "))
+}
+
+func sortedKeyNames() []string {
+	// Same order as in the twin.KeyCode list, with space last
+	names := []string{}
+	for keyCode := twin.KeyEscape; keyCode <= twin.KeyAltW; keyCode++ {
+		names = append(names, KeyPress{KeyCode: keyCode}.String())
+	}
+	return append(names, "space")
+}
+
+// The inverse of ParseKeyPress()
+func (k KeyPress) String() string {
+	if k.Rune == ' ' {
+		return "space"
+	}
+
+	if k.Rune >= 1 && k.Rune <= 26 {
+		return "ctrl-" + string('a'+k.Rune-1)
+	}
+
+	if k.Rune != 0 {
+		return string(k.Rune)
+	}
+
+	for name, keyPress := range keyNames {
+		if keyPress == k {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("<key code %d>", k.KeyCode)
+}
+
+// Some keys to be pressed one after the other, like "gg"
+type KeySequence []KeyPress
+
+// Parses a key sequence. Keys are separated by spaces, like "ctrl-x ctrl-s".
+// Two character sequences can also be written without a space, like "gg" or
+// ":n".
+func ParseKeySequence(keys string) (KeySequence, error) {
+	keyPress, err := ParseKeyPress(keys)
+	if err == nil {
+		return KeySequence{keyPress}, nil
+	}
+
+	names := strings.Fields(keys)
+	if len(names) == 1 && utf8.RuneCountInString(keys) == 2 {
+		names = []string{}
+		for _, char := range keys {
+			names = append(names, string(char))
+		}
+	}
+	if len(names) < 2 {
+		// Report problems with single keys the same way as ParseKeyPress()
+		return nil, err
+	}
+
+	sequence := make(KeySequence, 0, len(names))
+	for _, name := range names {
+		keyPress, err := ParseKeyPress(name)
+		if err != nil {
+			return nil, err
+		}
+		sequence = append(sequence, keyPress)
+	}
+
+	return sequence, nil
+}
+
+// The inverse of ParseKeySequence()
+func (s KeySequence) String() string {
+	names := make([]string, 0, len(s))
+	for _, keyPress := range s {
+		names = append(names, keyPress.String())
+	}
+
+	if len(names) == 2 && utf8.RuneCountInString(names[0]) == 1 && utf8.RuneCountInString(names[1]) == 1 {
+		joined := names[0] + names[1]
+		_, err := ParseKeyPress(joined)
+		if err != nil {
+			// Not the name of some other key, write it the short way
+			return joined
+		}
+	}
+
+	return strings.Join(names, " ")
+}
+
+// Pass a key press on to the current mode. Remapped keys only apply when
+// viewing, so that they don't get in the way of typing into prompts.
+func (p *Pager) handleKeyPress(keyPress KeyPress) {
+	if p.isViewing() || p.isNotFound() {
+		remapped, found := p.KeyRemaps[keyPress]
+		if found {
+			log.Tracef("Key <%s> remapped to <%s>", keyPress, remapped)
+			keyPress = remapped
+		}
+	}
+
+	p.sendToMode(keyPress)
+}
+
+func (p *Pager) sendToMode(keyPress KeyPress) {
+	if keyPress.Rune != 0 {
+		p.mode.onRune(keyPress.Rune)
+	} else {
+		p.mode.onKey(keyPress.KeyCode)
+	}
+}
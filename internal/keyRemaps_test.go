@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func TestParseKeyPress(t *testing.T) {
+	for _, name := range []string{"j", "G", "space", "ctrl-f", "pagedown", "alt-left", "escape", "ö"} {
+		keyPress, err := ParseKeyPress(name)
+		assert.NilError(t, err)
+		assert.Equal(t, name, keyPress.String())
+	}
+
+	keyPress, err := ParseKeyPress("PageUp")
+	assert.NilError(t, err)
+	assert.Equal(t, KeyPress{KeyCode: twin.KeyPgUp}, keyPress)
+
+	_, err = ParseKeyPress("ctrl-1")
+	assert.ErrorContains(t, err, "unknown key <ctrl-1>")
+}
+
+func TestParseKeySequence(t *testing.T) {
+	for _, keys := range []string{"j", "gg", ":n", "ctrl-x ctrl-s", "z space", "u p"} {
+		sequence, err := ParseKeySequence(keys)
+		assert.NilError(t, err)
+		assert.Equal(t, keys, sequence.String())
+	}
+
+	sequence, err := ParseKeySequence("g g")
+	assert.NilError(t, err)
+	assert.Equal(t, "gg", sequence.String())
+
+	// Not "u" followed by "p"
+	sequence, err = ParseKeySequence("up")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, KeySequence{{KeyCode: twin.KeyUp}}, sequence)
+
+	// Probably a misspelled key name rather than six keys
+	_, err = ParseKeySequence("pgdown")
+	assert.ErrorContains(t, err, "unknown key <pgdown>")
+}
+
+func TestKeyRemapsOnlyWhenViewing(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "a\nb\nc\nd\ne\nf\n"))
+	pager.screen = twin.NewFakeScreen(20, 3)
+	pager.KeyRemaps = map[KeyPress]KeyPress{
+		{Rune: 'j'}: {KeyCode: twin.KeyDown},
+	}
+
+	pager.handleKeyPress(KeyPress{Rune: 'j'})
+	assert.Equal(t, 1, pager.lineIndex().Index())
+
+	// When searching, j should just be a j
+	pager.handleKeyPress(KeyPress{Rune: '/'})
+	pager.handleKeyPress(KeyPress{Rune: 'j'})
+	assert.Equal(t, "j", pager.searchString)
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+)
+
+// Render the full JSON object of a JSON log line, as extra screen lines after
+// the rendered log line itself.
+func (p *Pager) renderLogObject(line *reader.NumberedLine, numberPrefixLength int, firstWrapIndex int) []renderedLine {
+	objectLines := line.Line.LogObjectLines()
+	style := plainTextStyle.WithAttr(twin.AttrDim)
+	lineLength := len([]rune(line.Plain()))
+
+	rendered := make([]renderedLine, 0, len(objectLines))
+	for _, objectLine := range objectLines {
+		styledRunes := make([]twin.StyledRune, 0, len(objectLine))
+		for _, char := range objectLine {
+			styledRunes = append(styledRunes, twin.NewStyledRune(char, style))
+		}
+
+		var wrapped [][]twin.StyledRune
+		if p.WrapLongLines {
+			wrapped = wrapLine(p.contentsWidth()-numberPrefixLength, styledRunes)
+		} else {
+			wrapped = [][]twin.StyledRune{styledRunes}
+		}
+
+		for _, part := range wrapped {
+			rendered = append(rendered, renderedLine{
+				inputLineIndex: line.Index,
+				wrapIndex:      firstWrapIndex + len(rendered),
+				cells:          p.decorateLine(nil, numberPrefixLength, part),
+
+				// Not part of the line contents, so nothing to select here
+				runeOffset: lineLength,
+			})
+		}
+	}
+
+	return rendered
+}
+
+// Show or hide the full JSON object of the JSON log line at the top of the
+// screen, or at the selection cursor if we have one.
+func (p *Pager) toggleLogObject() {
+	lineIndex := p.lineIndex()
+	if p.selection != nil {
+		lineIndex = &p.selection.cursor.lineIndex
+	}
+	if lineIndex == nil {
+		return
+	}
+
+	line := p.Reader().GetLine(*lineIndex)
+	if line == nil || line.Line.LogObjectLines() == nil {
+		log.Debug("Not a JSON log line, nothing to toggle")
+		return
+	}
+
+	if p.expandedLogLines[line.Number] {
+		delete(p.expandedLogLines, line.Number)
+	} else {
+		p.expandedLogLines[line.Number] = true
+	}
+}
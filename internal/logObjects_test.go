@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func TestToggleLogObject(t *testing.T) {
+	logReader, err := reader.NewFromStream("", strings.NewReader(strings.Join([]string{
+		`{"ts": "10:00", "level": "info", "msg": "First"}`,
+		`{"ts": "10:01", "level": "info", "msg": "Second"}`,
+	}, "\n")), nil, reader.ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.NilError(t, logReader.Wait())
+
+	screen := twin.NewFakeScreen(40, 5)
+	pager := NewPager(logReader)
+	pager.ShowLineNumbers = false
+	pager.screen = screen
+
+	// Exit immediately
+	pager.Quit()
+
+	pager.StartPaging(screen, nil, nil)
+	pager.redraw("")
+	assert.Equal(t, "10:00 INFO  First", rowToString(screen.GetRow(0)))
+	assert.Equal(t, "10:01 INFO  Second", rowToString(screen.GetRow(1)))
+
+	pager.toggleLogObject()
+	pager.redraw("")
+	assert.Equal(t, "10:00 INFO  First", rowToString(screen.GetRow(0)))
+	assert.Equal(t, "{", rowToString(screen.GetRow(1)))
+	assert.Equal(t, `  "ts": "10:00",`, rowToString(screen.GetRow(2)))
+
+	// Scrolling should step through the object
+	pager.scrollPosition = pager.scrollPosition.NextLine(3)
+	pager.redraw("")
+	assert.Equal(t, `  "level": "info",`, rowToString(screen.GetRow(0)))
+	assert.Equal(t, "}", rowToString(screen.GetRow(2)))
+	assert.Equal(t, "10:01 INFO  Second", rowToString(screen.GetRow(3)))
+
+	pager.toggleLogObject()
+	pager.scrollPosition = scrollPosition{}
+	pager.redraw("")
+	assert.Equal(t, "10:01 INFO  Second", rowToString(screen.GetRow(1)))
+}
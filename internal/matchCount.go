@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/walles/moor/v2/internal/util"
+)
+
+// Something like "match 17/342" if the top of the screen shows a match,
+// otherwise something like "342 matches". Empty if we aren't searching.
+func (p *Pager) matchCountText() string {
+	if p.searchPattern == nil {
+		return ""
+	}
+
+	done := p.updateSearchHits()
+	total := p.searchHits.total()
+
+	totalString := util.FormatInt(total)
+	if !done {
+		// Still counting
+		totalString += "+"
+	}
+
+	firstVisible := p.lineIndex()
+	lastVisible := p.getLastVisiblePosition()
+	if firstVisible != nil && lastVisible != nil {
+		current := p.searchHits.matchesBefore(firstVisible.Index()) + 1
+		afterScreen := p.searchHits.matchesBefore(lastVisible.lineIndex(p).Index() + 1)
+		if current <= afterScreen {
+			return fmt.Sprintf("match %s/%s", util.FormatInt(current), totalString)
+		}
+	}
+
+	if total == 1 && done {
+		return "1 match"
+	}
+	return totalString + " matches"
+}
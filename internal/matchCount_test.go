@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func waitForMatchCount(pager *Pager) string {
+	for {
+		text := pager.matchCountText()
+		if !strings.Contains(text, "+") {
+			return text
+		}
+	}
+}
+
+func TestMatchCountText(t *testing.T) {
+	lines := []string{}
+	for i := 0; i < 20; i++ {
+		if i%5 == 2 {
+			lines = append(lines, "apa apa")
+		} else {
+			lines = append(lines, "bepa")
+		}
+	}
+
+	pager := NewPager(reader.NewFromTextForTesting("", strings.Join(lines, "\n")))
+	pager.screen = twin.NewFakeScreen(40, 5)
+	assert.Equal(t, "", pager.matchCountText())
+
+	pager.searchPattern = regexp.MustCompile("apa")
+	assert.Equal(t, "match 1/8", waitForMatchCount(pager))
+
+	// Lines 3-6 are on screen, none of them match
+	pager.scrollPosition = pager.scrollPosition.NextLine(3)
+	assert.Equal(t, "8 matches", waitForMatchCount(pager))
+
+	// Lines 5-8 are on screen, line 7 has the third and fourth matches
+	pager.scrollPosition = pager.scrollPosition.NextLine(2)
+	assert.Equal(t, "match 3/8", waitForMatchCount(pager))
+
+	// A new search should be counted from scratch
+	pager.searchPattern = regexp.MustCompile("bepa")
+	assert.Equal(t, "match 5/16", waitForMatchCount(pager))
+}
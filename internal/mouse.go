@@ -0,0 +1,332 @@
+package internal
+
+import (
+	"net/url"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/twin"
+)
+
+// Where the left mouse button went down. Clicks are handled when the button is
+// released without having moved, otherwise this is a drag.
+type _MousePress struct {
+	column int
+	row    int
+
+	// Last row we saw while dragging, for figuring out how far to scroll
+	lastRow int
+
+	// Dragging from the line numbers scrolls, dragging over the text selects
+	inLineNumbers bool
+
+	// Pressing or dragging in the scrollbar scrolls to that position
+	inScrollbar bool
+
+	// Where in the text the press was, nil if not on any text
+	textPosition *selectionPoint
+
+	dragged bool
+}
+
+func (p *Pager) onMouseEvent(event twin.EventMouse) {
+	column, row := event.Position()
+
+	switch event.Buttons() {
+	case twin.MouseWheelUp:
+		// Clipping is done in _Redraw()
+		p.scrollPosition = p.scrollPosition.PreviousLine(1)
+		return
+
+	case twin.MouseWheelDown:
+		// Clipping is done in _Redraw()
+		p.scrollPosition = p.scrollPosition.NextLine(1)
+		return
+
+	case twin.MouseWheelLeft:
+		p.moveRight(-p.SideScrollAmount)
+		return
+
+	case twin.MouseWheelRight:
+		p.moveRight(p.SideScrollAmount)
+		return
+
+	case twin.MouseButtonLeft:
+		// Handled below
+
+	default:
+		log.Tracef("Unhandled mouse buttons %d", event.Buttons())
+		return
+	}
+
+	if !p.isViewing() && !p.isNotFound() {
+		// Clicking around while typing into some prompt is not supported
+		p.mousePress = nil
+		return
+	}
+
+	switch event.Action() {
+	case twin.MousePress:
+		p.onMousePress(column, row)
+
+	case twin.MouseDrag:
+		p.onMouseDrag(column, row)
+
+	case twin.MouseRelease:
+		press := p.mousePress
+		p.mousePress = nil
+		if press == nil || press.inScrollbar {
+			return
+		}
+		if press.dragged {
+			// Like in most terminals, selecting is copying
+			p.copySelection()
+			return
+		}
+		if press.column != column || press.row != row {
+			return
+		}
+
+		p.onMouseClick(column, row)
+	}
+}
+
+func (p *Pager) onMousePress(column int, row int) {
+	// A new press means a new selection
+	p.selection = nil
+
+	press := _MousePress{column: column, row: row, lastRow: row}
+
+	width, _ := p.screen.Size()
+	if p.ShowScrollbar && column == width-1 && row < p.visibleHeight() {
+		press.inScrollbar = true
+		p.mousePress = &press
+		p.scrollToScrollbarRow(row)
+		return
+	}
+
+	renderedLines, _ := p.renderLines()
+	if row < len(renderedLines) {
+		press.inLineNumbers = column < p.renderedNumberPrefixLength(renderedLines)
+		textPosition := p.textPositionAt(renderedLines, column, row)
+		press.textPosition = &textPosition
+	}
+
+	p.mousePress = &press
+}
+
+func (p *Pager) onMouseDrag(column int, row int) {
+	press := p.mousePress
+	if press == nil {
+		// Dragging something we didn't see the start of, never mind
+		return
+	}
+
+	if press.inScrollbar {
+		p.scrollToScrollbarRow(row)
+		return
+	}
+
+	if press.inLineNumbers {
+		p.dragScroll(row)
+		return
+	}
+
+	if press.textPosition == nil {
+		// Started below the text, nothing to select
+		return
+	}
+	press.dragged = true
+
+	// Scroll when dragging to the top or bottom edge, so that more than one
+	// screen can be selected
+	if row <= 0 {
+		p.scrollPosition = p.scrollPosition.PreviousLine(1)
+		p.handleScrolledUp()
+		row = 0
+	} else if row >= p.visibleHeight()-1 {
+		p.scrollPosition = p.scrollPosition.NextLine(1)
+		p.handleScrolledDown()
+		row = p.visibleHeight() - 1
+	}
+
+	renderedLines, _ := p.renderLines()
+	if len(renderedLines) == 0 {
+		return
+	}
+	row = min(row, len(renderedLines)-1)
+
+	p.selection = &_Selection{
+		anchor: *press.textPosition,
+		cursor: p.textPositionAt(renderedLines, column, row),
+	}
+}
+
+// Drag the contents along with the mouse pointer
+func (p *Pager) dragScroll(row int) {
+	press := p.mousePress
+
+	delta := row - press.lastRow
+	press.lastRow = row
+	if delta == 0 {
+		return
+	}
+	press.dragged = true
+
+	if delta > 0 {
+		// Mouse moved down, contents should follow
+		p.scrollPosition = p.scrollPosition.PreviousLine(delta)
+		p.handleScrolledUp()
+	} else {
+		p.scrollPosition = p.scrollPosition.NextLine(-delta)
+		p.handleScrolledDown()
+	}
+}
+
+// How many screen columns are used for line numbers
+func (p *Pager) renderedNumberPrefixLength(renderedLines []renderedLine) int {
+	if len(renderedLines) == 0 {
+		return 0
+	}
+
+	lastLine := p.Reader().GetLine(renderedLines[len(renderedLines)-1].inputLineIndex)
+	if lastLine == nil {
+		return 0
+	}
+
+	return p.getLineNumberPrefixLength(lastLine.Number)
+}
+
+// Which text is at this screen position? Positions to the left of the text
+// map to the start of the line, positions to the right of it map to just
+// past its end.
+//
+// The row must be less than len(renderedLines).
+func (p *Pager) textPositionAt(renderedLines []renderedLine, column int, row int) selectionPoint {
+	line := renderedLines[row]
+	contentsColumn := column - p.renderedNumberPrefixLength(renderedLines) + p.leftColumnZeroBased
+
+	position := selectionPoint{
+		lineIndex: line.inputLineIndex,
+		runeIndex: line.runeOffset,
+	}
+
+	cellColumn := 0
+	for _, cell := range line.contents {
+		if contentsColumn < cellColumn+cell.Width() {
+			return position
+		}
+
+		cellColumn += cell.Width()
+		position.runeIndex++
+	}
+
+	return position
+}
+
+// Click the status bar to toggle showing the help, click a line number to set
+// a mark there, click an archive member to open it, or click a hyperlink to
+// open it.
+func (p *Pager) onMouseClick(column int, row int) {
+	_, height := p.screen.Size()
+	if p.ShowStatusBar && row == height-1 {
+		p.leaveNotFound()
+		if p.isShowingHelp {
+			p.Quit()
+		} else {
+			p.showHelp()
+		}
+		return
+	}
+
+	renderedLines, _ := p.renderLines()
+	if row >= len(renderedLines) {
+		// Clicked below the last line
+		return
+	}
+	clickedLine := renderedLines[row]
+
+	inLineNumbers := column < p.renderedNumberPrefixLength(renderedLines)
+	if inLineNumbers && !p.isShowingHelp && clickedLine.wrapIndex == 0 {
+		p.mode = PagerModeMark{pager: p, lineIndex: &clickedLine.inputLineIndex}
+		p.setTargetLine(nil)
+		return
+	}
+
+	if !p.isShowingHelp && p.reader.IsArchiveListing() {
+		p.openArchiveMember(clickedLine.inputLineIndex)
+		return
+	}
+
+	hyperlink := hyperlinkAt(clickedLine.cells, column)
+	if hyperlink != "" {
+		openURL(hyperlink)
+	}
+}
+
+// Returns the URL of the hyperlink at the given screen column, or an empty
+// string if there is none.
+func hyperlinkAt(cells []twin.StyledRune, column int) string {
+	cellColumn := 0
+	for _, cell := range cells {
+		if column < cellColumn+cell.Width() {
+			url := cell.Style.HyperlinkURL()
+			if url == nil {
+				return ""
+			}
+			return *url
+		}
+
+		cellColumn += cell.Width()
+	}
+
+	return ""
+}
+
+// Open a URL in the user's browser, or whatever else handles it
+func openURL(urlString string) {
+	parsed, err := url.Parse(urlString)
+	if err != nil {
+		log.Info("Not opening unparsable URL <", urlString, ">: ", err)
+		return
+	}
+
+	switch parsed.Scheme {
+	case "http", "https", "file", "mailto":
+		// These are fine
+	default:
+		// Could be anything, don't just run it
+		log.Info("Not opening URL with unsupported scheme <", urlString, ">")
+		return
+	}
+
+	var command *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		command = exec.Command("open", urlString)
+	case "windows":
+		command = exec.Command("rundll32", "url.dll,FileProtocolHandler", urlString)
+	default:
+		command = exec.Command("xdg-open", urlString)
+	}
+
+	log.Debug("Opening URL: ", command.String())
+	err = command.Start()
+	if err != nil {
+		log.Info("Failed to open URL <", urlString, ">: ", err)
+		return
+	}
+
+	go func() {
+		defer func() {
+			PanicHandler("openURL()", recover(), debug.Stack())
+		}()
+
+		err := command.Wait()
+		if err != nil {
+			log.Info("Opening URL <", urlString, "> failed: ", err)
+		}
+	}()
+}
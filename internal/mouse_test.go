@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func newPagerForMouseTesting(t *testing.T) *Pager {
+	t.Helper()
+
+	pager := NewPager(reader.NewFromTextForTesting("", strings.Repeat("line\n", 100)))
+	pager.screen = twin.NewFakeScreen(20, 10)
+	pager.marks = map[rune]linemetadata.Number{}
+
+	return pager
+}
+
+func TestMouseDrag(t *testing.T) {
+	pager := newPagerForMouseTesting(t)
+	pager.scrollPosition = pager.scrollPosition.NextLine(50)
+
+	// Press on the line numbers
+	pager.onMousePress(1, 5)
+	assert.Assert(t, pager.mousePress.inLineNumbers)
+
+	// Dragging up should scroll down, just like on a touch screen
+	pager.onMouseDrag(1, 2)
+	assert.Equal(t, 53, pager.lineIndex().Index())
+
+	pager.onMouseDrag(1, 4)
+	assert.Equal(t, 51, pager.lineIndex().Index())
+	assert.Assert(t, pager.mousePress.dragged)
+	assert.Assert(t, pager.selection == nil)
+}
+
+func TestMouseDragSelects(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "first line\nsecond line\nthird line"))
+	pager.screen = twin.NewFakeScreen(20, 10)
+
+	// Line numbers take four columns, so this is the "r" in "first"
+	pager.onMousePress(6, 0)
+	pager.onMouseDrag(7, 1)
+
+	// Up to and including the "o" in "second"
+	assert.Equal(t, "rst line\nseco", pager.selectedText())
+
+	// The selection should be visible
+	rendered, _ := pager.renderScreenLines()
+	assert.Equal(t, twin.StyleDefault, rendered[0][5].Style)
+	assert.Equal(t, twin.StyleDefault.WithAttr(twin.AttrReverse), rendered[0][6].Style)
+	assert.Equal(t, twin.StyleDefault.WithAttr(twin.AttrReverse), rendered[1][7].Style)
+	assert.Equal(t, twin.StyleDefault, rendered[1][8].Style)
+}
+
+func TestMouseClickLineNumberSetsMark(t *testing.T) {
+	pager := newPagerForMouseTesting(t)
+	pager.scrollPosition = pager.scrollPosition.NextLine(10)
+
+	// Click the line number on the fourth screen line
+	pager.onMouseClick(1, 3)
+	assert.Assert(t, !pager.isViewing())
+
+	pager.mode.onRune('a')
+	assert.Assert(t, pager.isViewing())
+	assert.Equal(t, 13, pager.marks['a'].AsZeroBased())
+
+	// The pager itself should not have moved
+	assert.Equal(t, 10, pager.lineIndex().Index())
+}
+
+func TestMouseClickStatusBarTogglesHelp(t *testing.T) {
+	pager := newPagerForMouseTesting(t)
+
+	pager.onMouseClick(3, 9)
+	assert.Assert(t, pager.isShowingHelp)
+
+	pager.onMouseClick(3, 9)
+	assert.Assert(t, !pager.isShowingHelp)
+	assert.Assert(t, !pager.quit)
+}
+
+func TestHyperlinkAt(t *testing.T) {
+	url := "https://example.com/"
+	linked := twin.StyleDefault.WithHyperlink(&url)
+	cells := []twin.StyledRune{
+		twin.NewStyledRune('午', twin.StyleDefault),
+		twin.NewStyledRune('a', linked),
+		twin.NewStyledRune('b', twin.StyleDefault),
+	}
+
+	assert.Equal(t, "", hyperlinkAt(cells, 0))
+	assert.Equal(t, "", hyperlinkAt(cells, 1))
+	assert.Equal(t, url, hyperlinkAt(cells, 2))
+	assert.Equal(t, "", hyperlinkAt(cells, 3))
+	assert.Equal(t, "", hyperlinkAt(cells, 4))
+}
@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+)
+
+// Per-file pager state, saved when switching away from a file and restored when
+// switching back to it.
+type _FileState struct {
+	// Nil until the file is first switched to, see openReader
+	reader *reader.ReaderImpl
+
+	// Opens the reader on first switch. Nil once the reader is open.
+	openReader func() (*reader.ReaderImpl, error)
+
+	// File name, available before the reader is open
+	name string
+
+	scrollPosition      scrollPosition
+	leftColumnZeroBased int
+	targetLine          *linemetadata.Index
+	marks               map[rune]linemetadata.Number
+	expandedLogLines    map[linemetadata.Number]bool
+
+	searchString   string
+	searchPattern  *regexp.Regexp
+	filterPattern  *regexp.Regexp
+	filterString   string
+	earlierFilters []filter
+}
+
+// A file that isn't opened until the pager first switches to it
+type LazyReader struct {
+	Name string
+	Open func() (*reader.ReaderImpl, error)
+}
+
+func newFileState(r *reader.ReaderImpl) _FileState {
+	name := ""
+	if r != nil && r.Name != nil {
+		name = *r.Name
+	}
+
+	state := newLazyFileState(LazyReader{Name: name})
+	state.reader = r
+	return state
+}
+
+func newLazyFileState(lazyReader LazyReader) _FileState {
+	scrollPositionName := "Pager"
+	if len(lazyReader.Name) > 0 {
+		scrollPositionName = "Pager " + lazyReader.Name
+	}
+
+	return _FileState{
+		openReader:     lazyReader.Open,
+		name:           lazyReader.Name,
+		scrollPosition: newScrollPosition(scrollPositionName),
+	}
+}
+
+// Switch to the next file, if there is one
+func (p *Pager) nextFile() {
+	p.switchToFile(p.currentFileIndex + 1)
+}
+
+// Switch to the previous file, if there is one
+func (p *Pager) previousFile() {
+	p.switchToFile(p.currentFileIndex - 1)
+}
+
+// Save the state of the current file, then restore the state of the file with
+// the given index.
+func (p *Pager) switchToFile(fileIndex int) {
+	if fileIndex < 0 || fileIndex >= len(p.files) {
+		log.Debugf("No file %d to switch to, have %d files", fileIndex, len(p.files))
+		return
+	}
+
+	if fileIndex == p.currentFileIndex {
+		return
+	}
+
+	if p.isShowingHelp {
+		// Switching files while showing the help text would mess up the
+		// pre-help state
+		return
+	}
+
+	if p.files[fileIndex].reader == nil {
+		openedReader, err := p.files[fileIndex].openReader()
+		if err != nil {
+			log.Warn("Failed to open ", p.files[fileIndex].name, ": ", err)
+			p.showMessage(err.Error())
+			return
+		}
+
+		p.files[fileIndex].reader = openedReader
+		p.files[fileIndex].openReader = nil
+		p.startReaderListeners(openedReader)
+	}
+
+	p.files[p.currentFileIndex] = _FileState{
+		reader:              p.reader,
+		name:                p.files[p.currentFileIndex].name,
+		scrollPosition:      p.scrollPosition,
+		leftColumnZeroBased: p.leftColumnZeroBased,
+		targetLine:          p.TargetLine,
+		marks:               p.marks,
+		expandedLogLines:    p.expandedLogLines,
+		searchString:        p.searchString,
+		searchPattern:       p.searchPattern,
+		filterPattern:       p.filterPattern,
+		filterString:        p.filterString,
+		earlierFilters:      p.earlierFilters,
+	}
+
+	newState := p.files[fileIndex]
+	if newState.marks == nil {
+		newState.marks = make(map[rune]linemetadata.Number)
+	}
+	if newState.expandedLogLines == nil {
+		newState.expandedLogLines = make(map[linemetadata.Number]bool)
+	}
+
+	p.currentFileIndex = fileIndex
+	p.reader = newState.reader
+	p.scrollPosition = newState.scrollPosition
+	p.leftColumnZeroBased = newState.leftColumnZeroBased
+	p.marks = newState.marks
+	p.expandedLogLines = newState.expandedLogLines
+	p.searchString = newState.searchString
+	p.searchPattern = newState.searchPattern
+	p.filterPattern = newState.filterPattern
+	p.filterString = newState.filterString
+	p.earlierFilters = newState.earlierFilters
+
+	// New backing reader, so the filtering cache needs to go
+	p.filteringReader = FilteringReader{
+		BackingReader:  p.reader,
+		FilterPattern:  &p.filterPattern,
+		filterString:   &p.filterString,
+		earlierFilters: &p.earlierFilters,
+		context:        &p.FilterContext,
+	}
+
+	p.mode = PagerModeViewing{pager: p}
+	p.watching = false
+	p.setTargetLine(newState.targetLine)
+
+	log.Debugf("Switched to file %d/%d", fileIndex+1, len(p.files))
+}
+
+// "file 2 of 5", or an empty string if we're paging only one file
+func (p *Pager) fileStatusText() string {
+	if len(p.files) < 2 {
+		return ""
+	}
+
+	return fmt.Sprintf("file %d of %d", p.currentFileIndex+1, len(p.files))
+}
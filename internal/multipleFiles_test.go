@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"archive/zip"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func createTwoFilesPager(t *testing.T) *Pager {
+	first := reader.NewFromTextForTesting("first.txt", "a\nb\nc\nd\ne\nf")
+	second := reader.NewFromTextForTesting("second.txt", "1\n2\n3\n4\n5\n6")
+	assert.NilError(t, first.Wait())
+	assert.NilError(t, second.Wait())
+
+	pager := NewPagerForReaders([]*reader.ReaderImpl{first, second})
+	pager.screen = twin.NewFakeScreen(20, 3)
+	pager.marks = make(map[rune]linemetadata.Number)
+
+	return pager
+}
+
+func TestSwitchFiles(t *testing.T) {
+	pager := createTwoFilesPager(t)
+	assert.Equal(t, "file 1 of 2", pager.fileStatusText())
+
+	pager.mode.onRune(':')
+	pager.mode.onRune('n')
+	assert.Equal(t, "file 2 of 2", pager.fileStatusText())
+	assert.Equal(t, "1", pager.Reader().GetLine(*pager.lineIndex()).Plain())
+	assert.Assert(t, pager.isViewing())
+
+	// No more files, this should be a no-op
+	pager.mode.onRune(':')
+	pager.mode.onRune('n')
+	assert.Equal(t, "file 2 of 2", pager.fileStatusText())
+
+	pager.mode.onRune(':')
+	pager.mode.onRune('p')
+	assert.Equal(t, "file 1 of 2", pager.fileStatusText())
+	assert.Equal(t, "a", pager.Reader().GetLine(*pager.lineIndex()).Plain())
+}
+
+func TestSwitchFilesKeepsState(t *testing.T) {
+	pager := createTwoFilesPager(t)
+
+	// Scroll down and search in the first file
+	pager.scrollPosition = pager.scrollPosition.NextLine(2)
+	pager.searchString = "c"
+	pager.searchPattern = toPattern(pager.searchString)
+	pager.marks['x'] = linemetadata.NumberFromZeroBased(2)
+
+	pager.nextFile()
+	assert.Equal(t, 0, pager.lineIndex().Index())
+	assert.Equal(t, "", pager.searchString)
+	assert.Assert(t, pager.searchPattern == nil)
+	assert.Equal(t, 0, len(pager.marks))
+
+	pager.previousFile()
+	assert.Equal(t, 2, pager.lineIndex().Index())
+	assert.Equal(t, "c", pager.searchString)
+	assert.Assert(t, pager.searchPattern != nil)
+	_, hasMark := pager.marks['x']
+	assert.Assert(t, hasMark)
+}
+
+func TestSwitchFilesOpensLazily(t *testing.T) {
+	first := reader.NewFromTextForTesting("first.txt", "a\nb\nc")
+	assert.NilError(t, first.Wait())
+
+	openCount := 0
+	pager := NewPagerForLazyReaders(first, []LazyReader{
+		{
+			Name: "second.txt",
+			Open: func() (*reader.ReaderImpl, error) {
+				openCount++
+				second := reader.NewFromTextForTesting("second.txt", "1\n2\n3")
+				return second, second.Wait()
+			},
+		},
+		{
+			Name: "missing.txt",
+			Open: func() (*reader.ReaderImpl, error) {
+				return nil, os.ErrNotExist
+			},
+		},
+	})
+	screen := twin.NewFakeScreen(30, 3)
+	pager.screen = screen
+	pager.marks = make(map[rune]linemetadata.Number)
+	assert.Equal(t, 0, openCount)
+
+	pager.nextFile()
+	assert.Equal(t, 1, openCount)
+	assert.Equal(t, "1", pager.Reader().GetLine(*pager.lineIndex()).Plain())
+
+	// Switching back and forth should not reopen the file
+	pager.previousFile()
+	pager.nextFile()
+	assert.Equal(t, 1, openCount)
+
+	// Failing to open a file should leave us where we are, and say why
+	pager.nextFile()
+	assert.Equal(t, "file 2 of 3", pager.fileStatusText())
+	assert.Equal(t, "1", pager.Reader().GetLine(*pager.lineIndex()).Plain())
+	pager.mode.drawFooter("", "")
+	assert.Equal(t, os.ErrNotExist.Error(), rowToString(screen.GetRow(2)))
+
+	// The message should go away on the next key press
+	pager.mode.onKey(twin.KeyDown)
+	assert.Assert(t, pager.isViewing())
+}
+
+func TestSingleFileStatusText(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "a"))
+	assert.Equal(t, "", pager.fileStatusText())
+}
+
+func TestOpenArchiveMember(t *testing.T) {
+	archiveName := path.Join(t.TempDir(), "bundle.zip")
+	archive, err := os.Create(archiveName)
+	assert.NilError(t, err)
+	zipWriter := zip.NewWriter(archive)
+	for _, name := range []string{"one.txt", "two.txt"} {
+		writer, err := zipWriter.Create(name)
+		assert.NilError(t, err)
+		_, err = writer.Write([]byte("This is " + name + "\n"))
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, zipWriter.Close())
+	assert.NilError(t, archive.Close())
+
+	listing, err := reader.NewFromFilename(archiveName, nil, reader.ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.NilError(t, listing.Wait())
+
+	pager := NewPager(listing)
+	pager.screen = twin.NewFakeScreen(40, 5)
+	pager.marks = make(map[rune]linemetadata.Number)
+
+	// Pick the second member
+	pager.mode.onRune('o')
+	pager.mode.onRune('j')
+	pager.mode.onKey(twin.KeyEnter)
+	assert.Equal(t, "file 2 of 2", pager.fileStatusText())
+	assert.NilError(t, pager.reader.Wait())
+	assert.Equal(t, "This is two.txt", pager.Reader().GetLine(*pager.lineIndex()).Plain())
+	assert.Assert(t, pager.isViewing())
+
+	// Opening it again should just switch to it
+	pager.previousFile()
+	pager.openArchiveMember(linemetadata.IndexFromZeroBased(1))
+	assert.Equal(t, "file 2 of 2", pager.fileStatusText())
+}
@@ -34,6 +34,10 @@ const (
 
 type eventSpinnerUpdate struct {
 	spinner string
+
+	// Which reader is this spinner for? Only the current reader's spinner is
+	// shown.
+	reader *reader.ReaderImpl
 }
 
 type eventMoreLinesAvailable struct{}
@@ -58,13 +62,43 @@ type Pager struct {
 
 	searchString  string
 	searchPattern *regexp.Regexp
+	searchOptions searchOptions
 	filterPattern *regexp.Regexp
 
+	// The current filter as typed, with any "|" or "!" prefix. Its pattern is
+	// in filterPattern.
+	filterString string
+
+	// Filters added before the current one, shown as chips in the footer
+	earlierFilters []filter
+
+	// Search patterns that stay highlighted in their own colors, oldest first
+	pins []pin
+
+	// Past search and filter expressions, recalled using the arrow keys
+	searchHistory *searchHistory
+
+	// True while following the end of the input, waiting for a new line
+	// matching watchedPattern. Lines before watchedLineCount have been
+	// checked, and the last of those contained watchedLastLine.
+	//
+	// Until watchCaughtUp, the file is still being read, and the lines coming
+	// in were already there when watching started.
+	watching         bool
+	watchedPattern   *regexp.Regexp
+	watchedLineCount int
+	watchedLastLine  string
+	watchCaughtUp    bool
+
+	// Ring the terminal bell when watching finds a new search hit
+	WatchBell bool
+
 	// We used to have a "Following" field here. If you want to follow, set
 	// TargetLineNumber to LineNumberMax() instead, see below.
 
 	isShowingHelp bool
 	preHelpState  *_PreHelpState
+	helpReader    *reader.ReaderImpl
 
 	// NewPager shows lines by default, this field can hide them
 	ShowLineNumbers bool
@@ -72,10 +106,20 @@ type Pager struct {
 	StatusBarStyle StatusBarOption
 	ShowStatusBar  bool
 
+	// Show a scrollbar at the right edge of the screen
+	ShowScrollbar bool
+
+	// Search hits, for the status bar match count and the scrollbar
+	searchHits *_SearchHits
+
 	UnprintableStyle textstyles.UnprintableStyleT
 
 	WrapLongLines bool
 
+	// When filtering, also show this many lines before and after each
+	// matching line, like "grep -C"
+	FilterContext int
+
 	// Ref: https://github.com/walles/moor/issues/113
 	QuitIfOneScreen bool
 
@@ -85,6 +129,24 @@ type Pager struct {
 
 	SideScrollAmount int // Should be positive
 
+	// When viewing, pressing a key in this map acts like pressing the key it
+	// maps to
+	KeyRemaps map[KeyPress]KeyPress
+
+	// Key sequences, as formatted by KeySequence.String(), mapped to what
+	// they are bound to. See actions.go for the defaults, and use BindKeys()
+	// to add more.
+	keyBindings map[string]keyBinding
+
+	// The start of a multi key sequence, like the first "g" of "gg"
+	pendingKeys KeySequence
+
+	// Non-nil while the left mouse button is down
+	mousePress *_MousePress
+
+	// Text selected with the mouse or in select mode, nil if none
+	selection *_Selection
+
 	// If non-nil, scroll to this line as soon as possible. Set this value to
 	// IndexMax() to follow the end of the input (tail).
 	//
@@ -108,7 +170,17 @@ type Pager struct {
 	// Bookmarks that you can come back to.
 	//
 	// Ref: https://github.com/walles/moor/issues/175
-	marks map[rune]scrollPosition
+	marks map[rune]linemetadata.Number
+
+	// JSON log lines currently showing their full JSON objects, by original
+	// line number so that filtering doesn't affect them
+	expandedLogLines map[linemetadata.Number]bool
+
+	// All files being paged. The state of the current file lives in the Pager
+	// fields above, and is only copied into files[currentFileIndex] when
+	// switching to some other file.
+	files            []_FileState
+	currentFileIndex int
 
 	AfterExit func() error
 }
@@ -119,78 +191,41 @@ type _PreHelpState struct {
 	targetLine          *linemetadata.Index
 }
 
-var _HelpReader = reader.NewFromTextForTesting("Help", `
-Welcome to Moor, the nice pager!
-
-Miscellaneous
--------------
-* Press 'q' or 'ESC' to quit
-* Press 'w' to toggle wrapping of long lines
-* Press '=' to toggle showing the status bar at the bottom
-* Press 'v' to edit the file in your favorite editor
-
-Moving around
--------------
-* Arrow keys
-* Alt key plus left / right arrow steps one column at a time
-* Left / right can be used to hide / show line numbers
-* Home and End for start / end of the document
-* 'g' for going to a specific line number
-* 'm' sets a mark, you will be asked for a letter to label it with
-* ' (single quote) jumps to the mark
-* CTRL-p moves to the previous line
-* CTRL-n moves to the next line
-* PageUp / 'b' and PageDown / 'f'
-* SPACE moves down a page
-* < / 'gg' to go to the start of the document
-* > / 'G' to go to the end of the document
-* Half page 'u'p / 'd'own, or CTRL-u / CTRL-d
-* RETURN moves down one line
-
-Filtering
----------
-Type '&' to start filtering, then type your filter expression.
-
-While filtering, arrow keys, PageUp, PageDown, Home and End work as usual.
-
-Press 'ESC' or RETURN to exit filtering mode.
-
-Searching
----------
-* Type / to start searching, then type what you want to find
-* Type ? to search backwards, then type what you want to find
-* Type RETURN to stop searching, or ESC to skip back to where the search started
-* Find next by typing 'n' (for "next")
-* Find previous by typing SHIFT-N or 'p' (for "previous")
-* Search is case sensitive if it contains any UPPER CASE CHARACTERS
-* Search is interpreted as a regexp if it is a valid one
-
-Reporting bugs
---------------
-File issues at https://github.com/walles/moor/issues, or post
-questions to johan.walles@gmail.com.
-
-Installing Moor as your default pager
--------------------------------------
-Put the following line in your ~/.bashrc, ~/.bash_profile or ~/.zshrc:
-  export PAGER=moor
-
-Source Code
------------
-Available at https://github.com/walles/moor/.
-`)
-
 // NewPager creates a new Pager with default settings
 func NewPager(r *reader.ReaderImpl) *Pager {
-	var name string
-	if r == nil || r.Name == nil || len(*r.Name) == 0 {
-		name = "Pager"
-	} else {
-		name = "Pager " + *r.Name
+	return NewPagerForReaders([]*reader.ReaderImpl{r})
+}
+
+// NewPagerForReaders creates a new Pager paging multiple files, starting with
+// the first one. Switch between them using :n and :p.
+func NewPagerForReaders(readers []*reader.ReaderImpl) *Pager {
+	if len(readers) == 0 {
+		panic("At least one reader required")
+	}
+
+	files := make([]_FileState, 0, len(readers))
+	for _, r := range readers {
+		files = append(files, newFileState(r))
+	}
+
+	return newPagerForFiles(files)
+}
+
+// NewPagerForLazyReaders creates a new Pager paging the first reader. The other
+// files are opened when the pager first switches to them.
+func NewPagerForLazyReaders(first *reader.ReaderImpl, more []LazyReader) *Pager {
+	files := make([]_FileState, 0, 1+len(more))
+	files = append(files, newFileState(first))
+	for _, lazyReader := range more {
+		files = append(files, newLazyFileState(lazyReader))
 	}
 
+	return newPagerForFiles(files)
+}
+
+func newPagerForFiles(files []_FileState) *Pager {
 	pager := Pager{
-		reader:           r,
+		reader:           files[0].reader,
 		quit:             false,
 		ShowLineNumbers:  true,
 		ShowStatusBar:    true,
@@ -198,13 +233,19 @@ func NewPager(r *reader.ReaderImpl) *Pager {
 		SideScrollAmount: 16,
 		ScrollLeftHint:   twin.NewStyledRune('<', twin.StyleDefault.WithAttr(twin.AttrReverse)),
 		ScrollRightHint:  twin.NewStyledRune('>', twin.StyleDefault.WithAttr(twin.AttrReverse)),
-		scrollPosition:   newScrollPosition(name),
+		scrollPosition:   files[0].scrollPosition,
+		files:            files,
+		searchHistory:    &searchHistory{},
+		keyBindings:      defaultKeyBindings(),
 	}
 
 	pager.mode = PagerModeViewing{pager: &pager}
 	pager.filteringReader = FilteringReader{
-		BackingReader: r,
-		FilterPattern: &pager.filterPattern,
+		BackingReader:  pager.reader,
+		FilterPattern:  &pager.filterPattern,
+		filterString:   &pager.filterString,
+		earlierFilters: &pager.earlierFilters,
+		context:        &pager.FilterContext,
 	}
 
 	return &pager
@@ -220,6 +261,16 @@ func (p *Pager) visibleHeight() int {
 	return height
 }
 
+// How many screen columns are available for contents and line numbers? Depends
+// on screen width and whether or not the scrollbar is visible.
+func (p *Pager) contentsWidth() int {
+	width, _ := p.screen.Size()
+	if p.ShowScrollbar {
+		return width - 1
+	}
+	return width
+}
+
 // How many cells are needed for this line number?
 //
 // Returns 0 if line numbers are disabled.
@@ -242,9 +293,13 @@ func (p *Pager) getLineNumberPrefixLength(lineNumber linemetadata.Number) int {
 
 // Draw the footer string at the bottom using the status bar style
 func (p *Pager) setFooter(footer string) {
+	p.setFooterFrom(0, footer)
+}
+
+// Like setFooter(), but starting at the given screen column
+func (p *Pager) setFooterFrom(pos int, footer string) {
 	width, height := p.screen.Size()
 
-	pos := 0
 	for _, token := range footer {
 		pos += p.screen.SetCell(pos, height-1, twin.NewStyledRune(token, statusbarStyle))
 	}
@@ -295,9 +350,18 @@ func (p *Pager) moveRight(delta int) {
 	}
 }
 
+// The index of the first line with the given line number or a later one.
+// Filtered lines keep their original line numbers.
+func (p *Pager) indexOfLineNumber(number linemetadata.Number) linemetadata.Index {
+	if p.isShowingHelp {
+		return linemetadata.IndexFromZeroBased(number.AsZeroBased())
+	}
+	return p.filteringReader.indexOfLineNumber(number)
+}
+
 func (p *Pager) Reader() reader.Reader {
 	if p.isShowingHelp {
-		return _HelpReader
+		return p.helpReader
 	}
 	return &p.filteringReader
 }
@@ -356,67 +420,32 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 
 	p.screen = screen
 	p.mode = PagerModeViewing{pager: p}
-	p.marks = make(map[rune]scrollPosition)
+	p.marks = make(map[rune]linemetadata.Number)
+	p.expandedLogLines = make(map[linemetadata.Number]bool)
 
 	// Make sure the reader knows how many lines we want
 	p.setTargetLine(p.TargetLine)
 
-	go func() {
-		defer func() {
-			PanicHandler("StartPaging()/moreLinesAvailable", recover(), debug.Stack())
-		}()
-
-		for range p.reader.MoreLinesAdded {
-			// Notify the main loop about the new lines so it can show them
-			screen.Events() <- eventMoreLinesAvailable{}
-
-			// Delay updates a bit so that we don't waste time refreshing
-			// the screen too often.
-			//
-			// Note that the delay is *after* reacting, this way single-line
-			// updates are reacted to immediately, and the first output line
-			// read will appear on screen without delay.
-			time.Sleep(200 * time.Millisecond)
-		}
-	}()
-
-	go func() {
-		defer func() {
-			PanicHandler("StartPaging()/spinner", recover(), debug.Stack())
-		}()
-
-		// Spin the spinner as long as contents is still loading
-		spinnerFrames := [...]string{"/.\\", "-o-", "\\O/", "| |"}
-		spinnerIndex := 0
-		for !p.reader.Done.Load() {
-			screen.Events() <- eventSpinnerUpdate{spinnerFrames[spinnerIndex]}
-			spinnerIndex++
-			if spinnerIndex >= len(spinnerFrames) {
-				spinnerIndex = 0
-			}
-
-			time.Sleep(200 * time.Millisecond)
-		}
-
-		// Empty our spinner, loading done!
-		screen.Events() <- eventSpinnerUpdate{""}
-	}()
-
-	go func() {
-		defer func() {
-			PanicHandler("StartPaging()/maybeDone", recover(), debug.Stack())
-		}()
-
-		for range p.reader.MaybeDone {
-			screen.Events() <- eventMaybeDone{}
+	for _, file := range p.files {
+		if file.reader == nil {
+			// Not opened yet, listeners start when we switch to it
+			continue
 		}
-	}()
+		p.startReaderListeners(file.reader)
+	}
 
 	log.Info("Entering pager main loop...")
 
 	// Main loop
 	spinner := ""
+	spinnerReader := p.reader
 	for !p.quit {
+		if p.reader != spinnerReader {
+			// We switched files, the old spinner doesn't apply any more
+			spinner = ""
+			spinnerReader = p.reader
+		}
+
 		if len(screen.Events()) == 0 {
 			// Nothing more to process for now, redraw the screen
 			p.redraw(spinner)
@@ -426,7 +455,7 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 			//
 			// Note that we do the slow (atomic) checks only if the fast ones (no locking
 			// required) passed
-			if p.QuitIfOneScreen && !p.isShowingHelp && p.reader.Done.Load() && p.reader.HighlightingDone.Load() {
+			if p.QuitIfOneScreen && !p.isShowingHelp && len(p.files) == 1 && p.reader.Done.Load() && p.reader.HighlightingDone.Load() {
 				width, height := p.screen.Size()
 				if fitsOnOneScreen(p.reader, width, height-p.DeInitFalseMargin) {
 					// Ref:
@@ -449,29 +478,15 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 		switch event := event.(type) {
 		case twin.EventKeyCode:
 			log.Tracef("Handling key event %d...", event.KeyCode())
-			p.mode.onKey(event.KeyCode())
+			p.handleKeyPress(KeyPress{KeyCode: event.KeyCode()})
 
 		case twin.EventRune:
 			log.Tracef("Handling rune event '%c'/0x%04x...", event.Rune(), event.Rune())
-			p.mode.onRune(event.Rune())
+			p.handleKeyPress(KeyPress{Rune: event.Rune()})
 
 		case twin.EventMouse:
 			log.Tracef("Handling mouse event %d...", event.Buttons())
-			switch event.Buttons() {
-			case twin.MouseWheelUp:
-				// Clipping is done in _Redraw()
-				p.scrollPosition = p.scrollPosition.PreviousLine(1)
-
-			case twin.MouseWheelDown:
-				// Clipping is done in _Redraw()
-				p.scrollPosition = p.scrollPosition.NextLine(1)
-
-			case twin.MouseWheelLeft:
-				p.moveRight(-p.SideScrollAmount)
-
-			case twin.MouseWheelRight:
-				p.moveRight(p.SideScrollAmount)
-			}
+			p.onMouseEvent(event)
 
 		case twin.EventResize:
 			// We'll be implicitly redrawn just by taking another lap in the loop
@@ -481,6 +496,8 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 			return
 
 		case eventMoreLinesAvailable:
+			p.checkWatchedLines()
+
 			if p.TargetLine != nil {
 				// The user wants to scroll down to a specific line number
 				if linemetadata.IndexFromLength(p.Reader().GetLineCount()).IsBefore(*p.TargetLine) {
@@ -493,12 +510,22 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 				}
 			}
 
+		case eventSearchHitsFound:
+			// Do nothing. We got this just so that we'll redraw the status bar
+			// match count and the scrollbar with the new hits.
+
 		case eventMaybeDone:
-			// Do nothing. We got this just so that we'll do the QuitIfOneScreen
-			// check (above) as soon as highlighting is done.
+			// Start watching for new lines as soon as the initial read is
+			// done
+			p.checkWatchedLines()
+
+			// Other than that, we got this just so that we'll do the
+			// QuitIfOneScreen check (above) as soon as highlighting is done.
 
 		case eventSpinnerUpdate:
-			spinner = event.spinner
+			if event.reader == p.reader {
+				spinner = event.spinner
+			}
 
 		case twin.EventTerminalBackgroundDetected:
 			// Do nothing, we don't care about background color updates
@@ -509,6 +536,62 @@ func (p *Pager) StartPaging(screen twin.Screen, chromaStyle *chroma.Style, chrom
 	}
 }
 
+// Forward reader notifications to the main loop as screen events.
+func (p *Pager) startReaderListeners(r *reader.ReaderImpl) {
+	screen := p.screen
+
+	go func() {
+		defer func() {
+			PanicHandler("StartPaging()/moreLinesAvailable", recover(), debug.Stack())
+		}()
+
+		for range r.MoreLinesAdded {
+			// Notify the main loop about the new lines so it can show them
+			screen.Events() <- eventMoreLinesAvailable{}
+
+			// Delay updates a bit so that we don't waste time refreshing
+			// the screen too often.
+			//
+			// Note that the delay is *after* reacting, this way single-line
+			// updates are reacted to immediately, and the first output line
+			// read will appear on screen without delay.
+			time.Sleep(200 * time.Millisecond)
+		}
+	}()
+
+	go func() {
+		defer func() {
+			PanicHandler("StartPaging()/spinner", recover(), debug.Stack())
+		}()
+
+		// Spin the spinner as long as contents is still loading
+		spinnerFrames := [...]string{"/.\\", "-o-", "\\O/", "| |"}
+		spinnerIndex := 0
+		for !r.Done.Load() {
+			screen.Events() <- eventSpinnerUpdate{spinner: spinnerFrames[spinnerIndex], reader: r}
+			spinnerIndex++
+			if spinnerIndex >= len(spinnerFrames) {
+				spinnerIndex = 0
+			}
+
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		// Empty our spinner, loading done!
+		screen.Events() <- eventSpinnerUpdate{spinner: "", reader: r}
+	}()
+
+	go func() {
+		defer func() {
+			PanicHandler("StartPaging()/maybeDone", recover(), debug.Stack())
+		}()
+
+		for range r.MaybeDone {
+			screen.Events() <- eventMaybeDone{}
+		}
+	}()
+}
+
 // The height parameter is the terminal height minus the height of the user's
 // shell prompt.
 //
@@ -520,7 +603,7 @@ func fitsOnOneScreen(reader *reader.ReaderImpl, width int, height int) bool {
 
 	lines := reader.GetLines(linemetadata.Index{}, reader.GetLineCount())
 	for _, line := range lines.Lines {
-		rendered := line.HighlightedTokens(twin.StyleDefault, nil, nil).StyledRunes
+		rendered := line.HighlightedTokens(twin.StyleDefault, nil, nil, nil).StyledRunes
 		if len(rendered) > width {
 			// This line is too long to fit on one screen line, no fit
 			return false
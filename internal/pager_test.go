@@ -339,6 +339,91 @@ func TestToPattern(t *testing.T) {
 	assert.Assert(t, toPattern(")g").MatchString(")g"))
 }
 
+func TestToPatternWithOptions(t *testing.T) {
+	// Literal
+	literal := searchOptions{literal: true}
+	assert.Assert(t, literal.toPattern("a.b(c)").MatchString("x a.b(c) y"))
+	assert.Assert(t, !literal.toPattern("a.b").MatchString("axb"))
+
+	// Case sensitive without any upper case chars
+	matchCase := searchOptions{caseMode: searchCaseSensitive}
+	assert.Assert(t, matchCase.toPattern("g.*s").MatchString("gRIIIs"))
+	assert.Assert(t, !matchCase.toPattern("g.*s").MatchString("GRIIIS"))
+
+	// Case insensitive even with upper case chars
+	ignoreCase := searchOptions{caseMode: searchCaseInsensitive}
+	assert.Assert(t, ignoreCase.toPattern("G.*S").MatchString("gRIIIs"))
+
+	// Whole words
+	wholeWord := searchOptions{wholeWord: true}
+	assert.Assert(t, wholeWord.toPattern("cat|dog").MatchString("a dog"))
+	assert.Assert(t, !wholeWord.toPattern("cat|dog").MatchString("dogma"))
+	assert.Assert(t, wholeWord.toPattern("a(").MatchString("x a( y"))
+	assert.Assert(t, !wholeWord.toPattern("a(").MatchString("xa("))
+
+	// Whole words, literal with non-word chars at the edges
+	literalWords := searchOptions{literal: true, wholeWord: true}
+	assert.Assert(t, literalWords.toPattern("(a)").MatchString("x(a)y"))
+	assert.Assert(t, !literalWords.toPattern(".a").MatchString(".ab"))
+}
+
+func TestSearchToggles(t *testing.T) {
+	reader := reader.NewFromTextForTesting("", "axb\na.b\n")
+	pager := NewPager(reader)
+	screen := twin.NewFakeScreen(60, 10)
+	pager.screen = screen
+
+	pager.startSearch(SearchDirectionForward)
+	for _, char := range "a.b" {
+		pager.mode.onRune(char)
+	}
+	assert.Assert(t, pager.searchPattern.MatchString("axb"))
+
+	pager.mode.onKey(twin.KeyAltR)
+	assert.Assert(t, pager.searchOptions.literal)
+	assert.Assert(t, !pager.searchPattern.MatchString("axb"))
+	assert.Assert(t, pager.searchPattern.MatchString("a.b"))
+
+	pager.mode.drawFooter("", "")
+	footer := rowToString(screen.GetRow(9))
+	assert.Equal(t, "Search: a.b    alt-r regexp   alt-c smart case   alt-w word", footer)
+	assert.Equal(t, twin.StyleDefault.WithAttr(twin.AttrDim), screen.GetRow(9)[15].Style)
+
+	// The options should be remembered for the next search
+	pager.mode.onKey(twin.KeyEnter)
+	pager.startSearch(SearchDirectionForward)
+	assert.Assert(t, pager.searchOptions.literal)
+}
+
+// The toggles should show how the search string is actually compiled
+func TestSearchTogglesShowEffectiveState(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "a(b\n"))
+	screen := twin.NewFakeScreen(60, 10)
+	pager.screen = screen
+
+	// Invalid regexp, searched for verbatim
+	pager.startSearch(SearchDirectionForward)
+	for _, char := range "A(" {
+		pager.mode.onRune(char)
+	}
+	pager.mode.drawFooter("", "")
+	footer := rowToString(screen.GetRow(9))
+	regexpColumn := strings.Index(footer, "regexp")
+	caseColumn := strings.Index(footer, "smart case")
+	assert.Equal(t, twin.StyleDefault.WithAttr(twin.AttrDim), screen.GetRow(9)[regexpColumn].Style)
+
+	// Smart case, with an upper case char
+	assert.Equal(t, twin.StyleDefault.WithAttr(twin.AttrReverse), screen.GetRow(9)[caseColumn].Style)
+
+	// Force case insensitive
+	pager.mode.onKey(twin.KeyAltC)
+	pager.mode.onKey(twin.KeyAltC)
+	assert.Assert(t, pager.searchPattern.MatchString("a(b"))
+	pager.mode.drawFooter("", "")
+	caseColumn = strings.Index(rowToString(screen.GetRow(9)), "alt-c case")
+	assert.Equal(t, twin.StyleDefault.WithAttr(twin.AttrDim), screen.GetRow(9)[caseColumn].Style)
+}
+
 func TestFindFirstHitSimple(t *testing.T) {
 	reader := reader.NewFromTextForTesting("TestFindFirstHitSimple", "AB")
 	pager := NewPager(reader)
@@ -6,8 +6,9 @@ import (
 )
 
 type PagerModeFilter struct {
-	pager        *Pager
-	filterString string
+	pager         *Pager
+	filterString  string
+	historyRecall historyRecall
 }
 
 func (m PagerModeFilter) drawFooter(_ string, _ string) {
@@ -16,7 +17,13 @@ func (m PagerModeFilter) drawFooter(_ string, _ string) {
 	prompt := "Filter: "
 
 	pos := 0
-	for _, token := range prompt + m.filterString {
+	for _, token := range prompt {
+		pos += m.pager.screen.SetCell(pos, height-1, twin.NewStyledRune(token, twin.StyleDefault))
+	}
+
+	pos = m.pager.drawFilterChips(m.pager.earlierFilters, pos, twin.StyleDefault)
+
+	for _, token := range m.filterString {
 		pos += m.pager.screen.SetCell(pos, height-1, twin.NewStyledRune(token, twin.StyleDefault))
 	}
 
@@ -32,25 +39,26 @@ func (m PagerModeFilter) drawFooter(_ string, _ string) {
 func (m *PagerModeFilter) onKey(key twin.KeyCode) {
 	switch key {
 	case twin.KeyEnter:
+		m.pager.searchHistory.addEntry(m.filterString)
 		m.pager.mode = PagerModeViewing{pager: m.pager}
 
 	case twin.KeyEscape:
+		// Drop the filter being typed, but keep any earlier ones
 		m.pager.mode = PagerModeViewing{pager: m.pager}
-		m.pager.filterPattern = nil
-		m.pager.searchString = ""
-		m.pager.searchPattern = nil
+		m.pager.popFilter()
+		m.pager.highlightFilterMatches()
 
 	case twin.KeyBackspace, twin.KeyDelete:
-		if len(m.filterString) == 0 {
-			return
-		}
+		m.historyRecall.reset()
+		m.backspace()
 
-		m.filterString = removeLastChar(m.filterString)
-		m.pager.filterPattern = toPattern(m.filterString)
-		m.pager.searchString = m.filterString
-		m.pager.searchPattern = toPattern(m.filterString)
+	case twin.KeyUp:
+		m.setFilterString(m.historyRecall.older(m.pager.searchHistory, m.filterString))
 
-	case twin.KeyUp, twin.KeyDown, twin.KeyRight, twin.KeyLeft, twin.KeyPgUp, twin.KeyPgDown, twin.KeyHome, twin.KeyEnd:
+	case twin.KeyDown:
+		m.setFilterString(m.historyRecall.newer(m.pager.searchHistory, m.filterString))
+
+	case twin.KeyRight, twin.KeyLeft, twin.KeyPgUp, twin.KeyPgDown, twin.KeyHome, twin.KeyEnd:
 		viewing := PagerModeViewing{pager: m.pager}
 
 		// Scroll up / down
@@ -62,18 +70,37 @@ func (m *PagerModeFilter) onKey(key twin.KeyCode) {
 }
 
 func (m *PagerModeFilter) onRune(char rune) {
+	m.historyRecall.reset()
+
 	if char == '\x08' {
 		// Backspace
-		if len(m.filterString) == 0 {
-			return
-		}
-
-		m.filterString = removeLastChar(m.filterString)
+		m.backspace()
 	} else {
-		m.filterString = m.filterString + string(char)
+		m.setFilterString(m.filterString + string(char))
 	}
+}
+
+// Backspacing past the start of the filter goes back to editing the previous
+// one
+func (m *PagerModeFilter) backspace() {
+	if len(m.filterString) > 0 {
+		m.setFilterString(removeLastChar(m.filterString))
+		return
+	}
+
+	if len(m.pager.earlierFilters) == 0 {
+		return
+	}
+
+	m.pager.popFilter()
+	m.setFilterString(m.pager.filterString)
+}
+
+func (m *PagerModeFilter) setFilterString(filterString string) {
+	filter := newFilter(filterString)
 
-	m.pager.filterPattern = toPattern(m.filterString)
-	m.pager.searchString = m.filterString
-	m.pager.searchPattern = toPattern(m.filterString)
+	m.filterString = filterString
+	m.pager.filterString = filterString
+	m.pager.filterPattern = filter.pattern
+	m.pager.highlightFilterMatches()
 }
@@ -45,7 +45,7 @@ func (m *PagerModeGotoLine) onKey(key twin.KeyCode) {
 	case twin.KeyEnter:
 		newLineNumber, err := strconv.Atoi(m.gotoLineString)
 		if err == nil {
-			targetIndex := linemetadata.IndexFromOneBased(newLineNumber)
+			targetIndex := p.indexOfLineNumber(linemetadata.NumberFromOneBased(newLineNumber))
 			p.scrollPosition = NewScrollPositionFromIndex(
 				targetIndex,
 				"onGotoLineKey",
@@ -79,13 +79,6 @@ func (m *PagerModeGotoLine) onRune(char rune) {
 		return
 	}
 
-	if char == 'g' {
-		p.scrollPosition = newScrollPosition("Pager scroll position")
-		p.handleScrolledUp()
-		p.mode = PagerModeViewing{pager: p}
-		return
-	}
-
 	newGotoLineString := m.gotoLineString + string(char)
 	newGotoLineNumber, err := strconv.Atoi(newGotoLineString)
 	if err != nil {
@@ -68,13 +68,13 @@ func (m PagerModeJumpToMark) onKey(key twin.KeyCode) {
 
 func (m PagerModeJumpToMark) onRune(char rune) {
 	if len(m.pager.marks) == 0 && char == 'm' {
-		m.pager.mode = PagerModeMark(m)
+		m.pager.mode = PagerModeMark{pager: m.pager}
 		return
 	}
 
-	destination, ok := m.pager.marks[char]
+	lineNumber, ok := m.pager.marks[char]
 	if ok {
-		m.pager.scrollPosition = destination
+		m.pager.scrollPosition = NewScrollPositionFromIndex(m.pager.indexOfLineNumber(lineNumber), "jumpToMark")
 	}
 
 	m.pager.mode = PagerModeViewing(m)
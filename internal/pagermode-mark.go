@@ -1,9 +1,15 @@
 package internal
 
-import "github.com/walles/moor/v2/twin"
+import (
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/twin"
+)
 
 type PagerModeMark struct {
 	pager *Pager
+
+	// Where to put the mark. If nil, the top line of the screen is used.
+	lineIndex *linemetadata.Index
 }
 
 func (m PagerModeMark) drawFooter(_ string, _ string) {
@@ -36,6 +42,19 @@ func (m PagerModeMark) onKey(key twin.KeyCode) {
 }
 
 func (m PagerModeMark) onRune(char rune) {
-	m.pager.marks[char] = m.pager.scrollPosition
-	m.pager.mode = PagerModeViewing(m)
+	lineIndex := m.lineIndex
+	if lineIndex == nil {
+		lineIndex = m.pager.lineIndex()
+	}
+
+	// Remember the line number rather than the index, so that the mark stays
+	// on the same line when filtering changes
+	if lineIndex != nil {
+		line := m.pager.Reader().GetLine(*lineIndex)
+		if line != nil {
+			m.pager.marks[char] = line.Number
+		}
+	}
+
+	m.pager.mode = PagerModeViewing{pager: m.pager}
 }
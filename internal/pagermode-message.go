@@ -0,0 +1,30 @@
+package internal
+
+import "github.com/walles/moor/v2/twin"
+
+// Shows a message in the footer until the next key press
+type PagerModeMessage struct {
+	pager   *Pager
+	message string
+}
+
+func (m PagerModeMessage) drawFooter(_ string, _ string) {
+	m.pager.setFooter(m.message)
+}
+
+// Any key dismisses the message, and then does what it would do in viewing
+// mode
+func (m PagerModeMessage) onKey(key twin.KeyCode) {
+	m.pager.mode = PagerModeViewing{pager: m.pager}
+	m.pager.onActionKey(KeyPress{KeyCode: key})
+}
+
+func (m PagerModeMessage) onRune(char rune) {
+	m.pager.mode = PagerModeViewing{pager: m.pager}
+	m.pager.onActionKey(KeyPress{Rune: char})
+}
+
+// Show a message in the footer until the next key press
+func (p *Pager) showMessage(message string) {
+	p.mode = PagerModeMessage{pager: p, message: message}
+}
@@ -10,24 +10,12 @@ func (m PagerModeNotFound) drawFooter(_ string, _ string) {
 	m.pager.setFooter("Not found: " + m.pager.searchString)
 }
 
+// Same key bindings as in viewing mode. Finding the next or previous hit
+// wraps around, and everything else goes back to viewing mode.
 func (m PagerModeNotFound) onKey(key twin.KeyCode) {
-	m.pager.mode = PagerModeViewing(m)
-	m.pager.mode.onKey(key)
+	m.pager.onActionKey(KeyPress{KeyCode: key})
 }
 
 func (m PagerModeNotFound) onRune(char rune) {
-	switch char {
-
-	// Should match the pagermode-viewing.go next-search-hit bindings
-	case 'n':
-		m.pager.scrollToNextSearchHit()
-
-	// Should match the pagermode-viewing.go previous-search-hit bindings
-	case 'p', 'N':
-		m.pager.scrollToPreviousSearchHit()
-
-	default:
-		m.pager.mode = PagerModeViewing(m)
-		m.pager.mode.onRune(char)
-	}
+	m.pager.onActionKey(KeyPress{Rune: char})
 }
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+)
+
+// Pick a member from an archive listing to open it
+type PagerModeOpenMember struct {
+	pager *Pager
+}
+
+func (p *Pager) startOpeningMember() {
+	if p.isShowingHelp || !p.reader.IsArchiveListing() || p.lineIndex() == nil {
+		return
+	}
+
+	start := selectionPoint{lineIndex: *p.lineIndex()}
+	p.selection = &_Selection{anchor: start, cursor: start, lineWise: true}
+	p.mode = PagerModeOpenMember{pager: p}
+	p.setTargetLine(nil)
+}
+
+func (m PagerModeOpenMember) drawFooter(_ string, _ string) {
+	m.pager.setFooter("Pick a member to open, up / down to move, RETURN to open, ESC to cancel")
+}
+
+func (m PagerModeOpenMember) done() {
+	m.pager.selection = nil
+	m.pager.mode = PagerModeViewing{pager: m.pager}
+}
+
+func (m PagerModeOpenMember) open() {
+	lineIndex := m.pager.selection.cursor.lineIndex
+	m.done()
+	m.pager.openArchiveMember(lineIndex)
+}
+
+func (m PagerModeOpenMember) onKey(key twin.KeyCode) {
+	p := m.pager
+
+	switch key {
+	case twin.KeyEscape:
+		m.done()
+
+	case twin.KeyEnter:
+		m.open()
+
+	case twin.KeyUp:
+		p.moveSelectionCursor(-1)
+
+	case twin.KeyDown:
+		p.moveSelectionCursor(1)
+
+	case twin.KeyPgUp:
+		p.moveSelectionCursor(-p.visibleHeight())
+
+	case twin.KeyPgDown:
+		p.moveSelectionCursor(p.visibleHeight())
+
+	default:
+		log.Debugf("Unhandled open member mode key event %v", key)
+	}
+}
+
+func (m PagerModeOpenMember) onRune(char rune) {
+	p := m.pager
+
+	switch char {
+	case 'q':
+		m.done()
+
+	case 'o':
+		m.open()
+
+	case 'k':
+		p.moveSelectionCursor(-1)
+
+	case 'j':
+		p.moveSelectionCursor(1)
+
+	default:
+		log.Debugf("Unhandled open member mode rune '%s'/0x%08x", string(char), int32(char))
+	}
+}
+
+// Open the archive member listed on the given line, as a new file right after
+// the archive listing. Switch back to the listing using :p.
+func (p *Pager) openArchiveMember(lineIndex linemetadata.Index) {
+	line := p.Reader().GetLine(lineIndex)
+	if line == nil {
+		return
+	}
+
+	memberName := p.reader.ArchiveMemberAt(line.Number)
+	if memberName == "" {
+		return
+	}
+
+	// Already open?
+	name := reader.ArchiveMemberName(p.reader.ArchiveName(), memberName)
+	for fileIndex, file := range p.files {
+		if file.name == name {
+			p.switchToFile(fileIndex)
+			return
+		}
+	}
+
+	memberReader, err := p.reader.OpenArchiveMember(memberName)
+	if err != nil {
+		log.Warn("Failed to open archive member: ", err)
+		return
+	}
+
+	p.files = slices.Insert(p.files, p.currentFileIndex+1, newFileState(memberReader))
+	p.startReaderListeners(memberReader)
+	p.switchToFile(p.currentFileIndex + 1)
+}
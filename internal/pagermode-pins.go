@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/twin"
+)
+
+// List the pinned search patterns, and let the user remove them
+type PagerModePins struct {
+	pager *Pager
+}
+
+func (m PagerModePins) drawFooter(_ string, _ string) {
+	p := m.pager
+
+	width, height := p.screen.Size()
+
+	pos := 0
+	addString := func(text string, style twin.Style) {
+		for _, token := range text {
+			pos += p.screen.SetCell(pos, height-1, twin.NewStyledRune(token, style))
+		}
+	}
+
+	if len(p.pins) == 0 {
+		addString("No pinned highlights, search and press * to pin one. ESC to close.", statusbarStyle)
+	} else {
+		addString("Pinned:", statusbarStyle)
+		for i, pin := range p.pins {
+			addString(" ", statusbarStyle)
+			addString(fmt.Sprintf("%d:%s", i+1, pin.searchString), pin.style())
+		}
+		addString(fmt.Sprintf("  Press 1-%d to unpin, ESC to close", len(p.pins)), statusbarStyle)
+	}
+
+	// Clear the rest of the line
+	for pos < width {
+		pos += p.screen.SetCell(pos, height-1, twin.NewStyledRune(' ', statusbarStyle))
+	}
+}
+
+func (m PagerModePins) onKey(key twin.KeyCode) {
+	p := m.pager
+
+	switch key {
+	case twin.KeyEnter, twin.KeyEscape:
+		p.mode = PagerModeViewing{pager: p}
+
+	default:
+		log.Debugf("Unhandled pins key event %v", key)
+	}
+}
+
+func (m PagerModePins) onRune(char rune) {
+	p := m.pager
+
+	if char == 'q' {
+		p.mode = PagerModeViewing{pager: p}
+		return
+	}
+
+	if char < '1' || char > '9' {
+		log.Debugf("Unhandled pins rune %q", char)
+		return
+	}
+
+	p.unpin(int(char - '1'))
+	if len(p.pins) == 0 {
+		p.mode = PagerModeViewing{pager: p}
+	}
+}
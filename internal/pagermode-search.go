@@ -20,9 +20,17 @@ type PagerModeSearch struct {
 	pager                 *Pager
 	initialScrollPosition scrollPosition // Pager position before search started
 	direction             SearchDirection
+	historyRecall         historyRecall
 }
 
-func (m PagerModeSearch) drawFooter(_ string, _ string) {
+func (p *Pager) startSearch(direction SearchDirection) {
+	p.mode = &PagerModeSearch{pager: p, direction: direction, initialScrollPosition: p.scrollPosition}
+	p.setTargetLine(nil)
+	p.searchString = ""
+	p.searchPattern = nil
+}
+
+func (m *PagerModeSearch) drawFooter(_ string, _ string) {
 	width, height := m.pager.screen.Size()
 
 	prompt := "Search: "
@@ -38,14 +46,66 @@ func (m PagerModeSearch) drawFooter(_ string, _ string) {
 	// Add a cursor
 	pos += m.pager.screen.SetCell(pos, height-1, twin.NewStyledRune(' ', twin.StyleDefault.WithAttr(twin.AttrReverse)))
 
+	promptEnd := pos
+
 	// Clear the rest of the line
 	for pos < width {
 		pos += m.pager.screen.SetCell(pos, height-1, twin.NewStyledRune(' ', twin.StyleDefault))
 	}
+
+	m.drawToggles(promptEnd, width, height)
+}
+
+// Show the search options at the right edge of the prompt, with the enabled
+// ones in reverse video. Nothing is drawn if that would cover the prompt.
+func (m *PagerModeSearch) drawToggles(promptEnd int, width int, height int) {
+	// Show what the search string will actually be compiled into, not just
+	// what the user asked for
+	options := m.pager.searchOptions
+	searchString := m.pager.searchString
+
+	caseText := " alt-c case "
+	if options.caseMode == searchCaseSmart {
+		caseText = " alt-c smart case "
+	}
+
+	toggles := []struct {
+		text    string
+		enabled bool
+	}{
+		{" alt-r regexp ", options.isRegexp(searchString)},
+		{caseText, options.isCaseSensitive(searchString)},
+		{" alt-w word ", options.wholeWord},
+	}
+
+	togglesWidth := len(toggles) - 1
+	for _, toggle := range toggles {
+		togglesWidth += len(toggle.text)
+	}
+
+	if promptEnd+1+togglesWidth > width {
+		// No room
+		return
+	}
+
+	pos := width - togglesWidth
+	for i, toggle := range toggles {
+		if i > 0 {
+			pos++
+		}
+
+		style := twin.StyleDefault.WithAttr(twin.AttrDim)
+		if toggle.enabled {
+			style = twin.StyleDefault.WithAttr(twin.AttrReverse)
+		}
+		for _, char := range toggle.text {
+			pos += m.pager.screen.SetCell(pos, height-1, twin.NewStyledRune(char, style))
+		}
+	}
 }
 
 func (m *PagerModeSearch) updateSearchPattern() {
-	m.pager.searchPattern = toPattern(m.pager.searchString)
+	m.pager.searchPattern = m.pager.searchOptions.toPattern(m.pager.searchString)
 
 	switch m.direction {
 	case SearchDirectionBackward:
@@ -55,6 +115,63 @@ func (m *PagerModeSearch) updateSearchPattern() {
 	}
 }
 
+type searchCase int
+
+const (
+	// Case sensitive only if the search string contains upper case chars
+	searchCaseSmart searchCase = iota
+	searchCaseSensitive
+	searchCaseInsensitive
+)
+
+// How to turn a search string into a pattern. The zero value guesses, see
+// toPattern().
+type searchOptions struct {
+	// Match the search string verbatim, even if it is a valid regexp
+	literal bool
+
+	caseMode searchCase
+
+	// Only match whole words
+	wholeWord bool
+}
+
+// Will this search string be compiled into a case sensitive pattern?
+func (options searchOptions) isCaseSensitive(searchString string) bool {
+	switch options.caseMode {
+	case searchCaseSensitive:
+		return true
+	case searchCaseInsensitive:
+		return false
+	}
+
+	for _, char := range searchString {
+		if unicode.IsUpper(char) {
+			return true
+		}
+	}
+	return false
+}
+
+// Will this search string be used as a regexp? Invalid regexps are matched
+// verbatim.
+func (options searchOptions) isRegexp(searchString string) bool {
+	if options.literal {
+		return false
+	}
+
+	_, err := regexp.Compile(options.asRegexp(searchString))
+	return err == nil
+}
+
+// The search string as a regexp, without any case flags
+func (options searchOptions) asRegexp(searchString string) string {
+	if options.wholeWord {
+		return `\b(?:` + searchString + `)\b`
+	}
+	return searchString
+}
+
 // toPattern compiles a search string into a pattern.
 //
 // If the string contains only lower-case letter the pattern will be case insensitive.
@@ -63,31 +180,43 @@ func (m *PagerModeSearch) updateSearchPattern() {
 //
 // If the string does not compile into a regexp the pattern will match the string verbatim
 func toPattern(compileMe string) *regexp.Regexp {
+	return searchOptions{}.toPattern(compileMe)
+}
+
+// Like toPattern(), but with the guessing overridden by the options
+func (options searchOptions) toPattern(compileMe string) *regexp.Regexp {
 	if len(compileMe) == 0 {
 		return nil
 	}
 
-	hasUppercase := false
-	for _, char := range compileMe {
-		if unicode.IsUpper(char) {
-			hasUppercase = true
-		}
-	}
-
-	// Smart case; be case insensitive unless there are upper case chars
-	// in the search string
 	prefix := "(?i)"
-	if hasUppercase {
+	if options.isCaseSensitive(compileMe) {
 		prefix = ""
 	}
 
-	pattern, err := regexp.Compile(prefix + compileMe)
-	if err == nil {
-		// Search string is a regexp
-		return pattern
+	if !options.literal {
+		pattern, err := regexp.Compile(prefix + options.asRegexp(compileMe))
+		if err == nil {
+			// Search string is a regexp
+			return pattern
+		}
 	}
 
-	pattern, err = regexp.Compile(prefix + regexp.QuoteMeta(compileMe))
+	literal := regexp.QuoteMeta(compileMe)
+	if options.wholeWord {
+		// "\b" next to a non-word character would require a word character on
+		// the other side of it, so only add it next to word characters
+		first, _ := utf8.DecodeRuneInString(compileMe)
+		if isWordChar(first) {
+			literal = `\b` + literal
+		}
+		last, _ := utf8.DecodeLastRuneInString(compileMe)
+		if isWordChar(last) {
+			literal = literal + `\b`
+		}
+	}
+
+	pattern, err := regexp.Compile(prefix + literal)
 	if err == nil {
 		// Pattern matching the string exactly
 		return pattern
@@ -97,6 +226,11 @@ func toPattern(compileMe string) *regexp.Regexp {
 	panic(err)
 }
 
+// Word characters as defined by "\b" in Go regexps
+func isWordChar(char rune) bool {
+	return char == '_' || char < utf8.RuneSelf && (unicode.IsLetter(char) || unicode.IsDigit(char))
+}
+
 // From: https://stackoverflow.com/a/57005674/473672
 func removeLastChar(s string) string {
 	r, size := utf8.DecodeLastRuneInString(s)
@@ -106,9 +240,10 @@ func removeLastChar(s string) string {
 	return s[:len(s)-size]
 }
 
-func (m PagerModeSearch) onKey(key twin.KeyCode) {
+func (m *PagerModeSearch) onKey(key twin.KeyCode) {
 	switch key {
 	case twin.KeyEnter:
+		m.pager.searchHistory.addEntry(m.pager.searchString)
 		m.pager.mode = PagerModeViewing{pager: m.pager}
 
 	case twin.KeyEscape:
@@ -121,18 +256,40 @@ func (m PagerModeSearch) onKey(key twin.KeyCode) {
 		}
 
 		m.pager.searchString = removeLastChar(m.pager.searchString)
+		m.historyRecall.reset()
+		m.updateSearchPattern()
+
+	case twin.KeyUp:
+		m.pager.searchString = m.historyRecall.older(m.pager.searchHistory, m.pager.searchString)
 		m.updateSearchPattern()
 
-	case twin.KeyUp, twin.KeyDown, twin.KeyPgUp, twin.KeyPgDown:
+	case twin.KeyDown:
+		m.pager.searchString = m.historyRecall.newer(m.pager.searchHistory, m.pager.searchString)
+		m.updateSearchPattern()
+
+	case twin.KeyPgUp, twin.KeyPgDown:
 		m.pager.mode = PagerModeViewing{pager: m.pager}
 		m.pager.mode.onKey(key)
 
+	case twin.KeyAltR:
+		m.pager.searchOptions.literal = !m.pager.searchOptions.literal
+		m.updateSearchPattern()
+
+	case twin.KeyAltC:
+		// Smart case, then case sensitive, then case insensitive
+		m.pager.searchOptions.caseMode = (m.pager.searchOptions.caseMode + 1) % 3
+		m.updateSearchPattern()
+
+	case twin.KeyAltW:
+		m.pager.searchOptions.wholeWord = !m.pager.searchOptions.wholeWord
+		m.updateSearchPattern()
+
 	default:
 		log.Debugf("Unhandled search key event %v", key)
 	}
 }
 
-func (m PagerModeSearch) onRune(char rune) {
+func (m *PagerModeSearch) onRune(char rune) {
 	if char == '\x08' {
 		// Backspace
 		if len(m.pager.searchString) == 0 {
@@ -144,5 +301,6 @@ func (m PagerModeSearch) onRune(char rune) {
 		m.pager.searchString = m.pager.searchString + string(char)
 	}
 
+	m.historyRecall.reset()
 	m.updateSearchPattern()
 }
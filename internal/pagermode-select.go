@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/twin"
+)
+
+// Select lines or characters using the keyboard, then copy them to the
+// clipboard
+type PagerModeSelect struct {
+	pager *Pager
+}
+
+func (p *Pager) startSelecting() {
+	if p.lineIndex() == nil {
+		// Nothing to select
+		return
+	}
+
+	// Start in the leftmost visible column, in case the user wants to select
+	// characters rather than lines
+	start := selectionPoint{lineIndex: *p.lineIndex(), runeIndex: p.leftColumnZeroBased}
+	p.selection = &_Selection{anchor: start, cursor: start, lineWise: true}
+	p.mode = PagerModeSelect{pager: p}
+	p.setTargetLine(nil)
+}
+
+func (m PagerModeSelect) drawFooter(_ string, _ string) {
+	first, last := m.pager.selection.bounds()
+	lineCount := first.lineIndex.CountLinesTo(last.lineIndex) + 1
+
+	selected := "1 line selected"
+	if !m.pager.selection.lineWise {
+		selected = fmt.Sprintf("%d characters selected", utf8.RuneCountInString(m.pager.selectedText()))
+	} else if lineCount != 1 {
+		selected = fmt.Sprintf("%d lines selected", lineCount)
+	}
+	m.pager.setFooter(selected + ", arrows to change, 'v' for lines / characters, 'y' / RETURN to copy, ESC to cancel")
+}
+
+func (m PagerModeSelect) done() {
+	m.pager.selection = nil
+	m.pager.mode = PagerModeViewing{pager: m.pager}
+}
+
+func (m PagerModeSelect) onKey(key twin.KeyCode) {
+	p := m.pager
+
+	switch key {
+	case twin.KeyEscape:
+		m.done()
+
+	case twin.KeyEnter:
+		p.copySelection()
+		m.done()
+
+	case twin.KeyUp:
+		p.moveSelectionCursor(-1)
+
+	case twin.KeyDown:
+		p.moveSelectionCursor(1)
+
+	case twin.KeyLeft:
+		p.moveSelectionColumn(-1)
+
+	case twin.KeyRight:
+		p.moveSelectionColumn(1)
+
+	case twin.KeyPgUp:
+		p.moveSelectionCursor(-p.visibleHeight())
+
+	case twin.KeyPgDown:
+		p.moveSelectionCursor(p.visibleHeight())
+
+	default:
+		log.Debugf("Unhandled select mode key event %v", key)
+	}
+}
+
+func (m PagerModeSelect) onRune(char rune) {
+	p := m.pager
+
+	switch char {
+	case 'q':
+		m.done()
+
+	case 'y':
+		p.copySelection()
+		m.done()
+
+	case 'k':
+		p.moveSelectionCursor(-1)
+
+	case 'j':
+		p.moveSelectionCursor(1)
+
+	case 'h':
+		p.moveSelectionColumn(-1)
+
+	case 'l':
+		p.moveSelectionColumn(1)
+
+	case 'v':
+		p.selection.lineWise = !p.selection.lineWise
+
+	default:
+		log.Debugf("Unhandled select mode rune '%s'/0x%08x", string(char), int32(char))
+	}
+}
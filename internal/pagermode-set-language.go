@@ -0,0 +1,59 @@
+package internal
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/twin"
+)
+
+// Prompt for a language to highlight as
+type PagerModeSetLanguage struct {
+	pager *Pager
+
+	language string
+}
+
+func (m *PagerModeSetLanguage) drawFooter(_ string, _ string) {
+	p := m.pager
+
+	_, height := p.screen.Size()
+
+	pos := 0
+	for _, token := range "Highlight as (like \"go\" or \"yaml\"): " + m.language {
+		pos += p.screen.SetCell(pos, height-1, twin.NewStyledRune(token, twin.StyleDefault))
+	}
+
+	// Add a cursor
+	p.screen.SetCell(pos, height-1, twin.NewStyledRune(' ', twin.StyleDefault.WithAttr(twin.AttrReverse)))
+}
+
+func (m *PagerModeSetLanguage) onKey(key twin.KeyCode) {
+	p := m.pager
+
+	switch key {
+	case twin.KeyEnter:
+		if m.language != "" {
+			err := p.reader.SetLanguage(m.language)
+			if err != nil {
+				log.Info("Failed to set language: ", err)
+			}
+		}
+		p.mode = PagerModeViewing{pager: p}
+
+	case twin.KeyEscape:
+		p.mode = PagerModeViewing{pager: p}
+
+	case twin.KeyBackspace, twin.KeyDelete:
+		if len(m.language) == 0 {
+			return
+		}
+
+		m.language = removeLastChar(m.language)
+
+	default:
+		log.Debugf("Unhandled set language key event %v", key)
+	}
+}
+
+func (m *PagerModeSetLanguage) onRune(char rune) {
+	m.language += string(char)
+}
@@ -1,7 +1,8 @@
 package internal
 
 import (
-	log "github.com/sirupsen/logrus"
+	"strings"
+
 	"github.com/walles/moor/v2/twin"
 )
 
@@ -10,183 +11,65 @@ type PagerModeViewing struct {
 }
 
 func (m PagerModeViewing) drawFooter(statusText string, spinner string) {
-	helpText := "Press 'ESC' / 'q' to exit, '/' to search, '&' to filter, 'h' for help"
-	if m.pager.isShowingHelp {
-		helpText = "Press 'ESC' / 'q' to exit help, '/' to search"
+	if len(m.pager.pendingKeys) > 0 {
+		// Show what can come next, even if the status bar is hidden
+		m.pager.setFooter(m.pager.pendingKeysFooter())
+		return
 	}
 
+	helpText := m.pager.footerHelpText()
+
 	if m.pager.ShowStatusBar {
+		if matchCount := m.pager.matchCountText(); matchCount != "" {
+			statusText += "  " + matchCount
+		}
+		if m.pager.isWatching() {
+			statusText += "  Watching for new matches"
+		}
 		if len(spinner) > 0 {
 			spinner = "  " + spinner
 		}
-		m.pager.setFooter(statusText + spinner + "  " + helpText)
+		pos := 0
+		if !m.pager.isShowingHelp {
+			pos = m.pager.drawFilterChips(m.pager.filters(), pos, statusbarStyle)
+		}
+		m.pager.setFooterFrom(pos, statusText+spinner+"  "+helpText)
 	}
 }
 
-func (m PagerModeViewing) onKey(keyCode twin.KeyCode) {
-	p := m.pager
-
-	switch keyCode {
-	case twin.KeyEscape:
-		p.Quit()
-
-	case twin.KeyUp:
-		// Clipping is done in _Redraw()
-		p.scrollPosition = p.scrollPosition.PreviousLine(1)
-		p.handleScrolledUp()
-
-	case twin.KeyDown, twin.KeyEnter:
-		// Clipping is done in _Redraw()
-		p.scrollPosition = p.scrollPosition.NextLine(1)
-		p.handleScrolledDown()
-
-	case twin.KeyRight:
-		p.moveRight(p.SideScrollAmount)
-
-	case twin.KeyLeft:
-		p.moveRight(-p.SideScrollAmount)
-
-	case twin.KeyAltRight:
-		p.moveRight(1)
-
-	case twin.KeyAltLeft:
-		p.moveRight(-1)
-
-	case twin.KeyHome:
-		p.scrollPosition = newScrollPosition("Pager scroll position")
-		p.handleScrolledUp()
-
-	case twin.KeyEnd:
-		p.scrollToEnd()
+// Something like "Press 'q' / ESC to exit, '/' to search", using the current key
+// bindings
+func (p *Pager) footerHelpText() string {
+	hints := []string{}
+	addHint := func(actionName string, what string) {
+		keys := p.keysText(actionName)
+		if keys != "" {
+			hints = append(hints, keys+" "+what)
+		}
+	}
 
-	case twin.KeyPgUp:
-		p.scrollPosition = p.scrollPosition.PreviousLine(p.visibleHeight())
-		p.handleScrolledUp()
+	if p.isShowingHelp {
+		addHint("quit", "to exit help")
+		addHint("search-forward", "to search")
+	} else if p.reader.IsArchiveListing() {
+		addHint("open-member", "to open a member")
+		addHint("quit", "to exit")
+		addHint("help", "for help")
+	} else {
+		addHint("quit", "to exit")
+		addHint("search-forward", "to search")
+		addHint("filter", "to filter")
+		addHint("help", "for help")
+	}
 
-	case twin.KeyPgDown:
-		p.scrollPosition = p.scrollPosition.NextLine(p.visibleHeight())
-		p.handleScrolledDown()
+	return "Press " + strings.Join(hints, ", ")
+}
 
-	default:
-		log.Debugf("Unhandled key event %v", keyCode)
-	}
+// Viewing mode keys are bound to actions, see actions.go
+func (m PagerModeViewing) onKey(keyCode twin.KeyCode) {
+	m.pager.onActionKey(KeyPress{KeyCode: keyCode})
 }
 
 func (m PagerModeViewing) onRune(char rune) {
-	p := m.pager
-
-	switch char {
-	case 'q':
-		p.Quit()
-
-	case 'v':
-		handleEditingRequest(p)
-
-	case 'h':
-		if p.isShowingHelp {
-			break
-		}
-
-		p.preHelpState = &_PreHelpState{
-			scrollPosition:      p.scrollPosition,
-			leftColumnZeroBased: p.leftColumnZeroBased,
-			targetLine:          p.TargetLine,
-		}
-		p.scrollPosition = newScrollPosition("Pager scroll position")
-		p.leftColumnZeroBased = 0
-		p.setTargetLine(nil)
-		p.isShowingHelp = true
-
-	case '=':
-		p.ShowStatusBar = !p.ShowStatusBar
-
-	// '\x10' = CTRL-p, should scroll up one line.
-	// Ref: https://github.com/walles/moor/issues/107#issuecomment-1328354080
-	case 'k', 'y', '\x10':
-		// Clipping is done in _Redraw()
-		p.scrollPosition = p.scrollPosition.PreviousLine(1)
-		p.handleScrolledUp()
-
-	// '\x0e' = CTRL-n, should scroll down one line.
-	// Ref: https://github.com/walles/moor/issues/107#issuecomment-1328354080
-	case 'j', 'e', '\x0e':
-		// Clipping is done in _Redraw()
-		p.scrollPosition = p.scrollPosition.NextLine(1)
-		p.handleScrolledDown()
-
-	case '<':
-		p.scrollPosition = newScrollPosition("Pager scroll position")
-		p.handleScrolledUp()
-
-	case '>', 'G':
-		p.scrollToEnd()
-
-	case 'f', ' ':
-		p.scrollPosition = p.scrollPosition.NextLine(p.visibleHeight())
-		p.handleScrolledDown()
-
-	case 'b':
-		p.scrollPosition = p.scrollPosition.PreviousLine(p.visibleHeight())
-		p.handleScrolledUp()
-
-	// '\x15' = CTRL-u, should work like just 'u'.
-	// Ref: https://github.com/walles/moor/issues/90
-	case 'u', '\x15':
-		p.scrollPosition = p.scrollPosition.PreviousLine(p.visibleHeight() / 2)
-		p.handleScrolledUp()
-
-	// '\x04' = CTRL-d, should work like just 'd'.
-	// Ref: https://github.com/walles/moor/issues/90
-	case 'd', '\x04':
-		p.scrollPosition = p.scrollPosition.NextLine(p.visibleHeight() / 2)
-		p.handleScrolledDown()
-
-	case '/':
-		p.mode = PagerModeSearch{pager: p, direction: SearchDirectionForward, initialScrollPosition: p.scrollPosition}
-		p.setTargetLine(nil)
-		p.searchString = ""
-		p.searchPattern = nil
-
-	case '?':
-		p.mode = PagerModeSearch{pager: p, direction: SearchDirectionBackward, initialScrollPosition: p.scrollPosition}
-		p.setTargetLine(nil)
-		p.searchString = ""
-		p.searchPattern = nil
-
-	case '&':
-		if !p.isShowingHelp {
-			// Filtering the help text is not supported. Feel free to work on
-			// that if you feel that's time well spent.
-			p.mode = &PagerModeFilter{pager: p}
-			p.searchString = ""
-			p.searchPattern = nil
-			p.filterPattern = nil
-		}
-
-	case 'g':
-		p.mode = &PagerModeGotoLine{pager: p}
-		p.setTargetLine(nil)
-
-	// Should match the pagermode-not-found.go previous-search-hit bindings
-	case 'n':
-		p.scrollToNextSearchHit()
-
-	// Should match the pagermode-not-found.go next-search-hit bindings
-	case 'p', 'N':
-		p.scrollToPreviousSearchHit()
-
-	case 'm':
-		p.mode = PagerModeMark{pager: p}
-		p.setTargetLine(nil)
-
-	case '\'':
-		p.mode = PagerModeJumpToMark{pager: p}
-		p.setTargetLine(nil)
-
-	case 'w':
-		p.WrapLongLines = !p.WrapLongLines
-
-	default:
-		log.Debugf("Unhandled rune keypress '%s'/0x%08x", string(char), int32(char))
-	}
+	m.pager.onActionKey(KeyPress{Rune: char})
 }
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+)
+
+// Pinned search patterns get these colors, one each
+var pinBackgrounds = []twin.Color{
+	twin.NewColor16(3), // Yellow
+	twin.NewColor16(2), // Green
+	twin.NewColor16(6), // Cyan
+	twin.NewColor16(5), // Magenta
+	twin.NewColor16(4), // Blue
+	twin.NewColor16(1), // Red
+}
+
+// How many search patterns can be pinned at the same time. Pinning one more
+// drops the oldest pin.
+var maxPins = len(pinBackgrounds)
+
+// A search pattern that stays highlighted after searching for something else
+type pin struct {
+	searchString string
+	pattern      *regexp.Regexp
+	background   twin.Color
+}
+
+func (pin pin) style() twin.Style {
+	return twin.StyleDefault.WithBackground(pin.background).WithForeground(twin.NewColor16(0))
+}
+
+// Keep highlighting the current search pattern in a color of its own, and
+// clear the search
+func (p *Pager) pinSearch() {
+	if p.searchPattern == nil {
+		p.showMessage("Search for something first, then pin it")
+		return
+	}
+
+	for _, existing := range p.pins {
+		if existing.searchString == p.searchString {
+			// Already pinned
+			p.searchString = ""
+			p.searchPattern = nil
+			return
+		}
+	}
+
+	if len(p.pins) >= maxPins {
+		log.Debug("Too many pins, dropping the oldest one: ", p.pins[0].searchString)
+		p.pins = p.pins[1:]
+	}
+
+	p.pins = append(p.pins, pin{
+		searchString: p.searchString,
+		pattern:      p.searchPattern,
+		background:   p.unusedPinBackground(),
+	})
+
+	p.searchString = ""
+	p.searchPattern = nil
+}
+
+// The first pin color that isn't in use
+func (p *Pager) unusedPinBackground() twin.Color {
+	for _, background := range pinBackgrounds {
+		inUse := false
+		for _, existing := range p.pins {
+			if existing.background == background {
+				inUse = true
+				break
+			}
+		}
+
+		if !inUse {
+			return background
+		}
+	}
+
+	// Can't happen as long as we never have more than maxPins pins
+	return pinBackgrounds[0]
+}
+
+// Remove the pin at the given zero based index
+func (p *Pager) unpin(index int) {
+	if index < 0 || index >= len(p.pins) {
+		return
+	}
+
+	p.pins = append(p.pins[:index:index], p.pins[index+1:]...)
+}
+
+// For highlighting the pinned patterns when rendering lines
+func (p *Pager) pinnedPatterns() []reader.PinnedPattern {
+	if len(p.pins) == 0 {
+		return nil
+	}
+
+	patterns := make([]reader.PinnedPattern, 0, len(p.pins))
+	for _, pin := range p.pins {
+		patterns = append(patterns, reader.PinnedPattern{
+			Pattern: pin.pattern,
+			Style:   pin.style(),
+		})
+	}
+
+	return patterns
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func pinnedStrings(pager *Pager) []string {
+	returnMe := []string{}
+	for _, pin := range pager.pins {
+		returnMe = append(returnMe, pin.searchString)
+	}
+	return returnMe
+}
+
+func pinSearchFor(pager *Pager, searchString string) {
+	pager.searchString = searchString
+	pager.searchPattern = regexp.MustCompile(searchString)
+	pager.pinSearch()
+}
+
+func TestPinSearch(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "ERROR req-123\n"))
+	pager.screen = twin.NewFakeScreen(80, 5)
+
+	pinSearchFor(pager, "ERROR")
+	pinSearchFor(pager, "req-123")
+	assert.Assert(t, pager.searchPattern == nil)
+	assert.DeepEqual(t, []string{"ERROR", "req-123"}, pinnedStrings(pager))
+	assert.Assert(t, pager.pins[0].background != pager.pins[1].background)
+
+	// Pinning the same thing twice should do nothing
+	pinSearchFor(pager, "ERROR")
+	assert.DeepEqual(t, []string{"ERROR", "req-123"}, pinnedStrings(pager))
+
+	// Unpin from the pick menu, the remaining pin should keep its color
+	background := pager.pins[1].background
+	pager.mode = PagerModePins{pager: pager}
+	pager.mode.onRune('1')
+	assert.DeepEqual(t, []string{"req-123"}, pinnedStrings(pager))
+	assert.Equal(t, background, pager.pins[0].background)
+
+	// New pins should get the free color
+	pinSearchFor(pager, "apa")
+	assert.Equal(t, pinBackgrounds[0], pager.pins[1].background)
+
+	// Removing the last pin should close the pick menu
+	pager.mode.onRune('2')
+	pager.mode.onRune('1')
+	assert.Equal(t, 0, len(pager.pins))
+	_, isViewing := pager.mode.(PagerModeViewing)
+	assert.Assert(t, isViewing)
+}
+
+func TestPinSearchDropsOldest(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "text\n"))
+	pager.screen = twin.NewFakeScreen(80, 5)
+
+	for i := 0; i <= maxPins; i++ {
+		pinSearchFor(pager, string(rune('a'+i)))
+	}
+
+	assert.Equal(t, maxPins, len(pager.pins))
+	assert.Equal(t, "b", pager.pins[0].searchString)
+
+	// The new pin should get the color of the dropped one
+	assert.Equal(t, pinBackgrounds[0], pager.pins[maxPins-1].background)
+}
@@ -0,0 +1,477 @@
+package reader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/util"
+)
+
+var zipMagic = []byte{0x50, 0x4b, 0x03, 0x04}
+var emptyZipMagic = []byte{0x50, 0x4b, 0x05, 0x06}
+
+// Found at offset 257 in tar headers, followed by either "\x0000" (POSIX) or
+// "  \x00" (GNU)
+var tarMagic = []byte("ustar")
+
+const tarMagicOffset = 257
+
+type archiveFormat int
+
+const (
+	archiveFormatNone archiveFormat = iota
+	archiveFormatTar
+	archiveFormatZip
+)
+
+// A file inside of an archive
+type ArchiveMember struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// A file inside of a tar or zip archive, like "bundle.tar.gz:logs/app.log".
+// Get one from SplitArchiveMemberName().
+type ArchiveMemberPath struct {
+	ArchiveName string
+	MemberName  string
+
+	// Detected once when splitting the name, rather than every time the
+	// member is opened
+	format archiveFormat
+}
+
+// An archive shown as a listing of its members, one member per line
+type archiveListing struct {
+	lock sync.Mutex
+
+	fileName string
+	format   archiveFormat
+
+	// Line N of the listing is members[N]
+	members []ArchiveMember
+
+	// For opening members the same way the archive was opened
+	formatter chroma.Formatter
+	options   ReaderOptions
+}
+
+// Check whether a file is a (possibly compressed) tar archive or a zip archive
+func detectArchiveFormat(filename string) (archiveFormat, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return archiveFormatNone, err
+	}
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			log.Debugf("Failed to close %s after checking for zip magic: %s", filename, err)
+		}
+	}()
+
+	firstBytes := make([]byte, len(zipMagic))
+	_, err = io.ReadFull(file, firstBytes)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// Too short to be an archive
+		return archiveFormatNone, nil
+	}
+	if err != nil {
+		return archiveFormatNone, err
+	}
+	if bytes.Equal(firstBytes, zipMagic) || bytes.Equal(firstBytes, emptyZipMagic) {
+		return archiveFormatZip, nil
+	}
+
+	// Tar archives are usually compressed, so look inside
+	stream, _, err := ZOpen(filename)
+	if err != nil {
+		return archiveFormatNone, err
+	}
+	defer func() {
+		err := stream.Close()
+		if err != nil {
+			log.Debugf("Failed to close %s after checking for tar magic: %s", filename, err)
+		}
+	}()
+
+	header := make([]byte, tarMagicOffset+len(tarMagic))
+	_, err = io.ReadFull(stream, header)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// Too short to be a tar archive
+		return archiveFormatNone, nil
+	}
+	if err != nil {
+		// Could be a broken compressed file, let the regular reader deal with
+		// that
+		log.Debugf("Failed to read tar header from %s: %s", filename, err)
+		return archiveFormatNone, nil
+	}
+	if bytes.Equal(header[tarMagicOffset:], tarMagic) {
+		return archiveFormatTar, nil
+	}
+
+	return archiveFormatNone, nil
+}
+
+// Call onMember for each regular file in the archive, in archive order. Stop
+// early if onMember returns false.
+func walkArchive(filename string, format archiveFormat, onMember func(member ArchiveMember, contents io.Reader) bool) error {
+	switch format {
+	case archiveFormatZip:
+		zipReader, err := zip.OpenReader(filename)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err := zipReader.Close()
+			if err != nil {
+				log.Debugf("Failed to close zip archive %s: %s", filename, err)
+			}
+		}()
+
+		for _, file := range zipReader.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+
+			contents, err := file.Open()
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", filename, file.Name, err)
+			}
+
+			keepGoing := onMember(ArchiveMember{
+				Name:    file.Name,
+				Size:    int64(file.UncompressedSize64),
+				ModTime: file.Modified,
+			}, contents)
+
+			err = contents.Close()
+			if err != nil {
+				log.Debugf("Failed to close %s in zip archive %s: %s", file.Name, filename, err)
+			}
+
+			if !keepGoing {
+				return nil
+			}
+		}
+
+		return nil
+
+	case archiveFormatTar:
+		stream, _, err := ZOpen(filename)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err := stream.Close()
+			if err != nil {
+				log.Debugf("Failed to close tar archive %s: %s", filename, err)
+			}
+		}()
+
+		tarReader := tar.NewReader(stream)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", filename, err)
+			}
+
+			if header.Typeflag != tar.TypeReg {
+				// Directories, links and such
+				continue
+			}
+
+			keepGoing := onMember(ArchiveMember{
+				Name:    header.Name,
+				Size:    header.Size,
+				ModTime: header.ModTime,
+			}, tarReader)
+			if !keepGoing {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("%s: not an archive", filename)
+}
+
+// Format one line of an archive listing, like "1234  2024-05-06 07:08  a/b.txt"
+func formatArchiveMember(member ArchiveMember) string {
+	return fmt.Sprintf("%10s  %s  %s",
+		util.FormatInt(int(member.Size)),
+		member.ModTime.Local().Format("2006-01-02 15:04"),
+		member.Name)
+}
+
+// List the archive members in the background, one per line
+func newArchiveListingReader(filename string, format archiveFormat, formatter chroma.Formatter, options ReaderOptions) *ReaderImpl {
+	listing := &archiveListing{
+		fileName:  filename,
+		format:    format,
+		formatter: formatter,
+		options:   options,
+	}
+
+	// The listing itself should just be plain text
+	listingOptions := options
+	listingOptions.Lexer = nil
+	listingOptions.ShouldFormat = false
+
+	pipeReader, pipeWriter := io.Pipe()
+	returnMe := newReaderFromStream(pipeReader, nil, formatter, listingOptions, nil)
+	returnMe.Lock()
+	returnMe.Name = &filename
+	returnMe.archive = listing
+	returnMe.Unlock()
+	returnMe.HighlightingDone.Store(true)
+
+	if options.Style != nil {
+		returnMe.SetStyleForHighlighting(*options.Style)
+	}
+
+	go func() {
+		defer func() {
+			PanicHandler("newArchiveListingReader()", recover(), debug.Stack())
+		}()
+
+		var writeErr error
+		err := walkArchive(filename, format, func(member ArchiveMember, _ io.Reader) bool {
+			listing.lock.Lock()
+			listing.members = append(listing.members, member)
+			listing.lock.Unlock()
+
+			_, writeErr = io.WriteString(pipeWriter, formatArchiveMember(member)+"\n")
+			return writeErr == nil
+		})
+		if err == nil {
+			err = writeErr
+		}
+		if err != nil {
+			log.Warn("Listing archive members failed: ", err)
+		}
+
+		err = pipeWriter.Close()
+		if err != nil {
+			log.Debug("Closing archive listing pipe failed: ", err)
+		}
+	}()
+
+	return returnMe
+}
+
+// OpenArchiveMember opens a file inside of a tar or zip archive. Tar archives
+// can be compressed.
+//
+// The member itself is returned as-is, without any decompression.
+func OpenArchiveMember(path ArchiveMemberPath) (io.Reader, error) {
+	archiveName := path.ArchiveName
+	memberName := path.MemberName
+	format := path.format
+	if format == archiveFormatNone {
+		return nil, fmt.Errorf("%s: not a tar or zip archive", archiveName)
+	}
+
+	// Archive members can be large, so rather than reading the contents into
+	// memory we stream the member while walking the archive
+	pipeReader, pipeWriter := io.Pipe()
+	foundChannel := make(chan error, 1)
+	go func() {
+		defer func() {
+			PanicHandler("OpenArchiveMember()", recover(), debug.Stack())
+		}()
+
+		found := false
+		err := walkArchive(archiveName, format, func(member ArchiveMember, contents io.Reader) bool {
+			if member.Name != memberName {
+				return true
+			}
+
+			found = true
+			foundChannel <- nil
+
+			_, err := io.Copy(pipeWriter, contents)
+			if err != nil && !errors.Is(err, io.ErrClosedPipe) {
+				log.Warnf("Reading %s from %s failed: %s", memberName, archiveName, err)
+			}
+			return false
+		})
+
+		if !found {
+			if err == nil {
+				err = fmt.Errorf("%s: %s: %w", archiveName, memberName, os.ErrNotExist)
+			}
+			foundChannel <- err
+		}
+
+		_ = pipeWriter.CloseWithError(err)
+	}()
+
+	err := <-foundChannel
+	if err != nil {
+		return nil, err
+	}
+
+	return pipeReader, nil
+}
+
+// The display name of an archive member, like "bundle.tar.gz:logs/app.log".
+// This format is also accepted on the command line.
+func ArchiveMemberName(archiveName string, memberName string) string {
+	return archiveName + ":" + memberName
+}
+
+// SplitArchiveMemberName splits a file name like "bundle.tar.gz:logs/app.log"
+// into an archive name and a member name.
+//
+// Returns false if name is an existing file, or if there is no archive before
+// any of the colons.
+func SplitArchiveMemberName(name string) (ArchiveMemberPath, bool) {
+	_, err := os.Stat(name)
+	if err == nil {
+		// Existing files are never archive members
+		return ArchiveMemberPath{}, false
+	}
+
+	for i, char := range name {
+		if char != ':' {
+			continue
+		}
+
+		archiveName := name[:i]
+		stat, err := os.Stat(archiveName)
+		if err != nil || !stat.Mode().IsRegular() {
+			continue
+		}
+
+		format, err := detectArchiveFormat(archiveName)
+		if err != nil || format == archiveFormatNone {
+			continue
+		}
+
+		return ArchiveMemberPath{ArchiveName: archiveName, MemberName: name[i+1:], format: format}, true
+	}
+
+	return ArchiveMemberPath{}, false
+}
+
+// Strip any compression extension from a file name, like "x.log.gz" -> "x.log"
+func withoutCompressionExtension(filename string) string {
+	for _, extension := range []string{".gz", ".bz2", ".zst", ".zstd", ".xz", ".lz4", ".lz", brotliExtension} {
+		if strings.HasSuffix(filename, extension) {
+			return strings.TrimSuffix(filename, extension)
+		}
+	}
+	return filename
+}
+
+// NewFromArchiveMember creates a new reader for a file inside of a tar or zip
+// archive. Compressed members are decompressed.
+//
+// If options.Lexer is nil it will be determined from the member name.
+//
+// If options.Style is nil, you must call reader.SetStyleForHighlighting() later
+// to get highlighting.
+func NewFromArchiveMember(path ArchiveMemberPath, formatter chroma.Formatter, options ReaderOptions) (*ReaderImpl, error) {
+	memberName := path.MemberName
+	stream, err := OpenArchiveMember(path)
+	if err != nil {
+		return nil, err
+	}
+
+	zReader, err := ZReader(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Lexer == nil {
+		options.Lexer = lexers.Match(withoutCompressionExtension(memberName))
+	}
+
+	// Neither of these work with archive members
+	options.FileBacked = false
+	options.FollowName = false
+
+	returnMe := newReaderFromStream(zReader, nil, formatter, options, nil)
+
+	name := ArchiveMemberName(path.ArchiveName, memberName)
+	returnMe.Lock()
+	returnMe.Name = &name
+	returnMe.Unlock()
+
+	if options.Lexer == nil {
+		returnMe.HighlightingDone.Store(true)
+	}
+
+	if options.Style != nil {
+		returnMe.SetStyleForHighlighting(*options.Style)
+	}
+
+	return returnMe, nil
+}
+
+// IsArchiveListing is true if this reader lists the members of an archive
+func (reader *ReaderImpl) IsArchiveListing() bool {
+	return reader.archive != nil
+}
+
+// The name of the archive member listed on the given line, or an empty string
+// if there is none
+func (reader *ReaderImpl) ArchiveMemberAt(lineNumber linemetadata.Number) string {
+	if reader.archive == nil {
+		return ""
+	}
+
+	reader.archive.lock.Lock()
+	defer reader.archive.lock.Unlock()
+
+	index := lineNumber.AsZeroBased()
+	if index < 0 || index >= len(reader.archive.members) {
+		return ""
+	}
+	return reader.archive.members[index].Name
+}
+
+// Which archive this reader lists the members of, or an empty string if this
+// is not an archive listing
+func (reader *ReaderImpl) ArchiveName() string {
+	if reader.archive == nil {
+		return ""
+	}
+	return reader.archive.fileName
+}
+
+// OpenArchiveMember opens one of the members of the archive this reader is
+// listing, with the same options and highlighting style as the listing.
+func (reader *ReaderImpl) OpenArchiveMember(memberName string) (*ReaderImpl, error) {
+	if reader.archive == nil {
+		return nil, fmt.Errorf("not an archive listing")
+	}
+
+	reader.archive.lock.Lock()
+	options := reader.archive.options
+	reader.archive.lock.Unlock()
+
+	path := ArchiveMemberPath{
+		ArchiveName: reader.archive.fileName,
+		MemberName:  memberName,
+		format:      reader.archive.format,
+	}
+	return NewFromArchiveMember(path, reader.archive.formatter, options)
+}
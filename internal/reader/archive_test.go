@@ -0,0 +1,196 @@
+package reader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/styles"
+	"gotest.tools/v3/assert"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+)
+
+func gzipped(t *testing.T, contents string) string {
+	buffer := bytes.Buffer{}
+	writer := gzip.NewWriter(&buffer)
+	_, err := writer.Write([]byte(contents))
+	assert.NilError(t, err)
+	assert.NilError(t, writer.Close())
+	return buffer.String()
+}
+
+// Members are name / contents pairs
+func writeTarGz(t *testing.T, members ...string) string {
+	filename := path.Join(t.TempDir(), "bundle.tar.gz")
+	file, err := os.Create(filename)
+	assert.NilError(t, err)
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+	assert.NilError(t, tarWriter.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0o755}))
+	for i := 0; i < len(members); i += 2 {
+		assert.NilError(t, tarWriter.WriteHeader(&tar.Header{
+			Name:     members[i],
+			Typeflag: tar.TypeReg,
+			Mode:     0o644,
+			Size:     int64(len(members[i+1])),
+			ModTime:  time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+		}))
+		_, err = tarWriter.Write([]byte(members[i+1]))
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, tarWriter.Close())
+	assert.NilError(t, gzipWriter.Close())
+	assert.NilError(t, file.Close())
+
+	return filename
+}
+
+// Members are name / contents pairs
+func writeZip(t *testing.T, members ...string) string {
+	filename := path.Join(t.TempDir(), "bundle.zip")
+	file, err := os.Create(filename)
+	assert.NilError(t, err)
+
+	zipWriter := zip.NewWriter(file)
+	for i := 0; i < len(members); i += 2 {
+		writer, err := zipWriter.Create(members[i])
+		assert.NilError(t, err)
+		_, err = writer.Write([]byte(members[i+1]))
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, zipWriter.Close())
+	assert.NilError(t, file.Close())
+
+	return filename
+}
+
+func memberPath(t *testing.T, archiveName string, memberName string) ArchiveMemberPath {
+	t.Helper()
+
+	path, ok := SplitArchiveMemberName(ArchiveMemberName(archiveName, memberName))
+	assert.Assert(t, ok)
+	return path
+}
+
+func readAllLines(t *testing.T, reader *ReaderImpl) []string {
+	t.Helper()
+	assert.NilError(t, reader.Wait())
+
+	lines := []string{}
+	for _, line := range reader.GetLines(linemetadata.Index{}, 100).Lines {
+		lines = append(lines, line.Plain())
+	}
+	return lines
+}
+
+func testArchiveListing(t *testing.T, archiveName string) {
+	listing, err := NewFromFilename(archiveName, formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+
+	lines := readAllLines(t, listing)
+	assert.Equal(t, 2, len(lines), "%v", lines)
+	assert.Assert(t, strings.HasSuffix(lines[0], "  dir/one.txt"), lines[0])
+	assert.Assert(t, strings.HasSuffix(lines[1], "  two.log"), lines[1])
+
+	assert.Assert(t, listing.IsArchiveListing())
+	assert.Equal(t, "dir/one.txt", listing.ArchiveMemberAt(linemetadata.NumberFromZeroBased(0)))
+	assert.Equal(t, "two.log", listing.ArchiveMemberAt(linemetadata.NumberFromZeroBased(1)))
+	assert.Equal(t, "", listing.ArchiveMemberAt(linemetadata.NumberFromZeroBased(2)))
+
+	member, err := listing.OpenArchiveMember("two.log")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"second", "member"}, readAllLines(t, member))
+	assert.Equal(t, archiveName+":two.log", *member.Name)
+}
+
+func TestArchiveListingTarGz(t *testing.T) {
+	testArchiveListing(t, writeTarGz(t, "dir/one.txt", "first\n", "two.log", "second\nmember\n"))
+}
+
+func TestArchiveListingZip(t *testing.T) {
+	testArchiveListing(t, writeZip(t, "dir/one.txt", "first\n", "two.log", "second\nmember\n"))
+}
+
+func TestArchiveMemberCompressed(t *testing.T) {
+	archiveName := writeTarGz(t, "logs/app.log.gz", gzipped(t, "compressed\nmember\n"))
+
+	member, err := NewFromArchiveMember(memberPath(t, archiveName, "logs/app.log.gz"), formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"compressed", "member"}, readAllLines(t, member))
+}
+
+func TestArchiveMemberNotFound(t *testing.T) {
+	archiveName := writeZip(t, "one.txt", "first\n")
+
+	_, err := NewFromArchiveMember(memberPath(t, archiveName, "two.txt"), formatters.TTY16m, ReaderOptions{})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestOpenArchiveMemberStreaming(t *testing.T) {
+	contents := strings.Repeat("Streaming is fun\n", 100_000)
+	archiveName := writeTarGz(t, "first.txt", "first\n", "big.txt", contents, "last.txt", "last\n")
+
+	stream, err := OpenArchiveMember(memberPath(t, archiveName, "big.txt"))
+	assert.NilError(t, err)
+
+	all, err := io.ReadAll(stream)
+	assert.NilError(t, err)
+	assert.Equal(t, contents, string(all))
+}
+
+func TestSplitArchiveMemberName(t *testing.T) {
+	archiveName := writeTarGz(t, "dir/one.txt", "first\n")
+
+	member, ok := SplitArchiveMemberName(archiveName + ":dir/one.txt")
+	assert.Assert(t, ok)
+	assert.Equal(t, archiveName, member.ArchiveName)
+	assert.Equal(t, "dir/one.txt", member.MemberName)
+	assert.Equal(t, archiveFormatTar, member.format)
+
+	// Colons in member names are fine
+	member, ok = SplitArchiveMemberName(archiveName + ":a:b")
+	assert.Assert(t, ok)
+	assert.Equal(t, "a:b", member.MemberName)
+
+	// Not an archive
+	_, ok = SplitArchiveMemberName(path.Join(samplesDir, "short.txt") + ":dir/one.txt")
+	assert.Assert(t, !ok)
+
+	// Existing file
+	_, ok = SplitArchiveMemberName(archiveName)
+	assert.Assert(t, !ok)
+}
+
+func TestNotAnArchive(t *testing.T) {
+	for _, filename := range []string{"short.txt", "compressed.txt.gz", "empty"} {
+		format, err := detectArchiveFormat(path.Join(samplesDir, filename))
+		assert.NilError(t, err)
+		assert.Equal(t, archiveFormatNone, format, filename)
+	}
+}
+
+// Files like .jar and .docx are zip archives, but shouldn't be listed when
+// opened. Their members can still be opened by name.
+func TestZipInDisguise(t *testing.T) {
+	zipName := writeZip(t, "META-INF/MANIFEST.MF", "Manifest-Version: 1.0\n")
+	jarName := strings.TrimSuffix(zipName, ".zip") + ".jar"
+	assert.NilError(t, os.Rename(zipName, jarName))
+
+	jar, err := NewFromFilename(jarName, formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.Assert(t, !jar.IsArchiveListing())
+
+	member, err := NewFromArchiveMember(memberPath(t, jarName, "META-INF/MANIFEST.MF"), formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"Manifest-Version: 1.0"}, readAllLines(t, member))
+}
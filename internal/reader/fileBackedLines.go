@@ -0,0 +1,417 @@
+package reader
+
+import (
+	"bufio"
+	"compress/gzip"
+	"container/list"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Uncompressed files larger than this will be file backed rather than kept in
+// memory.
+//
+//revive:disable-next-line:var-naming
+const FILE_BACKED_MIN_SIZE int64 = 256 * 1024 * 1024
+
+// Compressed files larger than this will be file backed rather than kept in
+// memory, if their compression format supports seeking.
+//
+//revive:disable-next-line:var-naming
+const COMPRESSED_FILE_BACKED_MIN_SIZE int64 = 64 * 1024 * 1024
+
+// We keep the byte offset of the first line of every block in memory. To get a
+// line, we read its whole block from disk.
+//
+// Blocks are counted in input lines. Reformatting newline delimited JSON can
+// turn each input line into several lines.
+const fileBackedBlockLineCount = 256
+
+// Max number of decoded blocks to keep in memory
+const fileBackedCachedBlockCount = 64
+
+// Instead of keeping all lines in memory, keep a line offset index and re-read
+// lines from disk on demand. Used for files too large to fit in memory.
+//
+// Memory usage is one int64 and one int per fileBackedBlockLineCount input
+// lines, plus the decoded lines of up to fileBackedCachedBlockCount blocks.
+//
+// Not thread safe, the ReaderImpl lock protects this.
+type fileBackedLines struct {
+	fileName string
+
+	// Where we re-read lines from
+	source randomAccess
+
+	// Set if we're rendering JSON log lines
+	logTemplate *logTemplate
+
+	// Set if we're pretty printing newline delimited JSON, one input line
+	// into possibly several lines
+	reformatNdjson bool
+
+	// Byte offset of the first input line of each block
+	blockOffsets []int64
+
+	// Index of the first line of each block. Without reformatting, this is
+	// the block index times fileBackedBlockLineCount.
+	blockFirstLines []int
+
+	inputLineCount int
+
+	// Number of lines, after any reformatting
+	lineCount int
+
+	// The number of lines the last input line was reformatted into
+	lastInputLineLineCount int
+
+	// Byte offset of the end of the last line we know about. Lines are never
+	// read beyond this point, even if the file has grown.
+	endOffset int64
+
+	// Cached blocks, most recently used first. Values are *fileBackedBlock.
+	lru *list.List
+
+	// Block index to its element in the lru list
+	cache map[int]*list.Element
+}
+
+type fileBackedBlock struct {
+	index int
+	lines []*Line
+}
+
+// Uncompressed contents that can be read starting from any offset
+type randomAccess interface {
+	openAt(offset int64) (io.ReadCloser, error)
+}
+
+// An uncompressed file on disk
+type plainFileAccess struct {
+	fileName string
+}
+
+func (p plainFileAccess) openAt(offset int64) (io.ReadCloser, error) {
+	file, err := os.Open(p.fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func newFileBackedLines(fileName string, source randomAccess) *fileBackedLines {
+	return &fileBackedLines{
+		fileName: fileName,
+		source:   source,
+		lru:      list.New(),
+		cache:    make(map[int]*list.Element),
+	}
+}
+
+// Returns nil if the file should be kept in memory
+func maybeFileBacked(file *os.File, options ReaderOptions) *fileBackedLines {
+	if options.FollowName {
+		// If the file gets replaced we won't be able to re-read the old lines
+		if options.FileBacked {
+			log.Info("Can't be file backed when following the file name: ", file.Name())
+		}
+		return nil
+	}
+
+	if options.FileBacked {
+		return newFileBackedLines(file.Name(), plainFileAccess{file.Name()})
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		log.Debug("Failed to stat ", file.Name(), ", keeping it in memory: ", err)
+		return nil
+	}
+
+	if !stat.Mode().IsRegular() {
+		// Might be a named pipe or something, we can't re-read those
+		return nil
+	}
+
+	if stat.Size() < FILE_BACKED_MIN_SIZE {
+		return nil
+	}
+
+	log.Info("Large file, re-reading lines from disk on demand: ", file.Name(), " is ", stat.Size(), " bytes")
+	return newFileBackedLines(file.Name(), plainFileAccess{file.Name()})
+}
+
+// Like maybeFileBacked(), but for compressed files. Only gzip and seekable zstd
+// files can be file backed, by decompressing from the closest checkpoint or
+// frame before the line we want.
+//
+// The returned stream should be read instead of the passed one. If we return
+// nil file backing, the returned stream is the passed one.
+func maybeCompressedFileBacked(fileName string, stream io.ReadCloser, options ReaderOptions) (io.ReadCloser, *fileBackedLines) {
+	if options.FollowName {
+		if options.FileBacked {
+			log.Info("Can't be file backed when following the file name: ", fileName)
+		}
+		return stream, nil
+	}
+
+	if !options.FileBacked {
+		stat, err := os.Stat(fileName)
+		if err != nil {
+			log.Debug("Failed to stat ", fileName, ", keeping it in memory: ", err)
+			return stream, nil
+		}
+
+		if !stat.Mode().IsRegular() || stat.Size() < COMPRESSED_FILE_BACKED_MIN_SIZE {
+			return stream, nil
+		}
+	}
+
+	if decompressed, ok := stream.(decompressedFile); ok {
+		if _, isGzip := decompressed.ReadCloser.(*gzip.Reader); isGzip {
+			// Decompress with the indexing reader from the start, so that we
+			// have checkpoints for everything we have read
+			index := newGzipIndex(fileName)
+			indexed, err := index.openAt(0)
+			if err != nil {
+				log.Debug("Failed to open ", fileName, " for indexing, keeping it in memory: ", err)
+				return stream, nil
+			}
+			_ = stream.Close()
+
+			log.Info("Large gzip file, re-decompressing lines from checkpoints on demand: ", fileName)
+			return indexed, newFileBackedLines(fileName, index)
+		}
+	}
+
+	seekTable, err := readZstdSeekTable(fileName)
+	if err != nil {
+		log.Debug("Failed to look for a zstd seek table in ", fileName, ": ", err)
+		return stream, nil
+	}
+	if seekTable != nil {
+		log.Info("Large seekable zstd file, re-decompressing lines from frames on demand: ", fileName)
+		return stream, newFileBackedLines(fileName, seekTable)
+	}
+
+	log.Info("Can't be file backed, compression format doesn't support seeking: ", fileName)
+	return stream, nil
+}
+
+// Register a new input line starting at startOffset and ending at endOffset.
+// The end offset includes the line's newline, if any. The line count is the
+// number of lines the input line turns into, see reformatNdjsonLine().
+func (f *fileBackedLines) addLine(startOffset int64, endOffset int64, lineCount int) {
+	if f.inputLineCount%fileBackedBlockLineCount == 0 {
+		f.blockOffsets = append(f.blockOffsets, startOffset)
+		f.blockFirstLines = append(f.blockFirstLines, f.lineCount)
+	}
+
+	f.inputLineCount++
+	f.lineCount += lineCount
+	f.lastInputLineLineCount = lineCount
+	f.endOffset = endOffset
+
+	// The cached version of this block, if any, is now missing a line
+	f.dropBlock(len(f.blockOffsets) - 1)
+}
+
+// The last input line didn't end with a newline, but more bytes were appended
+// to it. The line count is the number of lines the whole input line now turns
+// into.
+func (f *fileBackedLines) extendLastLine(endOffset int64, lineCount int) {
+	f.lineCount += lineCount - f.lastInputLineLineCount
+	f.lastInputLineLineCount = lineCount
+	f.endOffset = endOffset
+
+	// The cached version of the last line is now outdated
+	f.dropBlock(len(f.blockOffsets) - 1)
+}
+
+func (f *fileBackedLines) dropBlock(blockIndex int) {
+	element, found := f.cache[blockIndex]
+	if !found {
+		return
+	}
+
+	f.lru.Remove(element)
+	delete(f.cache, blockIndex)
+}
+
+// Get the line at the given zero based index, which must be less than
+// lineCount.
+func (f *fileBackedLines) getLine(index int) *Line {
+	return f.getLines(index, 1)[0]
+}
+
+// Get count lines starting at the given zero based index. All lines must be
+// before lineCount.
+//
+// Consecutive uncached blocks are read in one go, so that compressed input
+// doesn't get decompressed from a checkpoint once per block.
+func (f *fileBackedLines) getLines(firstIndex int, count int) []*Line {
+	lines := make([]*Line, 0, count)
+	lastBlock := f.blockIndexOf(firstIndex + count - 1)
+	blockIndex := f.blockIndexOf(firstIndex)
+	for blockIndex <= lastBlock {
+		var blocks [][]*Line
+		if element, found := f.cache[blockIndex]; found {
+			f.lru.MoveToFront(element)
+			blocks = [][]*Line{element.Value.(*fileBackedBlock).lines}
+		} else {
+			runEnd := blockIndex
+			for runEnd < lastBlock && f.cache[runEnd+1] == nil {
+				runEnd++
+			}
+
+			var err error
+			blocks, err = f.readBlocks(blockIndex, runEnd)
+			if err != nil {
+				log.Warn("Failed to re-read lines from ", f.fileName, ": ", err)
+
+				// Don't cache these, maybe we'll have better luck next time
+				blocks = f.emptyBlocks(blockIndex, runEnd)
+			} else {
+				for i, block := range blocks {
+					f.cacheBlock(blockIndex+i, block)
+				}
+			}
+		}
+
+		for _, block := range blocks {
+			blockFirstLine := f.blockFirstLines[blockIndex]
+			first := max(0, firstIndex-blockFirstLine)
+			last := min(len(block), firstIndex+count-blockFirstLine)
+			lines = append(lines, block[first:last]...)
+			blockIndex++
+		}
+	}
+
+	return lines
+}
+
+// The index of the block containing the line with the given index
+func (f *fileBackedLines) blockIndexOf(lineIndex int) int {
+	// The last block starting at or before the line
+	return sort.Search(len(f.blockFirstLines), func(i int) bool {
+		return f.blockFirstLines[i] > lineIndex
+	}) - 1
+}
+
+// Number of lines in the given block, after any reformatting
+func (f *fileBackedLines) blockLineCount(blockIndex int) int {
+	if blockIndex+1 < len(f.blockFirstLines) {
+		return f.blockFirstLines[blockIndex+1] - f.blockFirstLines[blockIndex]
+	}
+	return f.lineCount - f.blockFirstLines[blockIndex]
+}
+
+func (f *fileBackedLines) cacheBlock(blockIndex int, lines []*Line) {
+	f.cache[blockIndex] = f.lru.PushFront(&fileBackedBlock{index: blockIndex, lines: lines})
+	if f.lru.Len() > fileBackedCachedBlockCount {
+		oldest := f.lru.Back()
+		f.lru.Remove(oldest)
+		delete(f.cache, oldest.Value.(*fileBackedBlock).index)
+	}
+}
+
+// Stand-ins for blocks we failed to read
+func (f *fileBackedLines) emptyBlocks(firstBlock int, lastBlock int) [][]*Line {
+	blocks := make([][]*Line, 0, lastBlock-firstBlock+1)
+	for blockIndex := firstBlock; blockIndex <= lastBlock; blockIndex++ {
+		block := make([]*Line, 0, f.blockLineCount(blockIndex))
+		for range f.blockLineCount(blockIndex) {
+			line := NewLine("")
+			block = append(block, &line)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// Read all lines of the given blocks from disk, in one pass
+func (f *fileBackedLines) readBlocks(firstBlock int, lastBlock int) ([][]*Line, error) {
+	startOffset := f.blockOffsets[firstBlock]
+	source, err := f.source.openAt(startOffset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := source.Close()
+		if err != nil {
+			log.Warn("Error closing file after re-reading lines: ", err)
+		}
+	}()
+
+	section := io.LimitReader(source, f.endOffset-startOffset)
+
+	// Split lines exactly like consumeLinesFromStream() does, or we'll end up
+	// with different line contents depending on whether we're file backed or
+	// not.
+	bufioReader := bufio.NewReader(section)
+	blocks := make([][]*Line, 0, lastBlock-firstBlock+1)
+	for blockIndex := firstBlock; blockIndex <= lastBlock; blockIndex++ {
+		inputLineCount := min(fileBackedBlockLineCount, f.inputLineCount-blockIndex*fileBackedBlockLineCount)
+		lines := make([]*Line, 0, f.blockLineCount(blockIndex))
+		completeLine := make([]byte, 0)
+		readCount := 0
+		for readCount < inputLineCount {
+			lineBytes, isPrefix, err := bufioReader.ReadLine()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			completeLine = append(completeLine, lineBytes...)
+			if isPrefix {
+				continue
+			}
+
+			lines = f.appendLines(lines, string(completeLine))
+			readCount++
+			completeLine = completeLine[:0]
+		}
+
+		if len(completeLine) > 0 && readCount < inputLineCount {
+			// Last line without a trailing newline
+			lines = f.appendLines(lines, string(completeLine))
+			readCount++
+		}
+
+		if readCount != inputLineCount || len(lines) != f.blockLineCount(blockIndex) {
+			return nil, fmt.Errorf("expected %d lines in block %d at offset %d, got %d",
+				inputLineCount, blockIndex, f.blockOffsets[blockIndex], readCount)
+		}
+
+		blocks = append(blocks, lines)
+	}
+
+	return blocks, nil
+}
+
+// Append the lines one input line turns into
+func (f *fileBackedLines) appendLines(lines []*Line, inputLine string) []*Line {
+	parts := []string{inputLine}
+	if f.reformatNdjson {
+		parts = reformatNdjsonLine(inputLine)
+	}
+
+	for _, part := range parts {
+		line := newLineMaybeLog(part, f.logTemplate)
+		lines = append(lines, &line)
+	}
+	return lines
+}
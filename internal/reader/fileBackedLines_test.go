@@ -0,0 +1,152 @@
+package reader
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/chroma/v2"
+	"gotest.tools/v3/assert"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+)
+
+// Without highlighting, since file backed readers don't highlight
+func readFileForTesting(t *testing.T, fileName string, fileBacked bool) *ReaderImpl {
+	t.Helper()
+
+	reader, err := NewFromFilename(fileName, nil, ReaderOptions{Style: &chroma.Style{}, FileBacked: fileBacked})
+	assert.NilError(t, err)
+	assert.NilError(t, reader.Wait())
+
+	return reader
+}
+
+// File backed readers should return the same lines as in-memory ones
+func TestFileBackedSameAsInMemory(t *testing.T) {
+	for _, fileName := range getTestFiles(t) {
+		t.Run(path.Base(fileName), func(t *testing.T) {
+			fileBacked := readFileForTesting(t, fileName, true)
+			if fileBacked.fileBacked == nil {
+				// Compression format doesn't support seeking
+				return
+			}
+			inMemory := readFileForTesting(t, fileName, false)
+
+			assert.Equal(t, inMemory.GetLineCount(), fileBacked.GetLineCount())
+
+			expected := inMemory.GetLines(linemetadata.Index{}, inMemory.GetLineCount())
+			actual := fileBacked.GetLines(linemetadata.Index{}, fileBacked.GetLineCount())
+			assert.Equal(t, len(expected.Lines), len(actual.Lines))
+			for i := range expected.Lines {
+				assert.Equal(t, expected.Lines[i].Line.raw, actual.Lines[i].Line.raw, "Line %d", i)
+			}
+			assert.Equal(t, expected.StatusText, actual.StatusText)
+		})
+	}
+}
+
+// Reformatting NDJSON turns each input line into several lines, spanning
+// block boundaries
+func TestFileBackedReformatNdjson(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "many-lines.ndjson")
+	contents := strings.Builder{}
+	for i := range 3 * fileBackedBlockLineCount {
+		if i%7 == 3 {
+			contents.WriteString("Not JSON\n")
+			continue
+		}
+		contents.WriteString(fmt.Sprintf(`{"line": %d, "msg": "hello"}`+"\n", i))
+	}
+	assert.NilError(t, os.WriteFile(fileName, []byte(contents.String()), 0o600))
+
+	read := func(fileBacked bool) *ReaderImpl {
+		reader, err := NewFromFilename(fileName, nil, ReaderOptions{Style: &chroma.Style{}, FileBacked: fileBacked, ShouldFormat: true})
+		assert.NilError(t, err)
+		assert.NilError(t, reader.Wait())
+		return reader
+	}
+	fileBacked := read(true)
+	assert.Assert(t, fileBacked.fileBacked != nil)
+	inMemory := read(false)
+
+	assert.Equal(t, inMemory.GetLineCount(), fileBacked.GetLineCount())
+	assert.Assert(t, fileBacked.GetLineCount() > 3*fileBackedBlockLineCount)
+
+	// Back to front, so that blocks get read in some other order than the
+	// lines were added
+	for i := inMemory.GetLineCount() - 1; i >= 0; i-- {
+		index := linemetadata.IndexFromZeroBased(i)
+		assert.Equal(t, inMemory.GetLine(index).Plain(), fileBacked.GetLine(index).Plain(), "Line %d", i)
+	}
+}
+
+func TestFileBackedCacheIsBounded(t *testing.T) {
+	// Long lines to verify that we can handle lines longer than bufio's
+	// buffer
+	longLine := strings.Repeat("x", 5000)
+
+	const lineCount = fileBackedBlockLineCount * (fileBackedCachedBlockCount + 10)
+	fileName := path.Join(t.TempDir(), "many-lines.txt")
+	contents := strings.Builder{}
+	for i := range lineCount {
+		if i%1000 == 0 {
+			contents.WriteString(longLine)
+		}
+		contents.WriteString(fmt.Sprintf("Line %d\r\n", i))
+	}
+	assert.NilError(t, os.WriteFile(fileName, []byte(contents.String()), 0o600))
+
+	reader := readFileForTesting(t, fileName, true)
+	assert.Equal(t, lineCount, reader.GetLineCount())
+	assert.Equal(t, 0, len(reader.lines))
+
+	// Back to front, to get a few cache evictions
+	for i := lineCount - 1; i >= 0; i-- {
+		line := reader.GetLine(linemetadata.IndexFromZeroBased(i))
+		expected := fmt.Sprintf("Line %d", i)
+		if i%1000 == 0 {
+			expected = longLine + expected
+		}
+		assert.Equal(t, expected, line.Plain())
+	}
+
+	assert.Equal(t, fileBackedCachedBlockCount, reader.fileBacked.lru.Len())
+	assert.Equal(t, fileBackedCachedBlockCount, len(reader.fileBacked.cache))
+}
+
+// Verify that tailing works for file backed files, including appending to a
+// last line without a trailing newline
+func TestFileBackedTailing(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "moor-TestFileBackedTailing-*.txt")
+	assert.NilError(t, err)
+	defer file.Close() //nolint:errcheck
+
+	_, err = file.WriteString("First line\nSecond")
+	assert.NilError(t, err)
+
+	testMe := readFileForTesting(t, file.Name(), true)
+	assert.Assert(t, testMe.fileBacked != nil)
+	assert.Equal(t, 2, testMe.GetLineCount())
+	assert.Equal(t, "Second", testMe.GetLine(linemetadata.IndexFromZeroBased(1)).Plain())
+
+	_, err = file.WriteString(" line\nThird line\n")
+	assert.NilError(t, err)
+
+	// Give the reader some time to react
+	for range 20 {
+		if testMe.GetLineCount() == 3 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	allLines := testMe.GetLines(linemetadata.Index{}, 10)
+	assert.Equal(t, len(allLines.Lines), 3)
+	assert.Equal(t, allLines.Lines[0].Plain(), "First line")
+	assert.Equal(t, allLines.Lines[1].Plain(), "Second line")
+	assert.Equal(t, allLines.Lines[2].Plain(), "Third line")
+}
@@ -0,0 +1,92 @@
+//go:build linux
+// +build linux
+
+package reader
+
+import (
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// If inotify doesn't tell us anything for this long, check the file anyway.
+// Inotify doesn't work on all file systems, network file systems in particular.
+const inotifySafetyTimeout = 10 * time.Second
+
+// Tells us when a file might have changed, using inotify
+type fileWatcher struct {
+	fileName string
+
+	// Inotify file descriptor, -1 means we're polling
+	fd int
+}
+
+// If watchDirectory is set, we'll also be woken up when files are created in
+// or moved into the file's directory. This is for noticing when the file is
+// replaced.
+func newFileWatcher(fileName string, watchDirectory bool) *fileWatcher {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		log.Debugf("Inotify init failed, polling %s for changes: %s", fileName, err.Error())
+		return &fileWatcher{fileName: fileName, fd: -1}
+	}
+
+	_, err = unix.InotifyAddWatch(fd, fileName,
+		unix.IN_MODIFY|unix.IN_ATTRIB|unix.IN_CLOSE_WRITE|unix.IN_MOVE_SELF|unix.IN_DELETE_SELF)
+	if err != nil {
+		log.Debugf("Inotify watch failed, polling %s for changes: %s", fileName, err.Error())
+		_ = unix.Close(fd)
+		return &fileWatcher{fileName: fileName, fd: -1}
+	}
+
+	if watchDirectory {
+		_, err = unix.InotifyAddWatch(fd, filepath.Dir(fileName), unix.IN_CREATE|unix.IN_MOVED_TO)
+		if err != nil {
+			log.Debugf("Inotify watch failed, polling the directory of %s for changes: %s", fileName, err.Error())
+			_ = unix.Close(fd)
+			return &fileWatcher{fileName: fileName, fd: -1}
+		}
+	}
+
+	return &fileWatcher{fileName: fileName, fd: fd}
+}
+
+// Returns when the file might have changed
+func (w *fileWatcher) waitForChange() {
+	if w.fd < 0 {
+		time.Sleep(pollInterval)
+		return
+	}
+
+	pollFds := []unix.PollFd{{Fd: int32(w.fd), Events: unix.POLLIN}}
+	_, err := unix.Poll(pollFds, int(inotifySafetyTimeout.Milliseconds()))
+	if err != nil && err != unix.EINTR {
+		log.Debugf("Polling inotify for %s failed: %s", w.fileName, err.Error())
+		time.Sleep(pollInterval)
+		return
+	}
+
+	// Drain the events. We don't care what they say, our caller will find out
+	// what changed on its own.
+	buffer := make([]byte, 4096)
+	for {
+		count, err := unix.Read(w.fd, buffer)
+		if count <= 0 || err != nil {
+			break
+		}
+	}
+}
+
+func (w *fileWatcher) close() {
+	if w.fd < 0 {
+		return
+	}
+
+	err := unix.Close(w.fd)
+	if err != nil {
+		log.Debugf("Closing inotify for %s failed: %s", w.fileName, err.Error())
+	}
+	w.fd = -1
+}
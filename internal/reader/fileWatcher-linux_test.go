@@ -0,0 +1,56 @@
+//go:build linux
+// +build linux
+
+package reader
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/styles"
+	"gotest.tools/v3/assert"
+)
+
+// With inotify, we should notice appended lines well before the next poll
+func TestTailingIsPrompt(t *testing.T) {
+	// We aim for below 100ms, with some margin for slow CI machines. Polling
+	// would take up to a full pollInterval.
+	const maxLatency = 200 * time.Millisecond
+
+	file, err := os.CreateTemp(t.TempDir(), "moor-TestTailingIsPrompt-*.txt")
+	assert.NilError(t, err)
+	defer file.Close() //nolint:errcheck
+
+	_, err = file.WriteString("First line\n")
+	assert.NilError(t, err)
+
+	testMe, err := NewFromFilename(file.Name(), formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.NilError(t, testMe.Wait())
+
+	// Give the reader time to start tailing
+	time.Sleep(100 * time.Millisecond)
+
+	// Drop any notification from the initial read
+	select {
+	case <-testMe.MoreLinesAdded:
+	default:
+	}
+
+	for _, line := range []string{"Second line\n", "Third line\n"} {
+		t0 := time.Now()
+		_, err = file.WriteString(line)
+		assert.NilError(t, err)
+
+		select {
+		case <-testMe.MoreLinesAdded:
+			assert.Assert(t, time.Since(t0) < maxLatency, "Tailing took %s", time.Since(t0))
+		case <-time.After(maxLatency):
+			t.Fatalf("No new lines %s after appending to the file", time.Since(t0))
+		}
+	}
+
+	assert.Equal(t, 3, testMe.GetLineCount())
+}
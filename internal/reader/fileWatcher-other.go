@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package reader
+
+import "time"
+
+// Tells us when a file might have changed. On this platform we just poll.
+type fileWatcher struct{}
+
+func newFileWatcher(_ string, _ bool) *fileWatcher {
+	return &fileWatcher{}
+}
+
+// Returns when the file might have changed
+func (w *fileWatcher) waitForChange() {
+	time.Sleep(pollInterval)
+}
+
+func (w *fileWatcher) close() {
+}
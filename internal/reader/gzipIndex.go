@@ -0,0 +1,762 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Record a gzip checkpoint at the first deflate block boundary after this many
+// uncompressed bytes since the last checkpoint. More checkpoints means faster
+// seeking but more memory.
+const gzipCheckpointSpan = 1024 * 1024
+
+const deflateWindowSize = 32 * 1024
+
+const maxHuffmanCodeLength = 15
+
+var deflateLengthBase = [29]uint16{3, 4, 5, 6, 7, 8, 9, 10, 11, 13, 15, 17, 19, 23, 27, 31, 35, 43, 51, 59, 67, 83, 99, 115, 131, 163, 195, 227, 258}
+var deflateLengthExtraBits = [29]uint8{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 4, 4, 4, 4, 5, 5, 5, 5, 0}
+var deflateDistanceBase = [30]uint32{1, 2, 3, 4, 5, 7, 9, 13, 17, 25, 33, 49, 65, 97, 129, 193, 257, 385, 513, 769, 1025, 1537, 2049, 3073, 4097, 6145, 8193, 12289, 16385, 24577}
+var deflateDistanceExtraBits = [30]uint8{0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 11, 11, 12, 12, 13, 13}
+var deflateCodeLengthOrder = [19]uint8{16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15}
+
+var fixedLiteralTable, fixedDistanceTable = fixedHuffmanTables()
+
+// Where and how to resume decompressing a gzip file in the middle
+type gzipCheckpoint struct {
+	// How much uncompressed data comes before this checkpoint
+	uncompressedOffset int64
+
+	// Bit offset into the compressed file of a deflate block start
+	compressedBitOffset int64
+
+	// The last (up to) 32kB of uncompressed data before this checkpoint, which
+	// later blocks can refer back to. Compressed to save memory.
+	window []byte
+}
+
+// Checkpoints for a gzip file. Checkpoints are collected while doing the
+// initial read, so re-reading a line never has to decompress more than about
+// gzipCheckpointSpan bytes.
+//
+// Thread safe.
+type gzipIndex struct {
+	fileName string
+
+	lock sync.Mutex
+
+	// Sorted by uncompressedOffset
+	checkpoints []gzipCheckpoint
+}
+
+func newGzipIndex(fileName string) *gzipIndex {
+	return &gzipIndex{fileName: fileName}
+}
+
+// Reads a bit stream, least significant bit first, like deflate wants it
+type bitReader struct {
+	input *bufio.Reader
+
+	// How many bytes we have read from the input
+	inputOffset int64
+
+	bits  uint64
+	count uint
+}
+
+func (b *bitReader) refill() error {
+	for b.count <= 56 {
+		byteValue, err := b.input.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		b.bits |= uint64(byteValue) << b.count
+		b.count += 8
+		b.inputOffset++
+	}
+
+	return nil
+}
+
+func (b *bitReader) need(count uint) error {
+	if b.count >= count {
+		return nil
+	}
+
+	err := b.refill()
+	if err != nil {
+		return err
+	}
+	if b.count < count {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (b *bitReader) take(count uint) (uint32, error) {
+	err := b.need(count)
+	if err != nil {
+		return 0, err
+	}
+
+	value := uint32(b.bits & (1<<count - 1))
+	b.bits >>= count
+	b.count -= count
+	return value, nil
+}
+
+func (b *bitReader) alignToByte() {
+	b.bits >>= b.count % 8
+	b.count -= b.count % 8
+}
+
+// Returns true if there is no more input
+func (b *bitReader) atEOF() (bool, error) {
+	if b.count > 0 {
+		return false, nil
+	}
+	err := b.refill()
+	return b.count == 0, err
+}
+
+// The bit offset into the input of the next bit we'll return
+func (b *bitReader) bitOffset() int64 {
+	return b.inputOffset*8 - int64(b.count)
+}
+
+// A canonical Huffman code lookup table
+type huffmanTable struct {
+	// Indexed by the next tableBits bits of input, least significant bit
+	// first. Each entry is symbol<<4 | code length, or zero for invalid codes.
+	entries []uint32
+
+	tableBits uint
+}
+
+func newHuffmanTable(lengths []uint8) (*huffmanTable, error) {
+	var counts [maxHuffmanCodeLength + 1]int
+	var tableBits uint
+	for _, length := range lengths {
+		counts[length]++
+		tableBits = max(tableBits, uint(length))
+	}
+	counts[0] = 0
+
+	// Over-subscribed codes are invalid, incomplete codes are allowed
+	left := 1
+	for length := 1; length <= maxHuffmanCodeLength; length++ {
+		left <<= 1
+		left -= counts[length]
+		if left < 0 {
+			return nil, errors.New("over-subscribed Huffman code")
+		}
+	}
+
+	var nextCode [maxHuffmanCodeLength + 1]int
+	code := 0
+	for length := 1; length <= maxHuffmanCodeLength; length++ {
+		code = (code + counts[length-1]) << 1
+		nextCode[length] = code
+	}
+
+	table := &huffmanTable{
+		entries:   make([]uint32, 1<<tableBits),
+		tableBits: tableBits,
+	}
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+
+		code := nextCode[length]
+		nextCode[length]++
+
+		// Codes are stored most significant bit first, but we read least
+		// significant bit first
+		reversed := 0
+		for i := uint8(0); i < length; i++ {
+			reversed = reversed<<1 | (code>>i)&1
+		}
+
+		for i := reversed; i < len(table.entries); i += 1 << length {
+			table.entries[i] = uint32(symbol)<<4 | uint32(length)
+		}
+	}
+
+	return table, nil
+}
+
+func fixedHuffmanTables() (*huffmanTable, *huffmanTable) {
+	literalLengths := make([]uint8, 288)
+	for i := range literalLengths {
+		switch {
+		case i < 144:
+			literalLengths[i] = 8
+		case i < 256:
+			literalLengths[i] = 9
+		case i < 280:
+			literalLengths[i] = 7
+		default:
+			literalLengths[i] = 8
+		}
+	}
+	literalTable, err := newHuffmanTable(literalLengths)
+	if err != nil {
+		panic(err)
+	}
+
+	distanceLengths := make([]uint8, 30)
+	for i := range distanceLengths {
+		distanceLengths[i] = 5
+	}
+	distanceTable, err := newHuffmanTable(distanceLengths)
+	if err != nil {
+		panic(err)
+	}
+
+	return literalTable, distanceTable
+}
+
+func (b *bitReader) decodeSymbol(table *huffmanTable) (int, error) {
+	if b.count < table.tableBits {
+		err := b.refill()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Near the end of the input we might have fewer bits than the table
+	// wants, but the code we're looking for could still be short enough
+	entry := table.entries[b.bits&(1<<table.tableBits-1)]
+	length := uint(entry & 0xf)
+	if length == 0 {
+		return 0, errors.New("invalid Huffman code")
+	}
+	if length > b.count {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	b.bits >>= length
+	b.count -= length
+	return int(entry >> 4), nil
+}
+
+type gzipState int
+
+const (
+	gzipStateHeader gzipState = iota
+	gzipStateBlock
+	gzipStateDone
+)
+
+// A gzip decompressor that can record checkpoints while decompressing, and
+// resume decompressing from such a checkpoint. Multi member gzip files are
+// supported. Checksums are not verified, since we usually start in the middle
+// of a member.
+//
+// The standard library's gzip decompressor can do neither. Deflate blocks
+// don't start on byte boundaries, and compress/flate neither tells us where
+// blocks start nor lets us start decompressing in the middle of a byte. So
+// this has its own deflate implementation, about 30% slower than the standard
+// library's.
+type gzipIndexReader struct {
+	bits   bitReader
+	closer io.Closer
+
+	state gzipState
+
+	// True if we have read at least one gzip header
+	sawHeader bool
+
+	window    [deflateWindowSize]byte
+	windowPos int
+
+	// How many bytes in the window are valid
+	windowFill int
+
+	// How many uncompressed bytes come before the end of out
+	uncompressedOffset int64
+
+	// Decompressed but not yet returned
+	out       []byte
+	outOffset int
+
+	// If set, receives checkpoints while decompressing
+	index          *gzipIndex
+	lastCheckpoint int64
+}
+
+func (r *gzipIndexReader) Close() error {
+	return r.closer.Close()
+}
+
+func (r *gzipIndexReader) Read(p []byte) (int, error) {
+	for r.outOffset >= len(r.out) {
+		r.out = r.out[:0]
+		r.outOffset = 0
+
+		var err error
+		switch r.state {
+		case gzipStateHeader:
+			err = r.readHeader()
+		case gzipStateBlock:
+			err = r.readBlock()
+		case gzipStateDone:
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	count := copy(p, r.out[r.outOffset:])
+	r.outOffset += count
+	return count, nil
+}
+
+func (r *gzipIndexReader) readByte() (byte, error) {
+	value, err := r.bits.take(8)
+	return byte(value), err
+}
+
+// Skip a zero terminated string
+func (r *gzipIndexReader) skipString() error {
+	for {
+		value, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if value == 0 {
+			return nil
+		}
+	}
+}
+
+// Ref: https://www.rfc-editor.org/rfc/rfc1952#section-2.3
+func (r *gzipIndexReader) readHeader() error {
+	r.bits.alignToByte()
+
+	if r.sawHeader {
+		eof, err := r.bits.atEOF()
+		if err != nil {
+			return err
+		}
+		if eof {
+			r.state = gzipStateDone
+			return nil
+		}
+	}
+
+	header := make([]byte, 10)
+	for i := range header {
+		value, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		header[i] = value
+	}
+	if !bytes.HasPrefix(header, gzipMagic) || header[2] != 8 {
+		if r.sawHeader {
+			// Trailing garbage, like zero padding, is fine
+			log.Debug("Ignoring trailing garbage after gzip member")
+			r.state = gzipStateDone
+			return nil
+		}
+		return errors.New("not a gzip file")
+	}
+
+	flags := header[3]
+	if flags&0x04 != 0 {
+		// FEXTRA
+		length, err := r.bits.take(16)
+		if err != nil {
+			return err
+		}
+		for i := uint32(0); i < length; i++ {
+			_, err := r.readByte()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if flags&0x08 != 0 {
+		// FNAME
+		err := r.skipString()
+		if err != nil {
+			return err
+		}
+	}
+	if flags&0x10 != 0 {
+		// FCOMMENT
+		err := r.skipString()
+		if err != nil {
+			return err
+		}
+	}
+	if flags&0x02 != 0 {
+		// FHCRC
+		_, err := r.bits.take(16)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.sawHeader = true
+	r.windowFill = 0
+	r.state = gzipStateBlock
+	return nil
+}
+
+func (r *gzipIndexReader) emit(value byte) {
+	r.window[r.windowPos] = value
+	r.windowPos = (r.windowPos + 1) % deflateWindowSize
+	r.windowFill = min(r.windowFill+1, deflateWindowSize)
+	r.out = append(r.out, value)
+	r.uncompressedOffset++
+}
+
+// The window contents, oldest byte first
+func (r *gzipIndexReader) windowContents() []byte {
+	contents := make([]byte, 0, r.windowFill)
+	start := (r.windowPos - r.windowFill + deflateWindowSize) % deflateWindowSize
+	if start+r.windowFill <= deflateWindowSize {
+		return append(contents, r.window[start:start+r.windowFill]...)
+	}
+
+	contents = append(contents, r.window[start:]...)
+	return append(contents, r.window[:r.windowPos]...)
+}
+
+func (r *gzipIndexReader) maybeCheckpoint() {
+	if r.index == nil {
+		return
+	}
+	if r.uncompressedOffset > 0 && r.uncompressedOffset-r.lastCheckpoint < gzipCheckpointSpan {
+		return
+	}
+	r.lastCheckpoint = r.uncompressedOffset
+
+	window, err := compressWindow(r.windowContents())
+	if err != nil {
+		log.Warn("Failed to compress gzip checkpoint window: ", err)
+		return
+	}
+
+	r.index.lock.Lock()
+	defer r.index.lock.Unlock()
+	if len(r.index.checkpoints) > 0 && r.index.checkpoints[len(r.index.checkpoints)-1].uncompressedOffset >= r.uncompressedOffset {
+		// Already have this one, from an earlier re-read
+		return
+	}
+	r.index.checkpoints = append(r.index.checkpoints, gzipCheckpoint{
+		uncompressedOffset:  r.uncompressedOffset,
+		compressedBitOffset: r.bits.bitOffset(),
+		window:              window,
+	})
+}
+
+// Ref: https://www.rfc-editor.org/rfc/rfc1951#section-3.2.3
+func (r *gzipIndexReader) readBlock() error {
+	r.maybeCheckpoint()
+
+	header, err := r.bits.take(3)
+	if err != nil {
+		return err
+	}
+	final := header&1 != 0
+
+	switch header >> 1 {
+	case 0:
+		err = r.readStoredBlock()
+	case 1:
+		err = r.readHuffmanBlock(fixedLiteralTable, fixedDistanceTable)
+	case 2:
+		var literalTable, distanceTable *huffmanTable
+		literalTable, distanceTable, err = r.readDynamicTables()
+		if err == nil {
+			err = r.readHuffmanBlock(literalTable, distanceTable)
+		}
+	default:
+		err = errors.New("invalid deflate block type")
+	}
+	if err != nil {
+		return err
+	}
+
+	if !final {
+		return nil
+	}
+
+	// Skip the CRC32 and ISIZE trailer, then look for another member
+	r.bits.alignToByte()
+	_, err = r.bits.take(32)
+	if err != nil {
+		return err
+	}
+	_, err = r.bits.take(32)
+	if err != nil {
+		return err
+	}
+	r.state = gzipStateHeader
+	return nil
+}
+
+func (r *gzipIndexReader) readStoredBlock() error {
+	r.bits.alignToByte()
+	length, err := r.bits.take(16)
+	if err != nil {
+		return err
+	}
+	notLength, err := r.bits.take(16)
+	if err != nil {
+		return err
+	}
+	if length != ^notLength&0xffff {
+		return errors.New("corrupt stored deflate block length")
+	}
+
+	for i := uint32(0); i < length; i++ {
+		value, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		r.emit(value)
+	}
+
+	return nil
+}
+
+func (r *gzipIndexReader) readDynamicTables() (*huffmanTable, *huffmanTable, error) {
+	counts, err := r.bits.take(14)
+	if err != nil {
+		return nil, nil, err
+	}
+	literalCount := int(counts&0x1f) + 257
+	distanceCount := int(counts>>5&0x1f) + 1
+	codeLengthCount := int(counts>>10) + 4
+
+	codeLengthLengths := make([]uint8, 19)
+	for i := 0; i < codeLengthCount; i++ {
+		length, err := r.bits.take(3)
+		if err != nil {
+			return nil, nil, err
+		}
+		codeLengthLengths[deflateCodeLengthOrder[i]] = uint8(length)
+	}
+	codeLengthTable, err := newHuffmanTable(codeLengthLengths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lengths := make([]uint8, literalCount+distanceCount)
+	for i := 0; i < len(lengths); {
+		symbol, err := r.bits.decodeSymbol(codeLengthTable)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if symbol < 16 {
+			lengths[i] = uint8(symbol)
+			i++
+			continue
+		}
+
+		var repeatValue uint8
+		var repeatCount uint32
+		switch symbol {
+		case 16:
+			if i == 0 {
+				return nil, nil, errors.New("deflate code length repeat without a previous length")
+			}
+			repeatValue = lengths[i-1]
+			repeatCount, err = r.bits.take(2)
+			repeatCount += 3
+		case 17:
+			repeatCount, err = r.bits.take(3)
+			repeatCount += 3
+		default:
+			repeatCount, err = r.bits.take(7)
+			repeatCount += 11
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if i+int(repeatCount) > len(lengths) {
+			return nil, nil, errors.New("too many deflate code lengths")
+		}
+
+		for ; repeatCount > 0; repeatCount-- {
+			lengths[i] = repeatValue
+			i++
+		}
+	}
+
+	literalTable, err := newHuffmanTable(lengths[:literalCount])
+	if err != nil {
+		return nil, nil, err
+	}
+	distanceTable, err := newHuffmanTable(lengths[literalCount:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return literalTable, distanceTable, nil
+}
+
+func (r *gzipIndexReader) readHuffmanBlock(literalTable *huffmanTable, distanceTable *huffmanTable) error {
+	for {
+		symbol, err := r.bits.decodeSymbol(literalTable)
+		if err != nil {
+			return err
+		}
+
+		if symbol < 256 {
+			r.emit(byte(symbol))
+			continue
+		}
+		if symbol == 256 {
+			// End of block
+			return nil
+		}
+
+		symbol -= 257
+		if symbol >= len(deflateLengthBase) {
+			return errors.New("invalid deflate length symbol")
+		}
+		extra, err := r.bits.take(uint(deflateLengthExtraBits[symbol]))
+		if err != nil {
+			return err
+		}
+		length := int(deflateLengthBase[symbol]) + int(extra)
+
+		distanceSymbol, err := r.bits.decodeSymbol(distanceTable)
+		if err != nil {
+			return err
+		}
+		if distanceSymbol >= len(deflateDistanceBase) {
+			return errors.New("invalid deflate distance symbol")
+		}
+		extra, err = r.bits.take(uint(deflateDistanceExtraBits[distanceSymbol]))
+		if err != nil {
+			return err
+		}
+		distance := int(deflateDistanceBase[distanceSymbol]) + int(extra)
+		if distance > r.windowFill {
+			return errors.New("deflate distance too far back")
+		}
+
+		source := (r.windowPos - distance + deflateWindowSize) % deflateWindowSize
+		for ; length > 0; length-- {
+			r.emit(r.window[source])
+			source = (source + 1) % deflateWindowSize
+		}
+	}
+}
+
+func compressWindow(window []byte) ([]byte, error) {
+	compressed := bytes.Buffer{}
+	writer, err := flate.NewWriter(&compressed, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	_, err = writer.Write(window)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+func decompressWindow(compressed []byte) ([]byte, error) {
+	return io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+}
+
+// Open the gzip file for reading uncompressed data starting at offset,
+// starting from the closest checkpoint before that offset.
+//
+// Reading past the last checkpoint records more checkpoints. Opening at offset
+// 0 before anything has been read gives you a reader that indexes the whole
+// file as you read it.
+func (index *gzipIndex) openAt(offset int64) (io.ReadCloser, error) {
+	index.lock.Lock()
+	checkpointIndex := sort.Search(len(index.checkpoints), func(i int) bool {
+		return index.checkpoints[i].uncompressedOffset > offset
+	}) - 1
+	var checkpoint *gzipCheckpoint
+	if checkpointIndex >= 0 {
+		checkpoint = &index.checkpoints[checkpointIndex]
+	}
+	isLastCheckpoint := checkpointIndex == len(index.checkpoints)-1
+	index.lock.Unlock()
+
+	file, err := os.Open(index.fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &gzipIndexReader{closer: file}
+	if checkpoint == nil {
+		reader.bits.input = bufio.NewReader(file)
+	} else {
+		err = reader.resumeFrom(file, *checkpoint)
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("%s: failed to resume from checkpoint: %w", index.fileName, err)
+		}
+	}
+
+	if isLastCheckpoint {
+		// Nothing is known beyond this point, collect checkpoints as we go
+		reader.index = index
+		reader.lastCheckpoint = reader.uncompressedOffset
+	}
+
+	_, err = io.CopyN(io.Discard, reader, offset-reader.uncompressedOffset)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("%s: failed to skip to offset %d: %w", index.fileName, offset, err)
+	}
+
+	return reader, nil
+}
+
+func (r *gzipIndexReader) resumeFrom(file *os.File, checkpoint gzipCheckpoint) error {
+	window, err := decompressWindow(checkpoint.window)
+	if err != nil {
+		return err
+	}
+
+	byteOffset := checkpoint.compressedBitOffset / 8
+	_, err = file.Seek(byteOffset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	r.bits = bitReader{input: bufio.NewReader(file), inputOffset: byteOffset}
+	_, err = r.bits.take(uint(checkpoint.compressedBitOffset % 8))
+	if err != nil {
+		return err
+	}
+
+	r.windowPos = copy(r.window[:], window) % deflateWindowSize
+	r.windowFill = len(window)
+	r.uncompressedOffset = checkpoint.uncompressedOffset
+	r.sawHeader = true
+	r.state = gzipStateBlock
+	return nil
+}
@@ -0,0 +1,154 @@
+package reader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+)
+
+// Log-like lines, compressible but not too compressible
+func makeGzipTestContents(byteCount int) []byte {
+	random := rand.New(rand.NewSource(1))
+	words := []string{"INFO", "WARN", "request", "handled", "in", "ms", "user", "cache", "miss", "hit"}
+
+	contents := bytes.Buffer{}
+	for lineNumber := 0; contents.Len() < byteCount; lineNumber++ {
+		fmt.Fprintf(&contents, "%d", lineNumber)
+		for i := random.Intn(12); i >= 0; i-- {
+			fmt.Fprintf(&contents, " %s %x", words[random.Intn(len(words))], random.Uint32())
+		}
+		contents.WriteByte('\n')
+	}
+
+	return contents.Bytes()
+}
+
+// Write contents as one gzip member per part
+func writeGzipMembers(t *testing.T, parts ...[]byte) string {
+	fileName := path.Join(t.TempDir(), "test.txt.gz")
+	file, err := os.Create(fileName)
+	assert.NilError(t, err)
+
+	for i, part := range parts {
+		// Vary the compression level to get stored, fixed and dynamic blocks
+		writer, err := gzip.NewWriterLevel(file, []int{gzip.DefaultCompression, gzip.NoCompression, gzip.HuffmanOnly, gzip.BestSpeed}[i%4])
+		assert.NilError(t, err)
+		writer.Name = "test.txt"
+		_, err = writer.Write(part)
+		assert.NilError(t, err)
+		assert.NilError(t, writer.Close())
+	}
+	assert.NilError(t, file.Close())
+
+	return fileName
+}
+
+func TestGzipIndexReaderSameAsStdlib(t *testing.T) {
+	contents := makeGzipTestContents(3 * gzipCheckpointSpan)
+	fileName := writeGzipMembers(t, contents[:1000], contents[1000:2_000_000], contents[2_000_000:2_100_000], contents[2_100_000:])
+
+	index := newGzipIndex(fileName)
+	reader, err := index.openAt(0)
+	assert.NilError(t, err)
+	decompressed, err := io.ReadAll(reader)
+	assert.NilError(t, err)
+	assert.NilError(t, reader.Close())
+
+	assert.Equal(t, len(contents), len(decompressed))
+	assert.Assert(t, bytes.Equal(contents, decompressed))
+
+	// One checkpoint at the start, then one per span
+	assert.Assert(t, len(index.checkpoints) >= 3, len(index.checkpoints))
+
+	for _, offset := range []int64{0, 1, 999, 1000, gzipCheckpointSpan + 17, int64(len(contents)) - 5, int64(len(contents))} {
+		stream, err := index.openAt(offset)
+		assert.NilError(t, err)
+
+		buffer := make([]byte, 5000)
+		count, err := io.ReadFull(stream, buffer)
+		if err != io.ErrUnexpectedEOF && err != io.EOF {
+			assert.NilError(t, err)
+		}
+		assert.NilError(t, stream.Close())
+
+		expected := contents[offset:min(int(offset)+5000, len(contents))]
+		assert.Assert(t, bytes.Equal(expected, buffer[:count]), "offset %d", offset)
+	}
+}
+
+// Checkpoints should be collected only as far as lines are re-read
+func TestGzipIndexLazy(t *testing.T) {
+	contents := makeGzipTestContents(5 * gzipCheckpointSpan)
+	index := newGzipIndex(writeGzipMembers(t, contents))
+
+	offset := int64(2*gzipCheckpointSpan + 17)
+	stream, err := index.openAt(offset)
+	assert.NilError(t, err)
+	buffer := make([]byte, 100)
+	_, err = io.ReadFull(stream, buffer)
+	assert.NilError(t, err)
+	assert.NilError(t, stream.Close())
+	assert.Assert(t, bytes.Equal(contents[offset:offset+100], buffer))
+
+	checkpointCount := len(index.checkpoints)
+	assert.Assert(t, checkpointCount >= 2, checkpointCount)
+	assert.Assert(t, index.checkpoints[checkpointCount-1].uncompressedOffset <= offset+100)
+
+	// Re-reading an indexed part shouldn't add any checkpoints
+	stream, err = index.openAt(gzipCheckpointSpan + 5)
+	assert.NilError(t, err)
+	_, err = io.ReadFull(stream, buffer)
+	assert.NilError(t, err)
+	assert.NilError(t, stream.Close())
+	assert.Equal(t, checkpointCount, len(index.checkpoints))
+	assert.Assert(t, bytes.Equal(contents[gzipCheckpointSpan+5:gzipCheckpointSpan+105], buffer))
+}
+
+func TestGzipIndexReaderEmpty(t *testing.T) {
+	reader, err := newGzipIndex(writeGzipMembers(t, []byte{})).openAt(0)
+	assert.NilError(t, err)
+	decompressed, err := io.ReadAll(reader)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(decompressed))
+}
+
+func TestGzipIndexReaderNotGzip(t *testing.T) {
+	reader, err := newGzipIndex(path.Join(samplesDir, "short.txt")).openAt(0)
+	assert.NilError(t, err)
+	_, err = io.ReadAll(reader)
+	assert.ErrorContains(t, err, "not a gzip file")
+}
+
+func TestFileBackedGzip(t *testing.T) {
+	contents := makeGzipTestContents(3 * gzipCheckpointSpan)
+	fileName := writeGzipMembers(t, contents)
+
+	reader := readFileForTesting(t, fileName, true)
+	assert.Assert(t, reader.fileBacked != nil)
+	assert.Equal(t, 0, len(reader.lines))
+
+	lines := bytes.Split(bytes.TrimSuffix(contents, []byte("\n")), []byte("\n"))
+	assert.Equal(t, len(lines), reader.GetLineCount())
+
+	// The initial read should have indexed the whole file, so that re-reading
+	// lines never has to start from the beginning
+	index := reader.fileBacked.source.(*gzipIndex)
+	assert.Assert(t, len(index.checkpoints) >= 3, len(index.checkpoints))
+	lastCheckpoint := index.checkpoints[len(index.checkpoints)-1]
+	assert.Assert(t, lastCheckpoint.uncompressedOffset >= int64(len(contents)-2*gzipCheckpointSpan), lastCheckpoint.uncompressedOffset)
+
+	// Back to front, to make the reader seek
+	for i := len(lines) - 1; i >= 0; i -= 499 {
+		line := reader.GetLine(linemetadata.IndexFromZeroBased(i))
+		assert.Equal(t, string(lines[i]), line.Plain())
+	}
+}
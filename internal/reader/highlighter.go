@@ -0,0 +1,456 @@
+package reader
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	log "github.com/sirupsen/logrus"
+)
+
+// Lines are highlighted this many at a time
+const highlightChunkLineCount = 1000
+
+// Chroma doesn't let us look at the lexer state, so we can't just stop lexing
+// at the end of one chunk and continue with the next.
+//
+// Instead we make a copy of the lexer where every rule gets a mutator. This
+// lets us restore a saved state stack when lexing starts, and lets us find a
+// place to stop where no token spans the chunk boundary, together with the
+// state stack at that place.
+type checkpointingLexer struct {
+	lexer chroma.Lexer
+
+	// Per-tokenise() state, only one tokenise() at a time!
+	outer       *chroma.LexerState
+	resumeStack []string
+
+	// Look for a boundary at or after this rune position
+	minBoundaryPos int
+
+	// Set when we have found a boundary
+	boundaryFound bool
+	boundaryPos   int
+	boundaryLine  int
+	boundaryStack []string
+}
+
+// Observes the lexer state after each match
+type stateRecorder struct {
+	lexer *checkpointingLexer
+	inner chroma.Mutator
+}
+
+// Like stateRecorder, but for mutators that change the rules at compile time,
+// like chroma.Include() and chroma.Combined()
+type compileTimeStateRecorder struct {
+	stateRecorder
+}
+
+func (r *stateRecorder) Mutate(state *chroma.LexerState) error {
+	lexer := r.lexer
+	if lexer.outer == nil {
+		// First match of a new tokenise(). Nested lexing using the same lexer
+		// can only happen after this, so this is our state.
+		lexer.outer = state
+		if lexer.resumeStack != nil {
+			state.Stack = slices.Clone(lexer.resumeStack)
+		}
+	}
+
+	if r.inner != nil {
+		err := r.inner.Mutate(state)
+		if err != nil {
+			return err
+		}
+	}
+
+	if state != lexer.outer || lexer.boundaryFound {
+		return nil
+	}
+	if state.Pos < lexer.minBoundaryPos || state.Text[state.Pos-1] != '\n' {
+		return nil
+	}
+
+	lexer.boundaryFound = true
+	lexer.boundaryPos = state.Pos
+	lexer.boundaryStack = slices.Clone(state.Stack)
+	for _, char := range state.Text[:state.Pos] {
+		if char == '\n' {
+			lexer.boundaryLine++
+		}
+	}
+
+	return nil
+}
+
+func (r *compileTimeStateRecorder) MutateLexer(rules chroma.CompiledRules, state string, index int) error {
+	err := r.inner.(chroma.LexerMutator).MutateLexer(rules, state, index)
+	if err != nil {
+		return err
+	}
+
+	// Include() replaces itself with the included rules, which are already
+	// recording. Combined() replaces itself with a Push(), which isn't.
+	if index < len(rules[state]) {
+		rule := rules[state][index]
+		switch rule.Mutator.(type) {
+		case *stateRecorder, *compileTimeStateRecorder:
+		default:
+			rule.Mutator = &stateRecorder{lexer: r.lexer, inner: rule.Mutator}
+		}
+	}
+
+	return nil
+}
+
+// Returns nil if this lexer can't do checkpoints
+func newCheckpointingLexer(lexer chroma.Lexer) *checkpointingLexer {
+	regexLexer, ok := lexer.(*chroma.RegexLexer)
+	if !ok {
+		return nil
+	}
+
+	rules, err := regexLexer.Rules()
+	if err != nil {
+		log.Debug("Failed to get lexer rules, highlighting chunks without checkpoints: ", err)
+		return nil
+	}
+
+	returnMe := &checkpointingLexer{}
+	recordingRules := chroma.Rules{}
+	for state, stateRules := range rules {
+		recordingStateRules := make([]chroma.Rule, 0, len(stateRules))
+		for _, rule := range stateRules {
+			recorder := stateRecorder{lexer: returnMe, inner: rule.Mutator}
+			if _, isCompileTime := rule.Mutator.(chroma.LexerMutator); isCompileTime {
+				rule.Mutator = &compileTimeStateRecorder{recorder}
+			} else {
+				rule.Mutator = &recorder
+			}
+			recordingStateRules = append(recordingStateRules, rule)
+		}
+		recordingRules[state] = recordingStateRules
+	}
+
+	recordingLexer, err := chroma.NewLexer(regexLexer.Config(), func() chroma.Rules { return recordingRules })
+	if err != nil {
+		log.Debug("Failed to create checkpointing lexer, highlighting chunks without checkpoints: ", err)
+		return nil
+	}
+	recordingLexer.SetRegistry(lexers.GlobalLexerRegistry)
+	returnMe.lexer = recordingLexer
+
+	return returnMe
+}
+
+// Tokenise text starting with the given lexer state stack. A nil stack means
+// starting from the root state.
+//
+// The returned iterator stops at the first line start at or after
+// minBoundaryPos (in runes) where no token continues past the line start.
+// After the iterator has been drained, check boundaryFound, boundaryLine and
+// boundaryStack for where it stopped.
+func (l *checkpointingLexer) tokenise(text string, stack []string, minBoundaryPos int) (chroma.Iterator, error) {
+	l.outer = nil
+	l.resumeStack = nil
+	if len(stack) > 0 {
+		l.resumeStack = stack
+	}
+	l.minBoundaryPos = max(minBoundaryPos, 1)
+	l.boundaryFound = false
+	l.boundaryPos = 0
+	l.boundaryLine = 0
+	l.boundaryStack = nil
+
+	options := &chroma.TokeniseOptions{State: "root"}
+	if l.resumeStack != nil {
+		options.State = l.resumeStack[len(l.resumeStack)-1]
+	}
+
+	iterator, err := l.lexer.Tokenise(options, text)
+	if err != nil {
+		return nil, err
+	}
+
+	emitted := 0
+	return func() chroma.Token {
+		if l.boundaryFound && emitted >= l.boundaryPos {
+			return chroma.EOF
+		}
+
+		token := iterator()
+		emitted += utf8.RuneCountInString(token.Value)
+		return token
+	}, nil
+}
+
+// Where to start highlighting a chunk of lines
+type highlightCheckpoint struct {
+	lineIndex int
+
+	// Lexer state stack at the start of lineIndex. Nil means the root state.
+	stack []string
+}
+
+// Highlights the lines of a reader in chunks, on demand. Chunks are
+// highlighted in order, saving the lexer state at the start of each chunk.
+type highlighter struct {
+	reader *ReaderImpl
+
+	style     chroma.Style
+	formatter chroma.Formatter
+	lexer     chroma.Lexer
+
+	// Nil if the lexer doesn't support checkpoints. In that case each chunk is
+	// lexed starting from the root state.
+	checkpointing *checkpointingLexer
+
+	// Only one highlighting pass at a time
+	workLock sync.Mutex
+
+	// Protects the fields below
+	lock sync.Mutex
+
+	// Where each chunk starts. Chunks before the last one are done, the last
+	// one is where to continue highlighting.
+	checkpoints []highlightCheckpoint
+
+	// Highest line index anybody has asked for
+	wantedLine int
+
+	// Tells the background goroutine there's work to do. Closed by stop().
+	poke    chan struct{}
+	stopped bool
+}
+
+// Returns nil if the lexer won't do any highlighting
+func newHighlighter(reader *ReaderImpl, style chroma.Style, formatter chroma.Formatter, lexer chroma.Lexer) *highlighter {
+	if lexer == nil || formatter == nil {
+		return nil
+	}
+
+	// FIXME: Can we test for the lexer implementation class instead? That
+	// should be more resilient towards this arbitrary string changing if we
+	// upgrade Chroma at some point.
+	if lexer.Config().Name == "plaintext" {
+		return nil
+	}
+
+	return &highlighter{
+		reader:        reader,
+		style:         style,
+		formatter:     formatter,
+		lexer:         lexer,
+		checkpointing: newCheckpointingLexer(lexer),
+		checkpoints:   []highlightCheckpoint{{lineIndex: 0}},
+		wantedLine:    -1,
+		poke:          make(chan struct{}, 1),
+	}
+}
+
+// Highlight requested lines in the background until stop() is called
+func (h *highlighter) run() {
+	for range h.poke {
+		h.lock.Lock()
+		wantedLine := h.wantedLine
+		h.lock.Unlock()
+
+		if h.highlightUpTo(wantedLine) {
+			select {
+			case h.reader.MoreLinesAdded <- true:
+			default:
+			}
+		}
+	}
+}
+
+func (h *highlighter) stop() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.stopped {
+		return
+	}
+	h.stopped = true
+	close(h.poke)
+}
+
+// Ask for the line with the given index to be highlighted in the background.
+func (h *highlighter) want(lineIndex int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.stopped {
+		return
+	}
+
+	if lineIndex > h.wantedLine {
+		h.wantedLine = lineIndex
+	}
+
+	select {
+	case h.poke <- struct{}{}:
+	default:
+		// Already poked
+	}
+}
+
+// Highlight all lines up to and including the given one, or as far as we have
+// lines. Returns true if any lines got highlighted.
+func (h *highlighter) highlightUpTo(lastLineIndex int) bool {
+	h.workLock.Lock()
+	defer h.workLock.Unlock()
+
+	highlightedSomething := false
+	for {
+		h.lock.Lock()
+		checkpoint := h.checkpoints[len(h.checkpoints)-1]
+		h.lock.Unlock()
+
+		if checkpoint.lineIndex > lastLineIndex {
+			return highlightedSomething
+		}
+
+		// Get some extra lines in case the chunk has to be extended to not end
+		// in the middle of a token
+		lines, moreAfter := h.reader.linesForHighlighting(checkpoint.lineIndex, 2*highlightChunkLineCount)
+		if len(lines) == 0 {
+			return highlightedSomething
+		}
+
+		if !moreAfter && allHighlighted(lines) {
+			// Waiting for more lines
+			return highlightedSomething
+		}
+
+		next, complete := h.highlightChunk(lines, checkpoint.stack)
+		highlightedSomething = true
+
+		if !complete && moreAfter {
+			// The chunk went on until the end of our lines, continue after
+			// them
+			complete = true
+		}
+
+		if !complete {
+			// Wait for more lines
+			return highlightedSomething
+		}
+
+		next.lineIndex += checkpoint.lineIndex
+		h.lock.Lock()
+		h.checkpoints = append(h.checkpoints, next)
+		h.lock.Unlock()
+	}
+}
+
+func allHighlighted(lines []*Line) bool {
+	for _, line := range lines {
+		if !line.isHighlighted() {
+			return false
+		}
+	}
+	return true
+}
+
+// Highlight a chunk of lines starting from the given lexer state stack. The
+// chunk will be at least highlightChunkLineCount lines long, unless there
+// aren't that many lines.
+//
+// Returns where the next chunk starts, relative to the first line, and whether
+// a complete chunk was highlighted. For incomplete chunks, all lines have been
+// highlighted.
+func (h *highlighter) highlightChunk(lines []*Line, stack []string) (highlightCheckpoint, bool) {
+	haveMoreLines := len(lines) > highlightChunkLineCount
+
+	text := strings.Builder{}
+	minBoundaryPos := 0
+	for i, line := range lines {
+		if i == highlightChunkLineCount {
+			minBoundaryPos = utf8.RuneCountInString(text.String())
+		}
+		text.WriteString(line.raw)
+		text.WriteString("\n")
+	}
+	if minBoundaryPos == 0 {
+		// Not enough lines for a complete chunk, highlight all of them
+		minBoundaryPos = utf8.RuneCountInString(text.String())
+	}
+
+	var iterator chroma.Iterator
+	var err error
+	if h.checkpointing != nil {
+		iterator, err = h.checkpointing.tokenise(text.String(), stack, minBoundaryPos)
+	} else {
+		// Without checkpoints, each chunk is exactly highlightChunkLineCount
+		// lines long
+		lines = lines[:min(len(lines), highlightChunkLineCount)]
+		iterator, err = h.lexer.Tokenise(nil, linesAsText(lines))
+	}
+	if err != nil {
+		log.Warn("Tokenizing for highlighting failed: ", err)
+		return h.giveUpOnChunk(lines, haveMoreLines)
+	}
+
+	var highlighted bytes.Buffer
+	err = h.formatter.Format(&highlighted, &h.style, iterator)
+	if err != nil {
+		log.Warn("Highlighting failed: ", err)
+		return h.giveUpOnChunk(lines, haveMoreLines)
+	}
+
+	next := highlightCheckpoint{lineIndex: len(lines)}
+	complete := haveMoreLines
+	if h.checkpointing != nil {
+		complete = h.checkpointing.boundaryFound && h.checkpointing.boundaryLine < len(lines)
+		if h.checkpointing.boundaryFound {
+			next = highlightCheckpoint{
+				lineIndex: h.checkpointing.boundaryLine,
+				stack:     h.checkpointing.boundaryStack,
+			}
+		}
+	}
+
+	highlightedLines := strings.Split(highlighted.String(), "\n")
+	if len(highlightedLines) < next.lineIndex {
+		log.Warnf("Highlighting %d lines returned only %d lines, not highlighting", next.lineIndex, len(highlightedLines))
+		return h.giveUpOnChunk(lines, haveMoreLines)
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.stopped {
+		// Our highlighting isn't wanted any more
+		return next, false
+	}
+	for i, line := range lines[:next.lineIndex] {
+		line.setHighlighted(highlightedLines[i])
+	}
+
+	return next, complete
+}
+
+// Mark the lines as highlighted without any highlighting, and move on with the
+// next chunk from the root state
+func (h *highlighter) giveUpOnChunk(lines []*Line, haveMoreLines bool) (highlightCheckpoint, bool) {
+	lines = lines[:min(len(lines), highlightChunkLineCount)]
+	for _, line := range lines {
+		line.setHighlighted(line.raw)
+	}
+
+	return highlightCheckpoint{lineIndex: len(lines)}, haveMoreLines
+}
+
+func linesAsText(lines []*Line) string {
+	text := strings.Builder{}
+	for _, line := range lines {
+		text.WriteString(line.raw)
+		text.WriteString("\n")
+	}
+	return text.String()
+}
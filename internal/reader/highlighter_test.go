@@ -0,0 +1,171 @@
+package reader
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"gotest.tools/v3/assert"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/textstyles"
+	"github.com/walles/moor/v2/twin"
+)
+
+// Go source spanning several highlighting chunks, with a raw string and a
+// block comment crossing chunk boundaries
+func makeChunkedGoSource() string {
+	source := strings.Builder{}
+	source.WriteString("package main\n\n")
+
+	lineCount := 2
+	for lineCount < 3*highlightChunkLineCount+100 {
+		if lineCount > highlightChunkLineCount-5 && lineCount < highlightChunkLineCount+5 {
+			source.WriteString("var raw = `\n")
+			for i := 0; i < 10; i++ {
+				source.WriteString("func notCode() { return 42 }\n")
+			}
+			source.WriteString("`\n")
+			lineCount += 12
+			continue
+		}
+
+		if lineCount > 2*highlightChunkLineCount-3 && lineCount < 2*highlightChunkLineCount+3 {
+			source.WriteString("/*\n")
+			for i := 0; i < 10; i++ {
+				source.WriteString("var commented = \"out\"\n")
+			}
+			source.WriteString("*/\n")
+			lineCount += 12
+			continue
+		}
+
+		source.WriteString(fmt.Sprintf("var x%d = \"value %d\" // Comment\n", lineCount, lineCount))
+		lineCount++
+	}
+
+	return source.String()
+}
+
+func assertSameHighlighting(t *testing.T, expected string, actual *Line) {
+	t.Helper()
+
+	expectedRunes := textstyles.StyledRunesFromString(twin.StyleDefault, expected, nil).StyledRunes
+	actualRunes := textstyles.StyledRunesFromString(twin.StyleDefault, actual.display(), nil).StyledRunes
+	assert.Equal(t, len(expectedRunes), len(actualRunes))
+	for i, expectedRune := range expectedRunes {
+		assert.Equal(t, expectedRune, actualRunes[i])
+	}
+}
+
+func TestHighlightInChunks(t *testing.T) {
+	source := makeChunkedGoSource()
+	style := styles.Get("native")
+	lexer := lexers.Get("go")
+
+	highlighted, err := Highlight(source, *style, formatters.TTY16m, lexer)
+	assert.NilError(t, err)
+	expectedLines := strings.Split(*highlighted, "\n")
+
+	testMe := NewFromTextForTesting("test.go", source)
+	highlighter := newHighlighter(testMe, *style, formatters.TTY16m, lexer)
+	assert.Assert(t, highlighter.checkpointing != nil)
+	assert.Assert(t, highlighter.highlightUpTo(math.MaxInt))
+
+	assert.Equal(t, len(testMe.lines), 3*highlightChunkLineCount+100)
+	for i, line := range testMe.lines {
+		assertSameHighlighting(t, expectedLines[i], line)
+	}
+
+	// The first chunk should have been extended to not end inside of the raw
+	// string
+	assert.Equal(t, len(highlighter.checkpoints), 4)
+	assert.Equal(t, highlighter.checkpoints[1].lineIndex, highlightChunkLineCount+8)
+}
+
+// Python triple quoted strings are lexed using a lexer state of their own,
+// which has to be restored when continuing with the next chunk
+func TestHighlightInChunksWithLexerState(t *testing.T) {
+	source := strings.Builder{}
+	for i := 0; i < 2*highlightChunkLineCount; i++ {
+		if i == highlightChunkLineCount-5 {
+			source.WriteString("docstring = \"\"\"\n")
+			for j := 0; j < 10; j++ {
+				source.WriteString("def not_code(): return 42\n")
+			}
+			source.WriteString("\"\"\"\n")
+		}
+		source.WriteString(fmt.Sprintf("x%d = 'value' # Comment\n", i))
+	}
+
+	style := styles.Get("native")
+	lexer := lexers.Get("python")
+
+	highlighted, err := Highlight(source.String(), *style, formatters.TTY16m, lexer)
+	assert.NilError(t, err)
+	expectedLines := strings.Split(*highlighted, "\n")
+
+	testMe := NewFromTextForTesting("test.py", source.String())
+	highlighter := newHighlighter(testMe, *style, formatters.TTY16m, lexer)
+	assert.Assert(t, highlighter.highlightUpTo(math.MaxInt))
+
+	for i, line := range testMe.lines {
+		assertSameHighlighting(t, expectedLines[i], line)
+	}
+
+	// The second chunk should start inside of the docstring
+	assert.Assert(t, len(highlighter.checkpoints[1].stack) > 1)
+}
+
+// Without checkpoints, the raw string would be highlighted as code
+func TestHighlightChunkInsideRawString(t *testing.T) {
+	source := makeChunkedGoSource()
+	lexer := lexers.Get("go")
+	testMe := NewFromTextForTesting("test.go", source)
+	highlighter := newHighlighter(testMe, *styles.Get("native"), formatters.TTY16m, lexer)
+
+	highlighter.highlightUpTo(highlightChunkLineCount)
+
+	notCode := testMe.lines[highlightChunkLineCount]
+	assert.Equal(t, notCode.raw, "func notCode() { return 42 }")
+
+	// All in one color
+	runes := textstyles.StyledRunesFromString(twin.StyleDefault, notCode.display(), nil).StyledRunes
+	for _, r := range runes {
+		assert.Equal(t, r.Style, runes[0].Style)
+	}
+}
+
+// Highlighting of files too large for highlighting up front should happen
+// when the lines are requested
+func TestHighlightLargeFileOnDemand(t *testing.T) {
+	line := "var x = \"Some text to make this line longer\" // Comment\n"
+	source := strings.Repeat(line, 2*int(MAX_HIGHLIGHT_SIZE)/len(line))
+
+	fileName := path.Join(t.TempDir(), "large.go")
+	err := os.WriteFile(fileName, []byte(source), 0o600)
+	assert.NilError(t, err)
+
+	testMe, err := NewFromFilename(fileName, formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.NilError(t, testMe.Wait())
+
+	lastLine := testMe.GetLineCount() - 1
+	assert.Assert(t, !testMe.lines[lastLine].isHighlighted())
+
+	lines := testMe.GetLines(linemetadata.IndexFromZeroBased(lastLine), 1)
+	assert.Equal(t, len(lines.Lines), 1)
+	for !lines.Lines[0].Line.isHighlighted() {
+		<-testMe.MoreLinesAdded
+	}
+	assert.Assert(t, strings.Contains(lines.Lines[0].Line.display(), "\x1b["))
+
+	// Chunks are highlighted in order, so the first one should be done too
+	assert.Assert(t, testMe.lines[0].isHighlighted())
+}
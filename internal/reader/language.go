@@ -0,0 +1,150 @@
+package reader
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	log "github.com/sirupsen/logrus"
+)
+
+// If we have no lexer, guess one from the contents once we have enough lines,
+// or as soon as the first line tells us. Assumes that the caller is holding the
+// lock.
+func (reader *ReaderImpl) maybeGuessLexerUnlocked() {
+	if reader.lexerGuessed || reader.lexer != nil || reader.logTemplate != nil || reader.fileBacked != nil {
+		return
+	}
+
+	if len(reader.lines) == 0 {
+		return
+	}
+
+	if len(reader.lines) < guessLanguageLineCount && sniffLexer(reader.lines[0].raw) == nil {
+		// Wait for more lines
+		return
+	}
+
+	reader.guessLexerUnlocked()
+}
+
+// Guess a lexer from the first lines of the input, and start highlighting using
+// it. Assumes that the caller is holding the lock.
+func (reader *ReaderImpl) guessLexerUnlocked() {
+	reader.lexerGuessed = true
+
+	text := strings.Builder{}
+	for _, line := range reader.lines[:min(len(reader.lines), guessLanguageLineCount)] {
+		text.WriteString(line.raw)
+		text.WriteString("\n")
+	}
+
+	candidates := guessLexers(text.String())
+	if len(candidates) == 0 {
+		return
+	}
+
+	log.Info("Guessed input language from its contents: ", candidates[0].Config().Name)
+	reader.lexerCandidates = candidates
+	reader.setLexerUnlocked(candidates[0])
+}
+
+// Switch to a new lexer, re-highlighting everything. Assumes that the caller is
+// holding the lock.
+func (reader *ReaderImpl) setLexerUnlocked(lexer chroma.Lexer) {
+	reader.showLanguage = true
+	if reader.lexer == lexer {
+		return
+	}
+
+	if reader.highlighter != nil {
+		// Stop it before removing its highlighting, so that it doesn't
+		// highlight anything more after that
+		reader.highlighter.stop()
+		reader.highlighter = nil
+	}
+	for _, line := range reader.lines {
+		line.clearHighlighted()
+	}
+
+	reader.lexer = lexer
+	reader.startHighlighterUnlocked()
+}
+
+// The name of the language we're highlighting as, or an empty string if it was
+// given by the file name or on the command line.
+func (reader *ReaderImpl) Language() string {
+	reader.Lock()
+	defer reader.Unlock()
+
+	if !reader.showLanguage || reader.lexer == nil {
+		return ""
+	}
+
+	return reader.lexer.Config().Name
+}
+
+// Switch to highlighting using the next likely language. After the last one,
+// turn off highlighting, then start over.
+func (reader *ReaderImpl) CycleLanguage() {
+	reader.Lock()
+
+	candidates := reader.lexerCandidates
+	if reader.lexer != nil && !containsLexer(candidates, reader.lexer) {
+		candidates = append([]chroma.Lexer{reader.lexer}, candidates...)
+	}
+
+	plaintext := lexers.Get("plaintext")
+	if !containsLexer(candidates, plaintext) {
+		candidates = append(candidates, plaintext)
+	}
+	reader.lexerCandidates = candidates
+
+	next := candidates[0]
+	for i, candidate := range candidates {
+		if candidate == reader.lexer {
+			next = candidates[(i+1)%len(candidates)]
+			break
+		}
+	}
+
+	log.Info("Switching highlighting language to ", next.Config().Name)
+	reader.setLexerUnlocked(next)
+	reader.Unlock()
+
+	select {
+	case reader.MoreLinesAdded <- true:
+	default:
+	}
+}
+
+// Switch to highlighting using the named language. The name can be anything
+// accepted by the --lang command line option.
+func (reader *ReaderImpl) SetLanguage(name string) error {
+	lexer := lexers.Get(name)
+	if lexer == nil {
+		return fmt.Errorf("unknown language: %s", name)
+	}
+
+	reader.Lock()
+	log.Info("Switching highlighting language to ", lexer.Config().Name)
+	reader.setLexerUnlocked(lexer)
+	reader.Unlock()
+
+	select {
+	case reader.MoreLinesAdded <- true:
+	default:
+	}
+
+	return nil
+}
+
+func containsLexer(lexers []chroma.Lexer, lexer chroma.Lexer) bool {
+	for _, candidate := range lexers {
+		if candidate == lexer {
+			return true
+		}
+	}
+	return false
+}
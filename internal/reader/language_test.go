@@ -0,0 +1,55 @@
+package reader
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/styles"
+	"gotest.tools/v3/assert"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+)
+
+func TestCycleLanguage(t *testing.T) {
+	testMe, err := NewFromStream("", strings.NewReader("#!/bin/bash\necho hello\n"), formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.NilError(t, testMe.Wait())
+
+	assert.Equal(t, testMe.Language(), "Bash")
+	assert.Assert(t, testMe.lines[1].isHighlighted())
+
+	testMe.CycleLanguage()
+	assert.Equal(t, testMe.Language(), "plaintext")
+	assert.Assert(t, !testMe.lines[1].isHighlighted())
+
+	testMe.CycleLanguage()
+	assert.Equal(t, testMe.Language(), "Bash")
+}
+
+func TestSetLanguage(t *testing.T) {
+	testMe, err := NewFromStream("", strings.NewReader("key: value\n"), formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.NilError(t, testMe.Wait())
+	assert.Equal(t, testMe.Language(), "")
+
+	assert.NilError(t, testMe.SetLanguage("yaml"))
+	assert.Equal(t, testMe.Language(), "YAML")
+
+	lines := testMe.GetLines(linemetadata.Index{}, 1)
+	for !lines.Lines[0].Line.isHighlighted() {
+		<-testMe.MoreLinesAdded
+	}
+
+	assert.ErrorContains(t, testMe.SetLanguage("no-such-language"), "no-such-language")
+	assert.Equal(t, testMe.Language(), "YAML")
+}
+
+// Languages given by file names shouldn't be shown
+func TestLanguageFromFileName(t *testing.T) {
+	testMe, err := NewFromFilename(samplesDir+"/with-tabs.c", formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.NilError(t, testMe.Wait())
+
+	assert.Equal(t, testMe.Language(), "")
+}
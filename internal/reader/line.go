@@ -14,6 +14,13 @@ type Line struct {
 	raw   string
 	plain *string
 	lock  sync.Mutex
+
+	// If this line was rendered from a JSON log line, this is the original JSON
+	logObject string
+
+	// Syntax highlighted version of raw, set by the highlighter. Nil until
+	// this line has been highlighted.
+	highlighted *string
 }
 
 // NewLine creates a new Line from a (potentially ANSI / man page formatted) string
@@ -25,13 +32,28 @@ func NewLine(raw string) Line {
 	}
 }
 
+// A pattern that stays highlighted in its own style, whatever we're searching
+// for
+type PinnedPattern struct {
+	Pattern *regexp.Regexp
+	Style   twin.Style
+}
+
 // Returns a representation of the string split into styled tokens. Any regexp
 // matches are highlighted. A nil regexp means no highlighting.
-func (line *Line) HighlightedTokens(plainTextStyle twin.Style, standoutStyle *twin.Style, search *regexp.Regexp, lineIndex *linemetadata.Index) textstyles.StyledRunesWithTrailer {
+//
+// Pinned pattern matches are highlighted using their own styles. Search
+// matches win over pinned matches, and earlier pins win over later ones.
+func (line *Line) HighlightedTokens(plainTextStyle twin.Style, standoutStyle *twin.Style, search *regexp.Regexp, pins []PinnedPattern, lineIndex *linemetadata.Index) textstyles.StyledRunesWithTrailer {
 	plain := line.Plain(lineIndex)
 	matchRanges := getMatchRanges(&plain, search)
 
-	fromString := textstyles.StyledRunesFromString(plainTextStyle, line.raw, lineIndex)
+	pinRanges := make([]*MatchRanges, len(pins))
+	for i, pin := range pins {
+		pinRanges[i] = getMatchRanges(&plain, pin.Pattern)
+	}
+
+	fromString := textstyles.StyledRunesFromString(plainTextStyle, line.display(), lineIndex)
 	returnRunes := make([]twin.StyledRune, 0, len(fromString.StyledRunes))
 	for _, token := range fromString.StyledRunes {
 		style := token.Style
@@ -43,6 +65,13 @@ func (line *Line) HighlightedTokens(plainTextStyle twin.Style, standoutStyle *tw
 				style = style.WithBackground(twin.ColorDefault)
 				style = style.WithForeground(twin.ColorDefault)
 			}
+		} else {
+			for i, ranges := range pinRanges {
+				if ranges.InRange(len(returnRunes)) {
+					style = pins[i].Style
+					break
+				}
+			}
 		}
 
 		returnRunes = append(returnRunes, twin.StyledRune{
@@ -68,3 +97,35 @@ func (line *Line) Plain(lineIndex *linemetadata.Index) string {
 	}
 	return *line.plain
 }
+
+// The highlighted line if available, otherwise the raw line
+func (line *Line) display() string {
+	line.lock.Lock()
+	defer line.lock.Unlock()
+
+	if line.highlighted != nil {
+		return *line.highlighted
+	}
+	return line.raw
+}
+
+func (line *Line) setHighlighted(highlighted string) {
+	line.lock.Lock()
+	defer line.lock.Unlock()
+
+	line.highlighted = &highlighted
+}
+
+func (line *Line) clearHighlighted() {
+	line.lock.Lock()
+	defer line.lock.Unlock()
+
+	line.highlighted = nil
+}
+
+func (line *Line) isHighlighted() bool {
+	line.lock.Lock()
+	defer line.lock.Unlock()
+
+	return line.highlighted != nil
+}
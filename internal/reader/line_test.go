@@ -1,6 +1,7 @@
 package reader
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/walles/moor/v2/internal/textstyles"
@@ -20,7 +21,7 @@ func TestHighlightedTokensWithManPageHeading(t *testing.T) {
 	}
 
 	line := NewLine(manPageHeading)
-	highlighted := line.HighlightedTokens(twin.StyleDefault, nil, nil, nil)
+	highlighted := line.HighlightedTokens(twin.StyleDefault, nil, nil, nil, nil)
 
 	assert.Equal(t, len(highlighted.StyledRunes), len(headingText))
 	for i, cell := range highlighted.StyledRunes {
@@ -28,3 +29,29 @@ func TestHighlightedTokensWithManPageHeading(t *testing.T) {
 		assert.Equal(t, cell.Style, textstyles.ManPageHeading)
 	}
 }
+
+func TestHighlightedTokensWithPins(t *testing.T) {
+	red := twin.StyleDefault.WithBackground(twin.NewColor16(1))
+	green := twin.StyleDefault.WithBackground(twin.NewColor16(2))
+	pins := []PinnedPattern{
+		{Pattern: regexp.MustCompile("apa"), Style: red},
+		{Pattern: regexp.MustCompile("pa b"), Style: green},
+	}
+
+	line := NewLine("apa bepa")
+	highlighted := line.HighlightedTokens(twin.StyleDefault, nil, regexp.MustCompile("bep"), pins, nil).StyledRunes
+	assert.Equal(t, len(highlighted), len("apa bepa"))
+
+	// The first pin wins over the second one
+	assert.Equal(t, highlighted[0].Style, red)
+	assert.Equal(t, highlighted[2].Style, red)
+
+	// Only the second pin matches here
+	assert.Equal(t, highlighted[3].Style, green)
+
+	// The search wins over the pins
+	assert.Equal(t, highlighted[4].Style, twin.StyleDefault.WithAttr(twin.AttrReverse))
+
+	// Nothing matches the end
+	assert.Equal(t, highlighted[7].Style, twin.StyleDefault)
+}
@@ -18,6 +18,6 @@ func (nl *NumberedLine) Plain() string {
 	return nl.Line.Plain(&nl.Index)
 }
 
-func (nl *NumberedLine) HighlightedTokens(plainTextStyle twin.Style, standoutStyle *twin.Style, search *regexp.Regexp) textstyles.StyledRunesWithTrailer {
-	return nl.Line.HighlightedTokens(plainTextStyle, standoutStyle, search, &nl.Index)
+func (nl *NumberedLine) HighlightedTokens(plainTextStyle twin.Style, standoutStyle *twin.Style, search *regexp.Regexp, pins []PinnedPattern) textstyles.StyledRunesWithTrailer {
+	return nl.Line.HighlightedTokens(plainTextStyle, standoutStyle, search, pins, &nl.Index)
 }
@@ -25,15 +25,21 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// Files larger than this won't be highlighted
+// Files up to this size are highlighted in full as soon as they have been
+// read. Larger files are highlighted on demand, in chunks, as they are being
+// viewed.
 //
 //revive:disable-next-line:var-naming
 const MAX_HIGHLIGHT_SIZE int64 = 1024 * 1024
 
 const DEFAULT_PAUSE_AFTER_LINES = 20_000
 
+// When tailing files on platforms where we can't get notified about changes,
+// check the file this often.
+const pollInterval = 1 * time.Second
+
 type ReaderOptions struct {
-	// Format JSON input
+	// Format JSON, NDJSON, XML and YAML input
 	ShouldFormat bool
 
 	// Pause after reading this many lines, unless told otherwise.
@@ -48,6 +54,23 @@ type ReaderOptions struct {
 
 	// If this is set, it will be used as the lexer for highlighting
 	Lexer chroma.Lexer
+
+	// Keep only a line offset index in memory, and re-read lines from disk
+	// when they are needed. Only works for uncompressed files. Always enabled
+	// for files larger than FILE_BACKED_MIN_SIZE.
+	FileBacked bool
+
+	// When tailing, keep following the file name even if the file is
+	// truncated or replaced, like "tail -F". Useful with log rotation.
+	FollowName bool
+
+	// If the input starts with a JSON log line, render JSON log lines using
+	// this template. Empty means DEFAULT_LOG_TEMPLATE.
+	LogTemplate string
+
+	// Show JSON log lines as-is, even if the input starts with one. Setting
+	// ShouldFormat implies this.
+	NoLogMode bool
 }
 
 type Reader interface {
@@ -78,6 +101,10 @@ type ReaderImpl struct {
 
 	lines []*Line
 
+	// If this is set, lines are read from disk on demand and the lines slice
+	// is unused.
+	fileBacked *fileBackedLines
+
 	// Display name for the buffer. If not set, no buffer name will be shown.
 	//
 	// For files, this will be the file name. For our help text, this will be
@@ -100,6 +127,24 @@ type ReaderImpl struct {
 
 	highlightingStyle chan chroma.Style
 
+	// For highlighting on demand. The style is nil until
+	// SetStyleForHighlighting() has been called.
+	formatter   chroma.Formatter
+	lexer       chroma.Lexer
+	style       *chroma.Style
+	highlighter *highlighter
+
+	// Set when we have tried guessing the lexer from the input contents
+	lexerGuessed bool
+
+	// Likely lexers for the input, best guess first. Used for cycling
+	// between languages.
+	lexerCandidates []chroma.Lexer
+
+	// Show the lexer name in the status bar if it wasn't given by the file
+	// name or on the command line
+	showLanguage bool
+
 	// This channel expects to be read exactly once. All other uses will lead to
 	// undefined behavior.
 	doneWaitingForFirstByte chan bool
@@ -118,6 +163,30 @@ type ReaderImpl struct {
 
 	// PauseStatus is true if the reader is paused, false if it is not
 	PauseStatus *atomic.Bool
+
+	// Set if this reader lists the members of an archive
+	archive *archiveListing
+
+	// What to render JSON log lines with if the input turns out to be a JSON
+	// log. Nil if log mode is disabled.
+	logTemplateCandidate *logTemplate
+
+	// Set if the input is a JSON log. Decided when we see the first non-empty
+	// line.
+	logTemplate    *logTemplate
+	logModeDecided bool
+
+	// Set if the input is newline delimited JSON, decided together with log
+	// mode. With --reformat, each JSON line is then pretty printed as it is
+	// read.
+	ndjson         bool
+	reformatNdjson bool
+	shouldFormat   bool
+
+	// The last input line, and how many lines it became after reformatting.
+	// Used when more text is appended to a last line that had no newline.
+	lastInputLine          string
+	lastInputLineLineCount int
 }
 
 // InputLines contains a number of lines from the reader, plus metadata
@@ -137,6 +206,15 @@ func (reader *ReaderImpl) preAllocLines() {
 		return
 	}
 
+	reader.Lock()
+	fileBacked := reader.fileBacked != nil
+	reader.Unlock()
+	if fileBacked {
+		// Counting the lines would mean reading the whole file one extra time,
+		// for no benefit since we aren't going to keep the lines in memory
+		return
+	}
+
 	if reader.GetLineCount() > 0 {
 		// We already have lines, could be because we're tailing some file. Too
 		// late for pre-allocation.
@@ -179,9 +257,16 @@ func (reader *ReaderImpl) readStream(stream io.Reader, formatter chroma.Formatte
 	default:
 	}
 
+	if _, seekable := stream.(io.Seeker); !seekable {
+		// Decompressed streams count uncompressed bytes, which can't be
+		// compared to the file size when checking for truncation
+		log.Debug("Input stream is not seekable, not tailing")
+		return
+	}
+
 	// Tail the file if the stream is coming from a file.
 	// Ref: https://github.com/walles/moor/issues/224
-	err := reader.tailFile()
+	err := reader.tailFile(options.FollowName)
 	if err != nil {
 		log.Warn("Failed to tail file: ", err)
 	}
@@ -192,7 +277,7 @@ func (reader *ReaderImpl) readStream(stream io.Reader, formatter chroma.Formatte
 func (reader *ReaderImpl) maybePause() {
 	for {
 		reader.Lock()
-		shouldPause := len(reader.lines) >= reader.pauseAfterLines
+		shouldPause := reader.lineCountUnlocked() >= reader.pauseAfterLines
 		reader.Unlock()
 
 		if !shouldPause {
@@ -214,16 +299,27 @@ func (reader *ReaderImpl) maybePause() {
 func (reader *ReaderImpl) consumeLinesFromStream(stream io.Reader) {
 	reader.preAllocLines()
 
+	// Where in the file the stream starts. Only used when file backed.
+	reader.Lock()
+	startOffset := reader.bytesCount
+	reader.Unlock()
+
 	inspectionReader := inspectionReader{base: stream}
 	bufioReader := bufio.NewReader(&inspectionReader)
 	completeLine := make([]byte, 0)
 
+	// Byte offset of the stream position, taking buffering into account
+	streamOffset := func() int64 {
+		return startOffset + inspectionReader.bytesCount - int64(bufioReader.Buffered())
+	}
+
 	t0 := time.Now()
 	for {
 		reader.maybePause()
 
 		keepReadingLine := true
 		eof := false
+		lineStartOffset := streamOffset()
 
 		var lineBytes []byte
 		var err error
@@ -265,19 +361,21 @@ func (reader *ReaderImpl) consumeLinesFromStream(stream io.Reader) {
 			break
 		}
 
-		newLineString := string(completeLine)
-		newLine := NewLine(newLineString)
-
 		reader.Lock()
-		if len(reader.lines) > 0 && !reader.endsWithNewline {
-			// The last line didn't end with a newline, append to it
-			newLineString = reader.lines[len(reader.lines)-1].raw + newLineString
-			newLine = NewLine(newLineString)
-			reader.lines[len(reader.lines)-1] = &newLine
+		reader.decideLineModeUnlocked(completeLine)
+		if reader.fileBacked != nil {
+			// Just keep track of where the line is, we'll re-read it from
+			// disk when it's needed
+			if reader.fileBacked.inputLineCount > 0 && !reader.endsWithNewline {
+				reader.fileBacked.extendLastLine(streamOffset(), reader.fileBackedLineCountUnlocked(completeLine, true))
+			} else {
+				reader.fileBacked.addLine(lineStartOffset, streamOffset(), reader.fileBackedLineCountUnlocked(completeLine, false))
+			}
 		} else {
-			reader.lines = append(reader.lines, &newLine)
+			reader.addLineUnlocked(string(completeLine))
 		}
 		reader.endsWithNewline = true
+		reader.maybeGuessLexerUnlocked()
 
 		reader.Unlock()
 
@@ -312,7 +410,9 @@ func (reader *ReaderImpl) consumeLinesFromStream(stream io.Reader) {
 	log.Info("Stream read in ", time.Since(t0), ", have ", reader.GetLineCount(), " lines")
 }
 
-func (reader *ReaderImpl) tailFile() error {
+// If followName is set, we keep tailing if the file is truncated or replaced,
+// like "tail -F". Otherwise we stop tailing when that happens.
+func (reader *ReaderImpl) tailFile(followName bool) error {
 	reader.Lock()
 	fileName := reader.FileName
 	reader.Unlock()
@@ -322,14 +422,27 @@ func (reader *ReaderImpl) tailFile() error {
 
 	log.Debugf("Tailing file %s", *fileName)
 
-	for {
-		// NOTE: We could use something like
-		// https://github.com/fsnotify/fsnotify instead of sleeping and polling
-		// here.
-		time.Sleep(1 * time.Second)
+	// The file we're currently tailing, for noticing if it gets replaced
+	tailedFile, err := os.Stat(*fileName)
+	if err != nil {
+		log.Debugf("Failed to stat file %s before tailing: %s", *fileName, err.Error())
+		tailedFile = nil
+	}
 
+	watcher := newFileWatcher(*fileName, followName)
+	defer func() {
+		watcher.close()
+	}()
+
+	// Wait at the end of each iteration rather than at the start, in case the
+	// file changed between us finishing reading it and starting to watch it.
+	for ; ; watcher.waitForChange() {
 		fileStats, err := os.Stat(*fileName)
 		if err != nil {
+			if followName {
+				log.Tracef("Failed to stat file %s while tailing, waiting for it to come back: %s", *fileName, err.Error())
+				continue
+			}
 			log.Debugf("Failed to stat file %s while tailing, giving up: %s", *fileName, err.Error())
 			return nil
 		}
@@ -343,20 +456,50 @@ func (reader *ReaderImpl) tailFile() error {
 			return nil
 		}
 
+		if followName && tailedFile != nil && !os.SameFile(tailedFile, fileStats) {
+			log.Debugf("File %s replaced, reading the new one from the start", *fileName)
+			reader.startOver("--- file replaced ---")
+			bytesCount = 0
+
+			// The old watcher is watching the old file
+			watcher.close()
+			watcher = newFileWatcher(*fileName, followName)
+
+			// The new file might have grown before we started watching it
+			fileStats, err = os.Stat(*fileName)
+			if err != nil {
+				log.Debugf("Failed to stat replacement file %s: %s", *fileName, err.Error())
+				tailedFile = nil
+				continue
+			}
+		} else if fileStats.Size() < bytesCount {
+			if !followName {
+				log.Debugf("File %s shrunk from %d to %d bytes, stop tailing",
+					*fileName, bytesCount, fileStats.Size())
+				return nil
+			}
+
+			log.Debugf("File %s shrunk from %d to %d bytes, reading it again from the start",
+				*fileName, bytesCount, fileStats.Size())
+			reader.startOver("--- file truncated ---")
+			bytesCount = 0
+		}
+		tailedFile = fileStats
+
 		if fileStats.Size() == bytesCount {
 			log.Tracef("File %s unchanged at %d bytes, continue tailing", *fileName, fileStats.Size())
 			continue
 		}
 
-		if fileStats.Size() < bytesCount {
-			log.Debugf("File %s shrunk from %d to %d bytes, stop tailing",
-				*fileName, bytesCount, fileStats.Size())
-			return nil
-		}
-
 		// File grew, read the new lines
 		stream, _, err := ZOpen(*fileName)
 		if err != nil {
+			if followName {
+				// Might have been removed since we checked, wait for it to
+				// come back
+				log.Debugf("Failed to open file %s for re-reading while tailing, will try again: %s", *fileName, err.Error())
+				continue
+			}
 			log.Debugf("Failed to open file %s for re-reading while tailing: %s", *fileName, err.Error())
 			return nil
 		}
@@ -402,7 +545,7 @@ func NewFromStream(name string, reader io.Reader, formatter chroma.Formatter, op
 	if err != nil {
 		return nil, err
 	}
-	mReader := newReaderFromStream(zReader, nil, formatter, options)
+	mReader := newReaderFromStream(zReader, nil, formatter, options, nil)
 
 	if len(name) > 0 {
 		mReader.Lock()
@@ -430,12 +573,15 @@ func NewFromStream(name string, reader io.Reader, formatter chroma.Formatter, op
 //
 // If lexer is set, the file will be highlighted after being fully read.
 //
+// If fileBacked is set, lines will be re-read from disk on demand rather than
+// kept in memory.
+//
 // Whatever data we get from the reader, that's what we'll have. Or in other
 // words, if the input needs to be decompressed, do that before coming here.
 //
 // Note that you must call reader.SetStyleForHighlighting() after this to get
 // highlighting.
-func newReaderFromStream(reader io.Reader, originalFileName *string, formatter chroma.Formatter, options ReaderOptions) *ReaderImpl {
+func newReaderFromStream(reader io.Reader, originalFileName *string, formatter chroma.Formatter, options ReaderOptions, fileBacked *fileBackedLines) *ReaderImpl {
 	done := atomic.Bool{}
 	done.Store(false)
 	highlightingDone := atomic.Bool{}
@@ -450,8 +596,15 @@ func newReaderFromStream(reader io.Reader, originalFileName *string, formatter c
 		// This needs to be size 1. If it would be 0, and we add more
 		// lines while the pager is processing, the pager would miss
 		// the lines added while it was processing.
-		FileName: originalFileName,
-		Name:     originalFileName,
+		FileName:   originalFileName,
+		Name:       originalFileName,
+		fileBacked: fileBacked,
+
+		formatter: formatter,
+		lexer:     options.Lexer,
+
+		logTemplateCandidate: logTemplateFromOptions(options),
+		shouldFormat:         options.ShouldFormat,
 
 		pauseAfterLines:        pauseAfterLines,
 		pauseAfterLinesUpdated: make(chan bool, 1),
@@ -604,6 +757,21 @@ func NewFromFilename(filename string, formatter chroma.Formatter, options Reader
 		return nil, fileError
 	}
 
+	format, err := detectArchiveFormat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if format == archiveFormatZip && !strings.EqualFold(filepath.Ext(filename), ".zip") {
+		// Lots of file formats are zip archives in disguise, like .jar and
+		// .docx. Only list the members of files named like zip archives.
+		// Members of the others can still be viewed using "x.jar:member".
+		format = archiveFormatNone
+	}
+	if format != archiveFormatNone {
+		log.Debugf("File is an archive, listing its members: %v", filename)
+		return newArchiveListingReader(filename, format, formatter, options), nil
+	}
+
 	stream, highlightingFilename, err := ZOpen(filename)
 	if err != nil {
 		return nil, err
@@ -613,7 +781,15 @@ func NewFromFilename(filename string, formatter chroma.Formatter, options Reader
 		options.Lexer = lexers.Match(highlightingFilename)
 	}
 
-	returnMe := newReaderFromStream(stream, &highlightingFilename, formatter, options)
+	var fileBacked *fileBackedLines
+	if file, ok := stream.(*os.File); ok {
+		// Uncompressed, we can re-read lines from disk on demand
+		fileBacked = maybeFileBacked(file, options)
+	} else {
+		stream, fileBacked = maybeCompressedFileBacked(filename, stream, options)
+	}
+
+	returnMe := newReaderFromStream(stream, &highlightingFilename, formatter, options, fileBacked)
 
 	if options.Lexer == nil {
 		returnMe.HighlightingDone.Store(true)
@@ -645,7 +821,8 @@ func (reader *ReaderImpl) Wait() error {
 	return reader.Err
 }
 
-func textAsString(reader *ReaderImpl, shouldFormat bool) string {
+// The second return value is true if the text was reformatted.
+func textAsString(reader *ReaderImpl, shouldFormat bool, lexer chroma.Lexer) (string, bool) {
 	reader.Lock()
 
 	text := strings.Builder{}
@@ -654,29 +831,31 @@ func textAsString(reader *ReaderImpl, shouldFormat bool) string {
 		text.WriteString("\n")
 	}
 	result := text.String()
+	ndjson := reader.ndjson
 	reader.Unlock()
 
-	var jsonData any
-	err := json.Unmarshal([]byte(result), &jsonData)
-	if err != nil {
-		// Not JSON, return the text as-is
-		return result
+	if !shouldFormat {
+		if json.Valid([]byte(result)) {
+			log.Info("Try the --reformat flag for automatic JSON reformatting")
+		} else if ndjson {
+			log.Info("Try the --reformat flag for automatic NDJSON reformatting")
+		}
+		return result, false
 	}
 
-	if !shouldFormat {
-		log.Info("Try the --reformat flag for automatic JSON reformatting")
-		return result
+	if ndjson {
+		// Already reformatted line by line while reading
+		return result, false
 	}
 
-	// Pretty print the JSON
-	prettyJSON, err := json.MarshalIndent(jsonData, "", "  ")
-	if err != nil {
-		log.Debug("Failed to pretty print JSON: ", err)
-		return result
+	formatted, formatName := reformat(result, lexer)
+	if formatName == "" {
+		// Nothing we know how to reformat, return the text as-is
+		return result, false
 	}
 
-	log.Debug("Got the --reformat flag, reformatted JSON input")
-	return string(prettyJSON)
+	log.Debug("Got the --reformat flag, reformatted ", formatName, " input")
+	return formatted, true
 }
 
 func isXml(text string) bool {
@@ -697,30 +876,60 @@ func highlightFromMemory(reader *ReaderImpl, formatter chroma.Formatter, options
 	// Is the buffer small enough?
 	var byteCount int64
 	reader.Lock()
+	if reader.fileBacked != nil {
+		log.Info("File backed, too large for highlighting")
+		reader.Unlock()
+		return
+	}
+	if reader.logTemplate != nil {
+		log.Info("JSON log lines are rendered using a template, not highlighting")
+		reader.Unlock()
+		return
+	}
 	for _, line := range reader.lines {
 		byteCount += int64(len(line.raw))
 
 		if byteCount > MAX_HIGHLIGHT_SIZE {
-			log.Info("File too large for highlighting: ", byteCount)
+			log.Info("File too large for highlighting up front, highlighting on demand: ", byteCount)
 			reader.Unlock()
 			return
 		}
 	}
+	if options.Lexer == nil {
+		// Possibly guessed from the first line
+		options.Lexer = reader.lexer
+	}
 	reader.Unlock()
 
-	text := textAsString(reader, options.ShouldFormat)
+	text, reformatted := textAsString(reader, options.ShouldFormat, options.Lexer)
 
 	if len(text) == 0 {
 		log.Debug("Buffer is empty, not highlighting")
 		return
 	}
 
+	if reformatted {
+		reader.setText(text)
+	}
+
+	guessedLexer := options.Lexer == nil
 	if options.Lexer == nil && json.Valid([]byte(text)) {
 		log.Info("Buffer is valid JSON, highlighting as JSON")
 		options.Lexer = lexers.Get("json")
+	} else if options.Lexer == nil && reader.isNdjson() {
+		log.Info("Buffer is newline delimited JSON, highlighting as JSON")
+		options.Lexer = lexers.Get("json")
 	} else if options.Lexer == nil && isXml(text) {
 		log.Info("Buffer is valid XML, highlighting as XML")
 		options.Lexer = lexers.Get("xml")
+	} else if options.Lexer == nil {
+		reader.Lock()
+		if !reader.lexerGuessed {
+			// Not enough lines for guessing while reading
+			reader.guessLexerUnlocked()
+		}
+		options.Lexer = reader.lexer
+		reader.Unlock()
 	}
 
 	if options.Lexer == nil {
@@ -738,18 +947,158 @@ func highlightFromMemory(reader *ReaderImpl, formatter chroma.Formatter, options
 		return
 	}
 
-	highlighted, err := Highlight(text, *options.Style, formatter, options.Lexer)
-	if err != nil {
-		log.Warn("Highlighting failed: ", err)
-		return
+	reader.Lock()
+	reader.style = options.Style
+	reader.formatter = formatter
+	if guessedLexer {
+		reader.setLexerUnlocked(options.Lexer)
+	} else {
+		reader.lexer = options.Lexer
 	}
+	highlighter := reader.startHighlighterUnlocked()
+	reader.Unlock()
 
-	if highlighted == nil {
+	if highlighter == nil {
 		// No highlighting would be done, never mind
 		return
 	}
 
-	reader.setText(*highlighted)
+	// Small enough, highlight everything before saying we're done
+	if highlighter.highlightUpTo(math.MaxInt) {
+		select {
+		case reader.MoreLinesAdded <- true:
+		default:
+		}
+	}
+}
+
+// Start highlighting in the background, unless we're already doing that using
+// the current lexer. Returns nil if there's nothing to highlight with. Assumes
+// that the caller is holding the lock.
+func (reader *ReaderImpl) startHighlighterUnlocked() *highlighter {
+	if reader.highlighter != nil && reader.highlighter.lexer == reader.lexer {
+		return reader.highlighter
+	}
+
+	if reader.highlighter != nil {
+		reader.highlighter.stop()
+		reader.highlighter = nil
+	}
+
+	if reader.fileBacked != nil || reader.style == nil {
+		return nil
+	}
+
+	reader.highlighter = newHighlighter(reader, *reader.style, reader.formatter, reader.lexer)
+	if reader.highlighter == nil {
+		return nil
+	}
+
+	highlighter := reader.highlighter
+	go func() {
+		defer func() {
+			PanicHandler("highlighter.run()", recover(), debug.Stack())
+		}()
+
+		highlighter.run()
+	}()
+
+	return highlighter
+}
+
+// Get up to maxCount lines starting at firstIndex for highlighting. The
+// second return value is true if there are more lines after the returned ones.
+func (reader *ReaderImpl) linesForHighlighting(firstIndex int, maxCount int) ([]*Line, bool) {
+	reader.Lock()
+	defer reader.Unlock()
+
+	if reader.fileBacked != nil || reader.logTemplate != nil {
+		return nil, false
+	}
+
+	if firstIndex >= len(reader.lines) {
+		return nil, false
+	}
+
+	end := min(firstIndex+maxCount, len(reader.lines))
+	return slices.Clone(reader.lines[firstIndex:end]), end < len(reader.lines)
+}
+
+// Go into log mode if the first non-empty line is a JSON log line, or into
+// NDJSON mode if it is some other JSON object. Assumes that the caller is
+// holding the lock.
+func (reader *ReaderImpl) decideLineModeUnlocked(line []byte) {
+	if reader.logModeDecided || len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	reader.logModeDecided = true
+
+	if reader.logTemplateCandidate != nil && looksLikeJsonLog(string(line)) {
+		log.Info("Input is a JSON log, rendering lines using a template")
+		reader.logTemplate = reader.logTemplateCandidate
+		if reader.fileBacked != nil {
+			reader.fileBacked.logTemplate = reader.logTemplate
+		}
+		return
+	}
+
+	if parseLogObject(string(line)) == nil {
+		return
+	}
+	reader.ndjson = true
+
+	if !reader.shouldFormat {
+		return
+	}
+	log.Info("Input is newline delimited JSON, reformatting it line by line")
+	reader.reformatNdjson = true
+	if reader.fileBacked != nil {
+		reader.fileBacked.reformatNdjson = true
+	}
+}
+
+// How many lines a file backed input line turns into. If extending, the text
+// continues the last input line. Assumes that the caller is holding the lock.
+func (reader *ReaderImpl) fileBackedLineCountUnlocked(text []byte, extending bool) int {
+	if !reader.reformatNdjson {
+		// No need to keep the last input line around
+		return 1
+	}
+
+	if extending {
+		reader.lastInputLine += string(text)
+	} else {
+		reader.lastInputLine = string(text)
+	}
+	return len(reformatNdjsonLine(reader.lastInputLine))
+}
+
+func (reader *ReaderImpl) isNdjson() bool {
+	reader.Lock()
+	defer reader.Unlock()
+	return reader.ndjson
+}
+
+// Add a line read from the input. If the last line didn't end with a newline,
+// the new text continues that line instead. Assumes that the caller is holding
+// the lock.
+func (reader *ReaderImpl) addLineUnlocked(text string) {
+	if len(reader.lines) > 0 && !reader.endsWithNewline {
+		// The last line didn't end with a newline, append to it
+		reader.lines = reader.lines[:len(reader.lines)-reader.lastInputLineLineCount]
+		text = reader.lastInputLine + text
+	}
+	reader.lastInputLine = text
+
+	parts := []string{text}
+	if reader.reformatNdjson {
+		parts = reformatNdjsonLine(text)
+	}
+	for _, part := range parts {
+		newLine := newLineMaybeLog(part, reader.logTemplate)
+		reader.lines = append(reader.lines, &newLine)
+	}
+	reader.lastInputLineLineCount = len(parts)
 }
 
 // createStatusUnlocked() assumes that its caller is holding the lock
@@ -759,7 +1108,8 @@ func (reader *ReaderImpl) createStatusUnlocked(lastLine linemetadata.Index) stri
 		filename = filepath.Base(*reader.Name)
 	}
 
-	if len(reader.lines) == 0 {
+	lineCount := reader.lineCountUnlocked()
+	if lineCount == 0 {
 		empty := "<empty>"
 		if len(filename) > 0 {
 			return filename + ": " + empty
@@ -769,13 +1119,13 @@ func (reader *ReaderImpl) createStatusUnlocked(lastLine linemetadata.Index) stri
 
 	linesCount := ""
 	percent := ""
-	if len(reader.lines) == 1 {
+	if lineCount == 1 {
 		linesCount = "1 line"
 		percent = "100%"
 	} else {
 		// More than one line
-		linesCount = util.FormatInt(len(reader.lines)) + " lines"
-		percent = fmt.Sprintf("%.0f%%", math.Floor(100*float64(lastLine.Index()+1)/float64(len(reader.lines))))
+		linesCount = util.FormatInt(lineCount) + " lines"
+		percent = fmt.Sprintf("%.0f%%", math.Floor(100*float64(lastLine.Index()+1)/float64(lineCount)))
 	}
 
 	if !reader.ShouldShowLineCount() {
@@ -817,9 +1167,28 @@ func (reader *ReaderImpl) GetLineCount() int {
 	reader.Lock()
 	defer reader.Unlock()
 
+	return reader.lineCountUnlocked()
+}
+
+// lineCountUnlocked() assumes that its caller is holding the lock
+func (reader *ReaderImpl) lineCountUnlocked() int {
+	if reader.fileBacked != nil {
+		return reader.fileBacked.lineCount
+	}
+
 	return len(reader.lines)
 }
 
+// lineUnlocked() assumes that its caller is holding the lock, and that the
+// index is within bounds
+func (reader *ReaderImpl) lineUnlocked(index int) *Line {
+	if reader.fileBacked != nil {
+		return reader.fileBacked.getLine(index)
+	}
+
+	return reader.lines[index]
+}
+
 func (reader *ReaderImpl) ShouldShowLineCount() bool {
 	if reader.Done.Load() {
 		// We are done, the number won't change, show it!
@@ -853,13 +1222,13 @@ func (reader *ReaderImpl) GetLine(index linemetadata.Index) *NumberedLine {
 		}
 	}
 
-	if !index.IsWithinLength(len(reader.lines)) {
+	if !index.IsWithinLength(reader.lineCountUnlocked()) {
 		return nil
 	}
 	return &NumberedLine{
 		Index:  index,
 		Number: linemetadata.NumberFromZeroBased(index.Index()),
-		Line:   reader.lines[index.Index()],
+		Line:   reader.lineUnlocked(index.Index()),
 	}
 }
 
@@ -873,16 +1242,20 @@ func (reader *ReaderImpl) GetLines(firstLine linemetadata.Index, wantedLineCount
 }
 
 func (reader *ReaderImpl) getLinesUnlocked(firstLine linemetadata.Index, wantedLineCount int) *InputLines {
-	if len(reader.lines) == 0 || wantedLineCount == 0 {
+	lineCount := reader.lineCountUnlocked()
+	if lineCount == 0 || wantedLineCount == 0 {
 		return &InputLines{
 			StatusText: reader.createStatusUnlocked(firstLine),
 		}
 	}
 
 	lastLine := firstLine.NonWrappingAdd(wantedLineCount - 1)
+	if reader.highlighter != nil {
+		reader.highlighter.want(lastLine.Index())
+	}
 
 	// Prevent reading past the end of the available lines
-	maxLineIndex := *linemetadata.IndexFromLength(len(reader.lines))
+	maxLineIndex := *linemetadata.IndexFromLength(lineCount)
 	if lastLine.IsAfter(maxLineIndex) {
 		lastLine = maxLineIndex
 
@@ -893,10 +1266,24 @@ func (reader *ReaderImpl) getLinesUnlocked(firstLine linemetadata.Index, wantedL
 		return reader.getLinesUnlocked(firstLine, firstLine.CountLinesTo(lastLine))
 	}
 
-	notNumberedReturnLines := reader.lines[firstLine.Index() : lastLine.Index()+1]
-	returnLines := make([]*NumberedLine, 0, len(notNumberedReturnLines))
-	for loopIndex, line := range notNumberedReturnLines {
+	returnLineCount := firstLine.CountLinesTo(lastLine)
+	var fileBackedLines []*Line
+	if reader.fileBacked != nil {
+		// Reads consecutive blocks in one go, rather than one block at a time
+		fileBackedLines = reader.fileBacked.getLines(firstLine.Index(), returnLineCount)
+	}
+
+	returnLines := make([]*NumberedLine, 0, returnLineCount)
+	for loopIndex := 0; loopIndex < returnLineCount; loopIndex++ {
 		lineIndex := firstLine.NonWrappingAdd(loopIndex)
+
+		var line *Line
+		if fileBackedLines != nil {
+			line = fileBackedLines[loopIndex]
+		} else {
+			line = reader.lineUnlocked(lineIndex.Index())
+		}
+
 		returnLines = append(returnLines, &NumberedLine{
 			Index:  lineIndex,
 			Number: linemetadata.NumberFromZeroBased(lineIndex.Index()),
@@ -929,7 +1316,7 @@ func (reader *ReaderImpl) PumpToStdout() {
 				continue
 			}
 
-			fmt.Println(line.Line.raw)
+			fmt.Println(line.Line.display())
 			printed = true
 			firstNotPrintedLine = lineIndex.NonWrappingAdd(1)
 		}
@@ -959,6 +1346,23 @@ func (reader *ReaderImpl) PumpToStdout() {
 	drainAllLines()
 }
 
+// Add a separator line, then continue reading the file from its start. Used when
+// following a file that was truncated or replaced.
+func (reader *ReaderImpl) startOver(separator string) {
+	separatorLine := NewLine(separator)
+
+	reader.Lock()
+	reader.lines = append(reader.lines, &separatorLine)
+	reader.endsWithNewline = true
+	reader.bytesCount = 0
+	reader.Unlock()
+
+	select {
+	case reader.MoreLinesAdded <- true:
+	default:
+	}
+}
+
 // Replace reader contents with the given text and mark as done
 func (reader *ReaderImpl) setText(text string) {
 	lines := []*Line{}
@@ -975,6 +1379,13 @@ func (reader *ReaderImpl) setText(text string) {
 
 	reader.Lock()
 	reader.lines = lines
+	reader.fileBacked = nil
+	if reader.highlighter != nil {
+		// Highlighting progress was for the old lines
+		reader.highlighter.stop()
+		reader.highlighter = nil
+		reader.startHighlighterUnlocked()
+	}
 	reader.Unlock()
 
 	reader.Done.Store(true)
@@ -1021,5 +1432,20 @@ func (reader *ReaderImpl) SetPauseAfterLines(lines int) {
 }
 
 func (reader *ReaderImpl) SetStyleForHighlighting(style chroma.Style) {
+	if reader.archive != nil {
+		// Archive members should be highlighted using the same style
+		reader.archive.lock.Lock()
+		reader.archive.options.Style = &style
+		reader.archive.lock.Unlock()
+	}
+
+	reader.Lock()
+	reader.style = &style
+	if reader.lexer != nil {
+		// Highlight on demand while we're still reading
+		reader.startHighlighterUnlocked()
+	}
+	reader.Unlock()
+
 	reader.highlightingStyle <- style
 }
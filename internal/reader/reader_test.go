@@ -1,6 +1,8 @@
 package reader
 
 import (
+	"bytes"
+	"compress/gzip"
 	"os"
 	"os/exec"
 	"path"
@@ -174,6 +176,15 @@ func testHighlightingLineCount(t *testing.T, filenameWithPath string) {
 	if strings.HasSuffix(filenameWithPath, ".zstd") {
 		return
 	}
+	if strings.HasSuffix(filenameWithPath, ".lz4") {
+		return
+	}
+	if strings.HasSuffix(filenameWithPath, ".lz") {
+		return
+	}
+	if strings.HasSuffix(filenameWithPath, ".br") {
+		return
+	}
 
 	// Load the unformatted file
 	rawBytes, err := os.ReadFile(filenameWithPath)
@@ -274,6 +285,9 @@ func TestCompressedFiles(t *testing.T) {
 	testCompressedFile(t, "compressed.txt.xz")
 	testCompressedFile(t, "compressed.txt.zst")
 	testCompressedFile(t, "compressed.txt.zstd")
+	testCompressedFile(t, "compressed.txt.lz4")
+	testCompressedFile(t, "compressed.txt.br")
+	testCompressedFile(t, "compressed.txt.lz")
 }
 
 func TestReadFileDoneNoHighlighting(t *testing.T) {
@@ -359,6 +373,127 @@ func TestFormatJsonArray(t *testing.T) {
 	assert.Equal(t, len(lines.Lines), 5)
 }
 
+// Returns the plain lines of the reformatted input
+func reformatForTesting(t *testing.T, name string, text string) []string {
+	t.Helper()
+
+	testMe, err := NewFromStream(
+		name,
+		strings.NewReader(text),
+		formatters.TTY,
+		ReaderOptions{
+			Lexer:        lexers.Match(name),
+			Style:        styles.Get("native"),
+			ShouldFormat: true,
+		})
+	assert.NilError(t, err)
+	assert.NilError(t, testMe.Wait())
+
+	lines := []string{}
+	for _, line := range testMe.GetLines(linemetadata.Index{}, 100).Lines {
+		lines = append(lines, line.Plain())
+	}
+	return lines
+}
+
+func TestFormatNdjson(t *testing.T) {
+	lines := reformatForTesting(t, "NDJSON test", `{"level": "info", "msg": "hello"}
+Not JSON
+{"level":"warn"}
+`)
+	assert.DeepEqual(t, []string{
+		"{",
+		`  "level": "info",`,
+		`  "msg": "hello"`,
+		"}",
+		"Not JSON",
+		"{",
+		`  "level": "warn"`,
+		"}",
+	}, lines)
+}
+
+// Too large for reformatting all of it after reading, NDJSON should still be
+// reformatted line by line
+func TestFormatLargeNdjson(t *testing.T) {
+	line := `{"level": "info", "msg": "Some text to make this line longer"}` + "\n"
+	lines := reformatForTesting(t, "NDJSON test", strings.Repeat(line, 2*int(MAX_HIGHLIGHT_SIZE)/len(line)))
+	assert.DeepEqual(t, []string{
+		"{",
+		`  "level": "info",`,
+		`  "msg": "Some text to make this line longer"`,
+		"}",
+	}, lines[:4])
+}
+
+// A JSON line arriving in two parts should be reformatted once it's complete
+func TestFormatNdjsonSplitLine(t *testing.T) {
+	for _, fileBacked := range []bool{false, true} {
+		t.Run("fileBacked="+strconv.FormatBool(fileBacked), func(t *testing.T) {
+			fileName := path.Join(t.TempDir(), "split.ndjson")
+			file, err := os.Create(fileName)
+			assert.NilError(t, err)
+			defer file.Close() //nolint:errcheck
+
+			_, err = file.WriteString(`{"a": 1}` + "\n" + `{"b":`)
+			assert.NilError(t, err)
+
+			testMe, err := NewFromFilename(fileName, formatters.TTY16m, ReaderOptions{Style: styles.Get("native"), ShouldFormat: true, FileBacked: fileBacked})
+			assert.NilError(t, err)
+			assert.NilError(t, testMe.Wait())
+			assert.Equal(t, fileBacked, testMe.fileBacked != nil)
+			assert.Equal(t, 4, testMe.GetLineCount())
+
+			_, err = file.WriteString(" 2}\n")
+			assert.NilError(t, err)
+
+			// Give the reader some time to react
+			for range 20 {
+				if testMe.GetLineCount() == 6 {
+					break
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			lines := []string{}
+			for _, line := range testMe.GetLines(linemetadata.Index{}, 100).Lines {
+				lines = append(lines, line.Plain())
+			}
+			assert.DeepEqual(t, []string{"{", `  "a": 1`, "}", "{", `  "b": 2`, "}"}, lines)
+		})
+	}
+}
+
+func TestFormatXml(t *testing.T) {
+	lines := reformatForTesting(t, "XML test",
+		`<?xml version="1.0"?><soap:Envelope xmlns:soap="http://example.com/"><soap:Body><b>text</b></soap:Body></soap:Envelope>`)
+	assert.DeepEqual(t, []string{
+		`<?xml version="1.0"?>`,
+		`<soap:Envelope xmlns:soap="http://example.com/">`,
+		"  <soap:Body>",
+		"    <b>text</b>",
+		"  </soap:Body>",
+		"</soap:Envelope>",
+	}, lines)
+}
+
+func TestFormatYaml(t *testing.T) {
+	lines := reformatForTesting(t, "test.yaml", "a:\n    b: [1, 2]\n    c:    'd'\n---\n- e\n")
+	assert.DeepEqual(t, []string{
+		"a:",
+		"  b: [1, 2]",
+		"  c: 'd'",
+		"---",
+		"- e",
+	}, lines)
+}
+
+// Plain text is valid YAML, but it shouldn't be reformatted
+func TestFormatYamlPlainText(t *testing.T) {
+	lines := reformatForTesting(t, "test.yaml", "Hello   world\n")
+	assert.DeepEqual(t, []string{"Hello   world"}, lines)
+}
+
 // If people keep appending to the currently opened file we should display those
 // changes.
 func TestReadUpdatingFile(t *testing.T) {
@@ -564,6 +699,111 @@ func TestReadUpdatingFile_HalfUtf8(t *testing.T) {
 	assert.Equal(t, int(testMe.bytesCount), len([]byte("här")))
 }
 
+// Wait for the reader to get the expected number of lines, then return them
+func awaitLines(t *testing.T, testMe *ReaderImpl, expectedLineCount int) []string {
+	t.Helper()
+
+	for range 20 {
+		if testMe.GetLineCount() == expectedLineCount {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	lines := []string{}
+	for _, line := range testMe.GetLines(linemetadata.Index{}, 100).Lines {
+		lines = append(lines, line.Plain())
+	}
+	return lines
+}
+
+// With FollowName, truncated files should be read again from the start
+func TestFollowName_Truncated(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "truncated.log")
+	assert.NilError(t, os.WriteFile(fileName, []byte("Old line 1\nOld line 2\n"), 0o600))
+
+	testMe, err := NewFromFilename(fileName, formatters.TTY16m, ReaderOptions{Style: styles.Get("native"), FollowName: true})
+	assert.NilError(t, err)
+	assert.NilError(t, testMe.Wait())
+	assert.Equal(t, testMe.GetLineCount(), 2)
+
+	// Truncate the file and write something shorter to it
+	assert.NilError(t, os.WriteFile(fileName, []byte("New\n"), 0o600))
+
+	assert.DeepEqual(t, []string{"Old line 1", "Old line 2", "--- file truncated ---", "New"},
+		awaitLines(t, testMe, 4))
+}
+
+// With FollowName, we should notice when the file is rotated away and a new one
+// is created in its place
+func TestFollowName_Replaced(t *testing.T) {
+	dir := t.TempDir()
+	fileName := path.Join(dir, "rotated.log")
+	assert.NilError(t, os.WriteFile(fileName, []byte("Old line\n"), 0o600))
+
+	testMe, err := NewFromFilename(fileName, formatters.TTY16m, ReaderOptions{Style: styles.Get("native"), FollowName: true})
+	assert.NilError(t, err)
+	assert.NilError(t, testMe.Wait())
+	assert.Equal(t, testMe.GetLineCount(), 1)
+
+	// Rotate the log file, then start logging to a new file with the same name
+	assert.NilError(t, os.Rename(fileName, path.Join(dir, "rotated.log.1")))
+	assert.NilError(t, os.WriteFile(fileName, []byte("New line 1\nNew line 2\n"), 0o600))
+
+	assert.DeepEqual(t, []string{"Old line", "--- file replaced ---", "New line 1", "New line 2"},
+		awaitLines(t, testMe, 4))
+
+	// Appending to the new file should still work
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_WRONLY, 0o600)
+	assert.NilError(t, err)
+	_, err = file.WriteString("New line 3\n")
+	assert.NilError(t, err)
+	assert.NilError(t, file.Close())
+
+	assert.DeepEqual(t, []string{"Old line", "--- file replaced ---", "New line 1", "New line 2", "New line 3"},
+		awaitLines(t, testMe, 5))
+}
+
+// Compressed files can't be tailed, their sizes can't be compared to the
+// number of bytes read
+func TestFollowName_Compressed(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte(strings.Repeat("Same line\n", 100)))
+	assert.NilError(t, err)
+	assert.NilError(t, writer.Close())
+
+	dir := t.TempDir()
+	fileName := path.Join(dir, "compressed.log.gz")
+	assert.NilError(t, os.WriteFile(fileName, compressed.Bytes(), 0o600))
+
+	// Compressed files are named without their .gz suffix, so this shouldn't
+	// be mistaken for the file being tailed
+	assert.NilError(t, os.WriteFile(path.Join(dir, "compressed.log"), []byte("Other\n"), 0o600))
+
+	testMe, err := NewFromFilename(fileName, formatters.TTY16m, ReaderOptions{Style: styles.Get("native"), FollowName: true})
+	assert.NilError(t, err)
+	assert.NilError(t, testMe.Wait())
+	time.Sleep(300 * time.Millisecond)
+
+	assert.Equal(t, 100, testMe.GetLineCount())
+}
+
+// Without FollowName, we should stop tailing truncated files
+func TestReadUpdatingFile_Truncated(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "truncated.log")
+	assert.NilError(t, os.WriteFile(fileName, []byte("Old line 1\nOld line 2\n"), 0o600))
+
+	testMe, err := NewFromFilename(fileName, formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.NilError(t, testMe.Wait())
+
+	assert.NilError(t, os.WriteFile(fileName, []byte("New\n"), 0o600))
+	time.Sleep(300 * time.Millisecond)
+
+	assert.DeepEqual(t, []string{"Old line 1", "Old line 2"}, awaitLines(t, testMe, 2))
+}
+
 // How long does it take to read a file?
 //
 // This can be slow due to highlighting.
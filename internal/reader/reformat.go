@@ -0,0 +1,199 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Reformat text if we know how. The lexer is the one we got from the file
+// name, if any. Newline delimited JSON is reformatted line by line while
+// reading instead, see reformatNdjsonLine().
+//
+// Returns the reformatted text and the name of its format, or an empty format
+// name if we don't know how to reformat this text.
+func reformat(text string, lexer chroma.Lexer) (string, string) {
+	if formatted, ok := reformatJson(text); ok {
+		return formatted, "JSON"
+	}
+
+	if formatted, ok := reformatXml(text); ok {
+		return formatted, "XML"
+	}
+
+	if formatted, ok := reformatYaml(text, lexer); ok {
+		return formatted, "YAML"
+	}
+
+	return text, ""
+}
+
+func reformatJson(text string) (string, bool) {
+	var jsonData any
+	err := json.Unmarshal([]byte(text), &jsonData)
+	if err != nil {
+		return text, false
+	}
+
+	prettyJSON, err := json.MarshalIndent(jsonData, "", "  ")
+	if err != nil {
+		log.Debug("Failed to pretty print JSON: ", err)
+		return text, false
+	}
+
+	return string(prettyJSON), true
+}
+
+// Pretty print one line of newline delimited JSON, like lots of services log.
+// Lines that aren't JSON objects, like stack traces, are kept as-is.
+func reformatNdjsonLine(line string) []string {
+	if !strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return []string{line}
+	}
+
+	pretty := bytes.Buffer{}
+	err := json.Indent(&pretty, []byte(line), "", "  ")
+	if err != nil {
+		// Not JSON, keep it as-is
+		return []string{line}
+	}
+
+	return strings.Split(pretty.String(), "\n")
+}
+
+// With RawToken() namespace prefixes end up in Space, which the encoder would
+// treat as a namespace URL. Put them back in the local name instead.
+func withXmlPrefix(name xml.Name) xml.Name {
+	if name.Space == "" {
+		return name
+	}
+	return xml.Name{Local: name.Space + ":" + name.Local}
+}
+
+func reformatXml(text string) (string, bool) {
+	if !isXml(text) {
+		return text, false
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(text))
+	formatted := bytes.Buffer{}
+	encoder := xml.NewEncoder(&formatted)
+	encoder.Indent("", "  ")
+
+	depth := 0
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Debug("Failed to parse XML for reformatting: ", err)
+			return text, false
+		}
+
+		switch typed := token.(type) {
+		case xml.StartElement:
+			depth++
+			typed.Name = withXmlPrefix(typed.Name)
+			for i := range typed.Attr {
+				typed.Attr[i].Name = withXmlPrefix(typed.Attr[i].Name)
+			}
+			token = typed
+
+		case xml.EndElement:
+			depth--
+			typed.Name = withXmlPrefix(typed.Name)
+			token = typed
+
+		case xml.CharData:
+			if len(bytes.TrimSpace(typed)) == 0 {
+				// The encoder does the indentation
+				continue
+			}
+		}
+
+		err = encoder.EncodeToken(xml.CopyToken(token))
+		if err != nil {
+			log.Debug("Failed to reformat XML: ", err)
+			return text, false
+		}
+
+		switch token.(type) {
+		case xml.ProcInst, xml.Directive:
+			if depth == 0 {
+				// The encoder won't put the prolog on lines of its own
+				err = encoder.Flush()
+				if err != nil {
+					log.Debug("Failed to reformat XML: ", err)
+					return text, false
+				}
+				formatted.WriteString("\n")
+			}
+		}
+	}
+
+	err := encoder.Flush()
+	if err != nil {
+		log.Debug("Failed to reformat XML: ", err)
+		return text, false
+	}
+
+	return formatted.String(), true
+}
+
+// Since almost any text is valid YAML, we only reformat if the file name says
+// YAML, or if the text starts with a YAML document marker.
+func reformatYaml(text string, lexer chroma.Lexer) (string, bool) {
+	isYamlLexer := lexer != nil && lexer.Config().Name == "YAML"
+	if !isYamlLexer && !strings.HasPrefix(text, "---\n") {
+		return text, false
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(text))
+	formatted := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&formatted)
+	encoder.SetIndent(2)
+
+	documentCount := 0
+	for {
+		document := yaml.Node{}
+		err := decoder.Decode(&document)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Debug("Failed to parse YAML for reformatting: ", err)
+			return text, false
+		}
+
+		if len(document.Content) == 0 || document.Content[0].Kind == yaml.ScalarNode {
+			// Plain text parses as one big string, that's not what we want
+			return text, false
+		}
+
+		err = encoder.Encode(&document)
+		if err != nil {
+			log.Debug("Failed to reformat YAML: ", err)
+			return text, false
+		}
+		documentCount++
+	}
+
+	err := encoder.Close()
+	if err != nil {
+		log.Debug("Failed to reformat YAML: ", err)
+		return text, false
+	}
+
+	if documentCount == 0 {
+		return text, false
+	}
+
+	return formatted.String(), true
+}
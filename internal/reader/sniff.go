@@ -0,0 +1,183 @@
+package reader
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+// Interpreters that Chroma doesn't know by name
+var interpreterLexerNames = map[string]string{
+	"node": "javascript",
+	"deno": "typescript",
+}
+
+var gitCommitHeader = regexp.MustCompile(`^commit [0-9a-f]{7,64}\b`)
+
+// How many lines to look at when guessing the language of some input
+const guessLanguageLineCount = 100
+
+var sqlStatement = regexp.MustCompile(`(?i)^(SELECT|INSERT\s+INTO|UPDATE|DELETE\s+FROM|CREATE\s+(TABLE|INDEX|VIEW|DATABASE|SCHEMA|FUNCTION)|ALTER\s+TABLE|DROP\s+TABLE|BEGIN;)\b`)
+var dockerfileInstruction = regexp.MustCompile(`^(FROM|ARG)\s+\S`)
+
+// Chroma analysers scoring less than this are too unsure for us
+const minAnalyserScore = 0.5
+
+// Chroma analysers that claim to recognize lots of things they shouldn't
+var unreliableAnalysers = map[string]bool{
+	"GDScript3": true, // Any text containing "func"
+	"MySQL":     true, // Any text with `backticks`
+}
+
+// Man pages are formatted using backspaces, like "b\bbo\bol\bld\bd"
+var manPageFormatting = regexp.MustCompile(`.\x08.`)
+
+// Guess which lexers could fit some input, best guess first, for when we have
+// no file name to go by. Returns nil if we have no idea, or if the input
+// shouldn't be highlighted.
+func guessLexers(text string) []chroma.Lexer {
+	if manPageFormatting.MatchString(text) {
+		// Already formatted, highlighting would just mess that up
+		return nil
+	}
+
+	candidates := []chroma.Lexer{}
+	addCandidate := func(lexer chroma.Lexer) {
+		if lexer == nil {
+			return
+		}
+		for _, candidate := range candidates {
+			if candidate.Config().Name == lexer.Config().Name {
+				return
+			}
+		}
+		candidates = append(candidates, lexer)
+	}
+
+	firstLine := true
+	for _, line := range strings.Split(text, "\n") {
+		if firstLine {
+			addCandidate(sniffLexer(line))
+			firstLine = false
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "--") {
+			// Comments don't tell us much
+			continue
+		}
+
+		// The first real line decides
+		if dockerfileInstruction.MatchString(line) {
+			addCandidate(lexers.Get("docker"))
+		}
+		if sqlStatement.MatchString(line) {
+			addCandidate(lexers.Get("sql"))
+		}
+		break
+	}
+
+	type scoredLexer struct {
+		lexer chroma.Lexer
+		score float32
+	}
+	scored := []scoredLexer{}
+	for _, lexer := range lexers.GlobalLexerRegistry.Lexers {
+		analyser, ok := lexer.(chroma.Analyser)
+		if !ok || unreliableAnalysers[lexer.Config().Name] {
+			continue
+		}
+
+		score := analyser.AnalyseText(text)
+		if score >= minAnalyserScore {
+			scored = append(scored, scoredLexer{lexer, score})
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+	for _, candidate := range scored {
+		addCandidate(candidate.lexer)
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates
+}
+
+// Guess a lexer from the first line of some input, for when we have no file
+// name to go by. Returns nil if we have no idea.
+func sniffLexer(firstLine string) chroma.Lexer {
+	if strings.HasPrefix(firstLine, "#!") {
+		return lexerFromShebang(firstLine)
+	}
+
+	// "git show" output starts with a commit header
+	if strings.HasPrefix(firstLine, "diff ") || gitCommitHeader.MatchString(firstLine) {
+		return lexers.Get("diff")
+	}
+
+	// "kubectl get -o yaml" output starts with "apiVersion:"
+	if strings.TrimRight(firstLine, " ") == "---" || strings.HasPrefix(firstLine, "apiVersion: ") {
+		return lexers.Get("yaml")
+	}
+
+	if strings.HasPrefix(firstLine, "<?xml ") {
+		return lexers.Get("xml")
+	}
+
+	return nil
+}
+
+// Handles both "#!/bin/bash" and "#!/usr/bin/env python3"
+func lexerFromShebang(shebang string) chroma.Lexer {
+	words := strings.Fields(strings.TrimPrefix(shebang, "#!"))
+	if len(words) == 0 {
+		return nil
+	}
+
+	interpreter := path.Base(words[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, word := range words[1:] {
+			if strings.HasPrefix(word, "-") || strings.Contains(word, "=") {
+				// Options and environment variable assignments
+				continue
+			}
+			interpreter = path.Base(word)
+			break
+		}
+	}
+
+	// "python3.12" -> "python"
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	if interpreter == "" {
+		return nil
+	}
+
+	if lexerName, found := interpreterLexerNames[interpreter]; found {
+		interpreter = lexerName
+	}
+
+	lexer := lexers.Get(interpreter)
+	if lexer == nil || !strings.EqualFold(lexer.Config().Name, interpreter) && !hasAlias(lexer, interpreter) {
+		// lexers.Get() falls back on matching file names, we don't want that
+		return nil
+	}
+
+	return lexer
+}
+
+func hasAlias(lexer chroma.Lexer, alias string) bool {
+	for _, lexerAlias := range lexer.Config().Aliases {
+		if strings.EqualFold(lexerAlias, alias) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package reader
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/styles"
+	"gotest.tools/v3/assert"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+)
+
+func assertSniffedLexer(t *testing.T, firstLine string, expectedLexerName string) {
+	t.Helper()
+
+	lexer := sniffLexer(firstLine)
+	if expectedLexerName == "" {
+		assert.Assert(t, lexer == nil, "Expected no lexer for <%s>, got %s", firstLine, lexer)
+		return
+	}
+
+	assert.Assert(t, lexer != nil, "Expected a lexer for <%s>", firstLine)
+	assert.Equal(t, lexer.Config().Name, expectedLexerName)
+}
+
+func TestSniffLexer(t *testing.T) {
+	assertSniffedLexer(t, "#!/bin/bash", "Bash")
+	assertSniffedLexer(t, "#!/bin/sh -e", "Bash")
+	assertSniffedLexer(t, "#!/usr/bin/env python3", "Python")
+	assertSniffedLexer(t, "#!/usr/bin/env -S python3.12 -u", "Python")
+	assertSniffedLexer(t, "#!/usr/bin/env node", "JavaScript")
+	assertSniffedLexer(t, "#!/usr/bin/perl -w", "Perl")
+	assertSniffedLexer(t, "#!/usr/bin/env", "")
+	assertSniffedLexer(t, "#!/usr/bin/env nosuchinterpreter", "")
+
+	assertSniffedLexer(t, "diff --git a/README.md b/README.md", "Diff")
+	assertSniffedLexer(t, "commit 3ab36131d1f2a2e0d6c6e35f8c3f2e1b1a2b3c4d", "Diff")
+
+	assertSniffedLexer(t, "---", "YAML")
+	assertSniffedLexer(t, "apiVersion: v1", "YAML")
+
+	assertSniffedLexer(t, `<?xml version="1.0" encoding="UTF-8"?>`, "XML")
+
+	assertSniffedLexer(t, "", "")
+	assertSniffedLexer(t, "Hello, world!", "")
+	assertSniffedLexer(t, "--- a/README.md", "")
+}
+
+// Streamed input should be highlighted as it arrives, not only when the stream
+// ends
+func TestHighlightStreamWhileReading(t *testing.T) {
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		// NewFromStream() blocks until it gets some input
+		_, err := pipeWriter.Write([]byte("diff --git a/x b/x\n+added\n-removed\n"))
+		assert.Check(t, err)
+	}()
+
+	testMe, err := NewFromStream("", pipeReader, formatters.TTY16m, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+
+	for testMe.GetLineCount() < 3 {
+		<-testMe.MoreLinesAdded
+	}
+
+	lines := testMe.GetLines(linemetadata.Index{}, 3)
+	for !lines.Lines[1].Line.isHighlighted() {
+		<-testMe.MoreLinesAdded
+	}
+	assert.Assert(t, !testMe.Done.Load())
+	assert.Assert(t, strings.Contains(lines.Lines[1].Line.display(), "\x1b["))
+	assert.Equal(t, lines.Lines[1].Line.Plain(nil), "+added")
+
+	assert.NilError(t, pipeWriter.Close())
+	assert.NilError(t, testMe.Wait())
+}
+
+func assertGuessedLexers(t *testing.T, text string, expectedLexerNames ...string) {
+	t.Helper()
+
+	var names []string
+	for _, lexer := range guessLexers(text) {
+		names = append(names, lexer.Config().Name)
+	}
+	assert.DeepEqual(t, names, expectedLexerNames)
+}
+
+func TestGuessLexers(t *testing.T) {
+	assertGuessedLexers(t, "#!/bin/bash\necho hello\n", "Bash")
+	assertGuessedLexers(t, "# Build image\nFROM golang:1.22\nRUN go build\n", "Docker")
+	assertGuessedLexers(t, "-- Users\nSELECT * FROM users;\n", "SQL")
+	assertGuessedLexers(t, "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n", "Go")
+
+	// Man page formatting, shouldn't be highlighted
+	assertGuessedLexers(t, "N\bNA\bAM\bME\bE\n     moor - the nice pager\n")
+
+	// Plain text, with things that fool some Chroma analysers
+	assertGuessedLexers(t, "Use `moor` for paging.\n\nThis is a func thing.\n")
+}
@@ -0,0 +1,232 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// How JSON log lines are rendered unless configured otherwise. Field names in
+// braces are replaced by the corresponding values from each JSON object.
+//
+//revive:disable-next-line:var-naming
+const DEFAULT_LOG_TEMPLATE = "{ts} {level} {msg}"
+
+// Different loggers use different names for the same things. If a template
+// field isn't found in a log object, try these instead.
+var logFieldAliases = map[string][]string{
+	"ts":    {"ts", "time", "timestamp", "@timestamp", "t"},
+	"level": {"level", "lvl", "severity", "log.level", "loglevel"},
+	"msg":   {"msg", "message", "@message", "log"},
+}
+
+var logTemplateField = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// Level values are padded to this width to line up the following columns
+const logLevelWidth = 5
+
+type logTemplatePart struct {
+	// Either literal text or a field name
+	literal string
+	field   string
+}
+
+// A parsed log line template, like DEFAULT_LOG_TEMPLATE
+type logTemplate struct {
+	parts []logTemplatePart
+}
+
+func newLogTemplate(template string) *logTemplate {
+	returnMe := logTemplate{}
+
+	lastEnd := 0
+	for _, match := range logTemplateField.FindAllStringSubmatchIndex(template, -1) {
+		if match[0] > lastEnd {
+			returnMe.parts = append(returnMe.parts, logTemplatePart{literal: template[lastEnd:match[0]]})
+		}
+		returnMe.parts = append(returnMe.parts, logTemplatePart{field: template[match[2]:match[3]]})
+		lastEnd = match[1]
+	}
+	if lastEnd < len(template) {
+		returnMe.parts = append(returnMe.parts, logTemplatePart{literal: template[lastEnd:]})
+	}
+
+	return &returnMe
+}
+
+// Returns nil if JSON log lines should be shown as-is
+func logTemplateFromOptions(options ReaderOptions) *logTemplate {
+	if options.NoLogMode {
+		return nil
+	}
+
+	if options.ShouldFormat {
+		// Asking for reformatting means wanting to see the JSON
+		return nil
+	}
+
+	if options.LogTemplate == "" {
+		return newLogTemplate(DEFAULT_LOG_TEMPLATE)
+	}
+
+	return newLogTemplate(options.LogTemplate)
+}
+
+// Returns nil if the line isn't a JSON object
+func parseLogObject(line string) map[string]any {
+	if !strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return nil
+	}
+
+	var object map[string]any
+	err := json.Unmarshal([]byte(line), &object)
+	if err != nil {
+		return nil
+	}
+
+	return object
+}
+
+func lookUpLogField(object map[string]any, field string) (any, bool) {
+	value, found := object[field]
+	if found {
+		return value, true
+	}
+
+	for _, alias := range logFieldAliases[field] {
+		value, found := object[alias]
+		if found {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// If the first line of a stream looks like this, we go into log mode for the
+// rest of it.
+func looksLikeJsonLog(line string) bool {
+	object := parseLogObject(line)
+	if object == nil {
+		return false
+	}
+
+	_, hasLevel := lookUpLogField(object, "level")
+	_, hasMessage := lookUpLogField(object, "msg")
+	return hasLevel || hasMessage
+}
+
+func formatLogValue(value any) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case float64:
+		return fmt.Sprint(typed)
+	}
+
+	asJson, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(asJson)
+}
+
+// ANSI SGR sequence for the given log level, or an empty string for unknown
+// levels. Numeric levels are the ones used by Bunyan and Pino.
+func logLevelColor(value any) string {
+	level := strings.ToLower(formatLogValue(value))
+	if number, isNumber := value.(float64); isNumber {
+		switch {
+		case number >= 50:
+			level = "error"
+		case number >= 40:
+			level = "warn"
+		case number >= 30:
+			level = "info"
+		default:
+			level = "debug"
+		}
+	}
+
+	switch level {
+	case "fatal", "panic", "critical", "crit", "alert", "emerg", "emergency", "error", "err":
+		return "\x1b[1;31m"
+	case "warn", "warning":
+		return "\x1b[33m"
+	case "info", "notice":
+		return "\x1b[32m"
+	case "debug", "trace":
+		return "\x1b[34m"
+	}
+
+	return ""
+}
+
+func (template *logTemplate) render(object map[string]any) string {
+	rendered := strings.Builder{}
+	for _, part := range template.parts {
+		if part.field == "" {
+			rendered.WriteString(part.literal)
+			continue
+		}
+
+		value, _ := lookUpLogField(object, part.field)
+		if part.field != "level" {
+			rendered.WriteString(formatLogValue(value))
+			continue
+		}
+
+		padded := fmt.Sprintf("%-*s", logLevelWidth, strings.ToUpper(formatLogValue(value)))
+		color := logLevelColor(value)
+		if color == "" {
+			rendered.WriteString(padded)
+			continue
+		}
+
+		rendered.WriteString(color)
+		rendered.WriteString(padded)
+		rendered.WriteString("\x1b[m")
+	}
+
+	return rendered.String()
+}
+
+// Create a line from raw input. If template is set and the input is a JSON
+// object, the line is rendered using the template, and the JSON object is kept
+// for LogObjectLines().
+func newLineMaybeLog(raw string, template *logTemplate) Line {
+	if template == nil {
+		return NewLine(raw)
+	}
+
+	object := parseLogObject(raw)
+	if object == nil {
+		// Stack traces and such
+		return NewLine(raw)
+	}
+
+	return Line{
+		raw:       template.render(object),
+		logObject: raw,
+	}
+}
+
+// If this line was rendered from a JSON log object, return that object pretty
+// printed. Returns nil otherwise.
+func (line *Line) LogObjectLines() []string {
+	if line.logObject == "" {
+		return nil
+	}
+
+	pretty := bytes.Buffer{}
+	err := json.Indent(&pretty, []byte(line.logObject), "", "  ")
+	if err != nil {
+		return []string{line.logObject}
+	}
+
+	return strings.Split(pretty.String(), "\n")
+}
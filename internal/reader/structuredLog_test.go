@@ -0,0 +1,84 @@
+package reader
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/styles"
+	"gotest.tools/v3/assert"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+)
+
+func TestLogTemplateRender(t *testing.T) {
+	template := newLogTemplate("[{ts}] {level} {msg} ({user.id})")
+	object := parseLogObject(`{"time": "12:34", "severity": "warn", "message": "Hello", "user.id": 42}`)
+
+	line := NewLine(template.render(object))
+	assert.Equal(t, "[12:34] WARN  Hello (42)", line.Plain(nil))
+
+	// Level should be colored
+	assert.Assert(t, strings.Contains(line.raw, "\x1b[33mWARN \x1b[m"), line.raw)
+}
+
+func TestLogTemplateMissingFields(t *testing.T) {
+	template := newLogTemplate(DEFAULT_LOG_TEMPLATE)
+	line := NewLine(template.render(parseLogObject(`{"msg": "Only a message", "nested": {"a": 1}}`)))
+	assert.Equal(t, "       Only a message", line.Plain(nil))
+
+	template = newLogTemplate("{nested}")
+	line = NewLine(template.render(parseLogObject(`{"msg": "Only a message", "nested": {"a": 1}}`)))
+	assert.Equal(t, `{"a":1}`, line.Plain(nil))
+}
+
+func TestLooksLikeJsonLog(t *testing.T) {
+	assert.Assert(t, looksLikeJsonLog(`{"level": "info", "msg": "hello"}`))
+	assert.Assert(t, looksLikeJsonLog(`  {"message": "hello"}`))
+
+	assert.Assert(t, !looksLikeJsonLog(`{"key": "value"}`))
+	assert.Assert(t, !looksLikeJsonLog(`{"level": "info"`))
+	assert.Assert(t, !looksLikeJsonLog(`level=info msg=hello`))
+	assert.Assert(t, !looksLikeJsonLog(`["level", "msg"]`))
+}
+
+func TestJsonLogMode(t *testing.T) {
+	testMe, err := NewFromStream("JSON log", strings.NewReader(`
+{"ts": "10:00", "level": "info", "msg": "Starting"}
+Not JSON, like a stack trace
+{"ts": "10:01", "level": 50, "msg": "Failed"}
+`), formatters.TTY, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, []string{
+		"",
+		"10:00 INFO  Starting",
+		"Not JSON, like a stack trace",
+		"10:01 50    Failed",
+	}, readAllLines(t, testMe))
+
+	objectLines := testMe.GetLine(linemetadata.IndexFromZeroBased(1)).Line.LogObjectLines()
+	assert.DeepEqual(t, []string{
+		"{",
+		`  "ts": "10:00",`,
+		`  "level": "info",`,
+		`  "msg": "Starting"`,
+		"}",
+	}, objectLines)
+
+	assert.Assert(t, testMe.GetLine(linemetadata.IndexFromZeroBased(2)).Line.LogObjectLines() == nil)
+}
+
+func TestJsonLogModeDisabled(t *testing.T) {
+	input := `{"level": "info", "msg": "Starting"}`
+
+	// Not a log
+	testMe, err := NewFromStream("JSON log", strings.NewReader("Hello\n"+input), formatters.TTY, ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"Hello", input}, readAllLines(t, testMe))
+
+	// Log mode disabled
+	testMe, err = NewFromStream("JSON log", strings.NewReader(input), formatters.TTY, ReaderOptions{Style: styles.Get("native"), NoLogMode: true})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{input}, readAllLines(t, testMe))
+}
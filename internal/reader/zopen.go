@@ -9,8 +9,11 @@ import (
 	"os"
 	"strings"
 
+	"github.com/andybalholm/brotli"
 	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
 	log "github.com/sirupsen/logrus"
+	lzip "github.com/sorairolake/lzip-go"
 	"github.com/ulikunitz/xz"
 )
 
@@ -18,6 +21,28 @@ var gzipMagic = []byte{0x1f, 0x8b}
 var bzip2Magic = []byte{0x42, 0x5a, 0x68}
 var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
 var xzMagic = []byte{0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00}
+var lz4Magic = []byte{0x04, 0x22, 0x4d, 0x18}
+var lzipMagic = []byte{0x4c, 0x5a, 0x49, 0x50}
+
+// Brotli has no magic number, so brotli files are recognized by their file
+// name extension only
+const brotliExtension = ".br"
+
+// Decompresses a file, and closes both the decompressor and the file when
+// closed. Closing a gzip.Reader or a zstd decoder leaves their input open.
+type decompressedFile struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (d decompressedFile) Close() error {
+	err := d.ReadCloser.Close()
+	fileErr := d.file.Close()
+	if err != nil {
+		return err
+	}
+	return fileErr
+}
 
 // The second return value is the file name with any compression extension removed.
 func ZOpen(filename string) (io.ReadCloser, string, error) {
@@ -34,12 +59,14 @@ func ZOpen(filename string) (io.ReadCloser, string, error) {
 			// File was empty
 			return file, filename, nil
 		}
+		_ = file.Close()
 		return nil, "", fmt.Errorf("failed to read file: %w", err)
 	}
 
 	// Reset file reader to start of file
 	_, err = file.Seek(0, 0)
 	if err != nil {
+		_ = file.Close()
 		return nil, "", fmt.Errorf("failed to seek to start of file: %w", err)
 	}
 
@@ -48,6 +75,7 @@ func ZOpen(filename string) (io.ReadCloser, string, error) {
 		log.Debugf("File is gzip compressed: %v", filename)
 		reader, err := gzip.NewReader(file)
 		if err != nil {
+			_ = file.Close()
 			return nil, "", err
 		}
 
@@ -58,7 +86,7 @@ func ZOpen(filename string) (io.ReadCloser, string, error) {
 			newName = strings.TrimSuffix(newName, ".tgz") + ".tar"
 		}
 
-		return reader, newName, err
+		return decompressedFile{reader, file}, newName, nil
 
 	case bytes.HasPrefix(firstBytes, bzip2Magic):
 		log.Debugf("File is bzip2 compressed: %v", filename)
@@ -71,17 +99,19 @@ func ZOpen(filename string) (io.ReadCloser, string, error) {
 		log.Debugf("File is zstd compressed: %v", filename)
 		decoder, err := zstd.NewReader(file)
 		if err != nil {
+			_ = file.Close()
 			return nil, "", err
 		}
 
 		newName := strings.TrimSuffix(filename, ".zst")
 		newName = strings.TrimSuffix(newName, ".zstd")
-		return decoder.IOReadCloser(), newName, nil
+		return decompressedFile{decoder.IOReadCloser(), file}, newName, nil
 
 	case bytes.HasPrefix(firstBytes, xzMagic):
 		log.Debugf("File is xz compressed: %v", filename)
 		xzReader, err := xz.NewReader(file)
 		if err != nil {
+			_ = file.Close()
 			return nil, "", err
 		}
 
@@ -89,6 +119,33 @@ func ZOpen(filename string) (io.ReadCloser, string, error) {
 			io.Reader
 			io.Closer
 		}{xzReader, file}, strings.TrimSuffix(filename, ".xz"), nil
+
+	case bytes.HasPrefix(firstBytes, lz4Magic):
+		log.Debugf("File is lz4 compressed: %v", filename)
+		return struct {
+			io.Reader
+			io.Closer
+		}{lz4.NewReader(file), file}, strings.TrimSuffix(filename, ".lz4"), nil
+
+	case bytes.HasPrefix(firstBytes, lzipMagic):
+		log.Debugf("File is lzip compressed: %v", filename)
+		lzipReader, err := lzip.NewReader(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, "", err
+		}
+
+		return struct {
+			io.Reader
+			io.Closer
+		}{lzipReader, file}, strings.TrimSuffix(filename, ".lz"), nil
+
+	case strings.HasSuffix(filename, brotliExtension):
+		log.Debugf("File is assumed to be brotli compressed: %v", filename)
+		return struct {
+			io.Reader
+			io.Closer
+		}{brotli.NewReader(file), file}, strings.TrimSuffix(filename, brotliExtension), nil
 	}
 
 	log.Debugf("File is assumed to be uncompressed: %v", filename)
@@ -99,6 +156,9 @@ func ZOpen(filename string) (io.ReadCloser, string, error) {
 // compression will be automatically detected. Uncompressed streams will be
 // returned as-is.
 //
+// Brotli streams can't be detected since brotli has no magic number, and will
+// be returned as-is.
+//
 // Ref: https://github.com/walles/moor/issues/261
 func ZReader(input io.Reader) (io.Reader, error) {
 	// Read the first 6 bytes to determine the compression type
@@ -129,6 +189,12 @@ func ZReader(input io.Reader) (io.Reader, error) {
 	case bytes.HasPrefix(firstBytes, xzMagic):
 		log.Info("Input stream is xz compressed")
 		return xz.NewReader(input)
+	case bytes.HasPrefix(firstBytes, lz4Magic):
+		log.Info("Input stream is lz4 compressed")
+		return lz4.NewReader(input), nil
+	case bytes.HasPrefix(firstBytes, lzipMagic):
+		log.Info("Input stream is lzip compressed")
+		return lzip.NewReader(input)
 	default:
 		// No magic numbers matched
 		log.Info("Input stream is assumed to be uncompressed")
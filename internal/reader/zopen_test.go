@@ -3,6 +3,8 @@ package reader
 import (
 	"bytes"
 	"io"
+	"os"
+	"path"
 	"testing"
 
 	"gotest.tools/v3/assert"
@@ -34,3 +36,57 @@ func TestZReaderOneByte(t *testing.T) {
 	assert.Equal(t, 1, len(all))
 	assert.Equal(t, byte(42), all[0])
 }
+
+func testZOpen(t *testing.T, filename string) {
+	stream, name, err := ZOpen(path.Join(samplesDir, filename))
+	assert.NilError(t, err)
+	defer func() {
+		assert.NilError(t, stream.Close())
+	}()
+
+	assert.Equal(t, path.Join(samplesDir, "compressed.txt"), name, filename)
+
+	all, err := io.ReadAll(stream)
+	assert.NilError(t, err)
+	assert.Equal(t, "This is a compressed file\n", string(all), filename)
+}
+
+func TestZOpen(t *testing.T) {
+	testZOpen(t, "compressed.txt.gz")
+	testZOpen(t, "compressed.txt.lz4")
+	testZOpen(t, "compressed.txt.lz")
+	testZOpen(t, "compressed.txt.br")
+}
+
+// Closing a gzip or zstd stream should close the file too
+func TestZOpenClosesFile(t *testing.T) {
+	for _, filename := range []string{"compressed.txt.gz", "compressed.txt.zst"} {
+		stream, _, err := ZOpen(path.Join(samplesDir, filename))
+		assert.NilError(t, err)
+		assert.NilError(t, stream.Close())
+
+		_, err = stream.(decompressedFile).file.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, os.ErrClosed, filename)
+	}
+}
+
+func testZReader(t *testing.T, filename string) {
+	file, err := os.Open(path.Join(samplesDir, filename))
+	assert.NilError(t, err)
+	defer func() {
+		assert.NilError(t, file.Close())
+	}()
+
+	zReader, err := ZReader(file)
+	assert.NilError(t, err)
+
+	all, err := io.ReadAll(zReader)
+	assert.NilError(t, err)
+	assert.Equal(t, "This is a compressed file\n", string(all), filename)
+}
+
+func TestZReader(t *testing.T) {
+	testZReader(t, "compressed.txt.gz")
+	testZReader(t, "compressed.txt.lz4")
+	testZReader(t, "compressed.txt.lz")
+}
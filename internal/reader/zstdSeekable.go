@@ -0,0 +1,155 @@
+package reader
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+)
+
+// Ref: https://github.com/facebook/zstd/blob/dev/contrib/seekable_format/zstd_seekable_compression_format.md
+const zstdSeekableMagic = 0x8F92EAB1
+const zstdSkippableFrameMagic = 0x184D2A5E
+const zstdSeekTableFooterSize = 9
+
+// Where an independently decompressible frame starts, both compressed and
+// uncompressed
+type zstdFrame struct {
+	compressedOffset   int64
+	uncompressedOffset int64
+}
+
+// The seek table of a seekable zstd file
+type zstdSeekTable struct {
+	fileName string
+
+	// Sorted by offset
+	frames []zstdFrame
+}
+
+// Returns nil and no error if the file has no seek table
+func readZstdSeekTable(fileName string) (*zstdSeekTable, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			log.Debugf("Failed to close %s after reading its zstd seek table: %s", fileName, err)
+		}
+	}()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if stat.Size() < zstdSeekTableFooterSize {
+		return nil, nil
+	}
+
+	footer := make([]byte, zstdSeekTableFooterSize)
+	_, err = file.ReadAt(footer, stat.Size()-zstdSeekTableFooterSize)
+	if err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(footer[5:]) != zstdSeekableMagic {
+		return nil, nil
+	}
+
+	frameCount := int64(binary.LittleEndian.Uint32(footer[0:]))
+	entrySize := int64(8)
+	if footer[4]&0x80 != 0 {
+		// Entries have checksums
+		entrySize = 12
+	}
+
+	// The seek table is a skippable frame, 8 bytes of frame header plus the
+	// entries plus the footer
+	entriesSize := frameCount * entrySize
+	tableStart := stat.Size() - zstdSeekTableFooterSize - entriesSize - 8
+	if tableStart < 0 {
+		return nil, fmt.Errorf("zstd seek table with %d frames doesn't fit in %d bytes", frameCount, stat.Size())
+	}
+
+	table := make([]byte, 8+entriesSize)
+	_, err = file.ReadAt(table, tableStart)
+	if err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(table[0:]) != zstdSkippableFrameMagic {
+		return nil, fmt.Errorf("zstd seek table frame magic not found at offset %d", tableStart)
+	}
+	if int64(binary.LittleEndian.Uint32(table[4:])) != entriesSize+zstdSeekTableFooterSize {
+		return nil, fmt.Errorf("zstd seek table frame size mismatch")
+	}
+
+	returnMe := &zstdSeekTable{fileName: fileName}
+	var compressedOffset, uncompressedOffset int64
+	for entry := table[8:]; len(entry) > 0; entry = entry[entrySize:] {
+		returnMe.frames = append(returnMe.frames, zstdFrame{
+			compressedOffset:   compressedOffset,
+			uncompressedOffset: uncompressedOffset,
+		})
+		compressedOffset += int64(binary.LittleEndian.Uint32(entry[0:]))
+		uncompressedOffset += int64(binary.LittleEndian.Uint32(entry[4:]))
+	}
+
+	if compressedOffset != tableStart {
+		return nil, fmt.Errorf("zstd seek table says frames end at %d, but the table starts at %d", compressedOffset, tableStart)
+	}
+
+	return returnMe, nil
+}
+
+// Open the zstd file for reading uncompressed data starting at offset,
+// starting from the closest frame before that offset.
+func (table *zstdSeekTable) openAt(offset int64) (io.ReadCloser, error) {
+	frameIndex := sort.Search(len(table.frames), func(i int) bool {
+		return table.frames[i].uncompressedOffset > offset
+	}) - 1
+	frame := zstdFrame{}
+	if frameIndex >= 0 {
+		frame = table.frames[frameIndex]
+	}
+
+	file, err := os.Open(table.fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = file.Seek(frame.compressedOffset, io.SeekStart)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	decoder, err := zstd.NewReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	_, err = io.CopyN(io.Discard, decoder, offset-frame.uncompressedOffset)
+	if err != nil {
+		decoder.Close()
+		_ = file.Close()
+		return nil, fmt.Errorf("%s: failed to skip to offset %d: %w", table.fileName, offset, err)
+	}
+
+	return zstdFrameReader{decoder, file}, nil
+}
+
+type zstdFrameReader struct {
+	*zstd.Decoder
+	file *os.File
+}
+
+func (r zstdFrameReader) Close() error {
+	r.Decoder.Close()
+	return r.file.Close()
+}
@@ -0,0 +1,80 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"gotest.tools/v3/assert"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+)
+
+// Compress each part into its own frame, then add a seek table
+func writeZstdSeekable(t *testing.T, parts ...[]byte) string {
+	encoder, err := zstd.NewWriter(nil)
+	assert.NilError(t, err)
+
+	contents := []byte{}
+	entries := []byte{}
+	for _, part := range parts {
+		frame := encoder.EncodeAll(part, nil)
+		contents = append(contents, frame...)
+		entries = binary.LittleEndian.AppendUint32(entries, uint32(len(frame)))
+		entries = binary.LittleEndian.AppendUint32(entries, uint32(len(part)))
+	}
+
+	contents = binary.LittleEndian.AppendUint32(contents, zstdSkippableFrameMagic)
+	contents = binary.LittleEndian.AppendUint32(contents, uint32(len(entries)+zstdSeekTableFooterSize))
+	contents = append(contents, entries...)
+	contents = binary.LittleEndian.AppendUint32(contents, uint32(len(parts)))
+	contents = append(contents, 0)
+	contents = binary.LittleEndian.AppendUint32(contents, zstdSeekableMagic)
+
+	fileName := path.Join(t.TempDir(), "test.txt.zst")
+	assert.NilError(t, os.WriteFile(fileName, contents, 0o600))
+	return fileName
+}
+
+func TestZstdSeekTable(t *testing.T) {
+	fileName := writeZstdSeekable(t, []byte("one\ntwo\n"), []byte("three\n"), []byte("four\nfive\n"))
+
+	table, err := readZstdSeekTable(fileName)
+	assert.NilError(t, err)
+	assert.Assert(t, table != nil)
+	assert.Equal(t, 3, len(table.frames))
+	assert.Equal(t, int64(8), table.frames[1].uncompressedOffset)
+	assert.Equal(t, int64(14), table.frames[2].uncompressedOffset)
+
+	stream, err := table.openAt(10)
+	assert.NilError(t, err)
+	rest := bytes.Buffer{}
+	_, err = rest.ReadFrom(stream)
+	assert.NilError(t, err)
+	assert.NilError(t, stream.Close())
+	assert.Equal(t, "ree\nfour\nfive\n", rest.String())
+}
+
+func TestZstdSeekTableMissing(t *testing.T) {
+	table, err := readZstdSeekTable(path.Join(samplesDir, "compressed.txt.zst"))
+	assert.NilError(t, err)
+	assert.Assert(t, table == nil)
+}
+
+func TestFileBackedZstdSeekable(t *testing.T) {
+	contents := makeGzipTestContents(300_000)
+	lines := bytes.Split(bytes.TrimSuffix(contents, []byte("\n")), []byte("\n"))
+	fileName := writeZstdSeekable(t, contents[:100_000], contents[100_000:200_000], contents[200_000:])
+
+	reader := readFileForTesting(t, fileName, true)
+	assert.Assert(t, reader.fileBacked != nil)
+	assert.Equal(t, len(lines), reader.GetLineCount())
+
+	for i := len(lines) - 1; i >= 0; i -= 37 {
+		line := reader.GetLine(linemetadata.IndexFromZeroBased(i))
+		assert.Equal(t, string(lines[i]), line.Plain())
+	}
+}
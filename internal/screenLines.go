@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"unicode"
 
 	"github.com/walles/moor/v2/internal/linemetadata"
 	"github.com/walles/moor/v2/internal/reader"
@@ -20,6 +21,13 @@ type renderedLine struct {
 
 	cells []twin.StyledRune
 
+	// The part of the input line shown on this screen line, without line
+	// number and scroll markers
+	contents []twin.StyledRune
+
+	// Where in the input line contents starts, in number of runes
+	runeOffset int
+
 	// Used for rendering clear-to-end-of-line control sequences:
 	// https://en.wikipedia.org/wiki/ANSI_escape_code#EL
 	//
@@ -36,6 +44,12 @@ func (p *Pager) redraw(spinner string) {
 	lastUpdatedScreenLineNumber := -1
 	var renderedScreenLines [][]twin.StyledRune
 	renderedScreenLines, statusText := p.renderScreenLines()
+	if language := p.reader.Language(); language != "" && !p.isShowingHelp {
+		statusText += "  (" + language + ")"
+	}
+	if fileStatus := p.fileStatusText(); fileStatus != "" && !p.isShowingHelp {
+		statusText += "  (" + fileStatus + ")"
+	}
 	for screenLineNumber, row := range renderedScreenLines {
 		lastUpdatedScreenLineNumber = screenLineNumber
 		column := 0
@@ -57,6 +71,10 @@ func (p *Pager) redraw(spinner string) {
 		column += p.screen.SetCell(column, lastUpdatedScreenLineNumber+1, cell)
 	}
 
+	if p.ShowScrollbar {
+		p.drawScrollbar()
+	}
+
 	p.mode.drawFooter(statusText, spinner)
 
 	p.screen.Show()
@@ -84,7 +102,7 @@ func (p *Pager) renderScreenLines() (lines [][]twin.StyledRune, statusText strin
 		}
 
 		// Fill up with the trailer
-		screenWidth, _ := p.screen.Size()
+		screenWidth := p.contentsWidth()
 		for len(screenLines[len(screenLines)-1]) < screenWidth {
 			screenLines[len(screenLines)-1] =
 				append(screenLines[len(screenLines)-1], twin.NewStyledRune(' ', renderedLine.trailer))
@@ -183,18 +201,26 @@ func (p *Pager) renderLines() ([]renderedLine, string) {
 // lineNumber and numberPrefixLength are required for knowing how much to
 // indent, and to (optionally) render the line number.
 func (p *Pager) renderLine(line *reader.NumberedLine, numberPrefixLength int) []renderedLine {
-	highlighted := line.HighlightedTokens(plainTextStyle, standoutStyle, p.searchPattern)
+	highlighted := line.HighlightedTokens(plainTextStyle, standoutStyle, p.searchPattern, p.pinnedPatterns())
+	styledRunes := p.selection.highlight(line.Index, highlighted.StyledRunes)
 	var wrapped [][]twin.StyledRune
 	if p.WrapLongLines {
-		width, _ := p.screen.Size()
-		wrapped = wrapLine(width-numberPrefixLength, highlighted.StyledRunes)
+		wrapped = wrapLine(p.contentsWidth()-numberPrefixLength, styledRunes)
 	} else {
 		// All on one line
-		wrapped = [][]twin.StyledRune{highlighted.StyledRunes}
+		wrapped = [][]twin.StyledRune{styledRunes}
 	}
 
 	rendered := make([]renderedLine, 0)
+	runeOffset := 0
 	for wrapIndex, inputLinePart := range wrapped {
+		if wrapIndex > 0 {
+			// Wrapping drops the whitespace between the parts
+			for runeOffset < len(styledRunes) && unicode.IsSpace(styledRunes[runeOffset].Rune) {
+				runeOffset++
+			}
+		}
+
 		lineNumber := line.Number
 		visibleLineNumber := &lineNumber
 		if wrapIndex > 0 {
@@ -207,7 +233,10 @@ func (p *Pager) renderLine(line *reader.NumberedLine, numberPrefixLength int) []
 			inputLineIndex: line.Index,
 			wrapIndex:      wrapIndex,
 			cells:          decorated,
+			contents:       inputLinePart,
+			runeOffset:     runeOffset,
 		})
+		runeOffset += len(inputLinePart)
 	}
 
 	if highlighted.Trailer != twin.StyleDefault {
@@ -216,6 +245,26 @@ func (p *Pager) renderLine(line *reader.NumberedLine, numberPrefixLength int) []
 		rendered[len(rendered)-1].trailer = highlighted.Trailer
 	}
 
+	if p.expandedLogLines[line.Number] {
+		rendered = append(rendered, p.renderLogObject(line, numberPrefixLength, len(rendered))...)
+	}
+
+	if p.FilterContext > 0 && !p.isShowingHelp && p.filteringReader.hasGapAfter(line) {
+		// Separate groups of context lines, like "grep -C" does
+		separator := []twin.StyledRune{
+			twin.NewStyledRune('-', lineNumbersStyle),
+			twin.NewStyledRune('-', lineNumbersStyle),
+		}
+		rendered = append(rendered, renderedLine{
+			inputLineIndex: line.Index,
+			wrapIndex:      len(rendered),
+			cells:          p.decorateLine(nil, numberPrefixLength, separator),
+
+			// Not part of the line contents, so nothing to select here
+			runeOffset: len([]rune(line.Plain())),
+		})
+	}
+
 	return rendered
 }
 
@@ -224,7 +273,7 @@ func (p *Pager) renderLine(line *reader.NumberedLine, numberPrefixLength int) []
 //   - Scroll left indicator
 //   - Scroll right indicator
 func (p *Pager) decorateLine(lineNumberToShow *linemetadata.Number, numberPrefixLength int, contents []twin.StyledRune) []twin.StyledRune {
-	width, _ := p.screen.Size()
+	width := p.contentsWidth()
 	newLine := make([]twin.StyledRune, 0, width)
 	newLine = append(newLine, createLinePrefix(lineNumberToShow, numberPrefixLength)...)
 
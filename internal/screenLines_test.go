@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/walles/moor/v2/internal/linemetadata"
 	"github.com/walles/moor/v2/internal/reader"
 	"github.com/walles/moor/v2/twin"
@@ -125,6 +126,7 @@ func TestSearchHighlight(t *testing.T) {
 	}, rendered,
 		cmp.AllowUnexported(twin.Style{}),
 		cmp.AllowUnexported(renderedLine{}),
+		cmpopts.IgnoreFields(renderedLine{}, "contents", "runeOffset"),
 		cmp.AllowUnexported(linemetadata.Number{}),
 		cmp.AllowUnexported(linemetadata.Index{}),
 	)
@@ -320,3 +322,18 @@ func TestShortenedInputManyLines(t *testing.T) {
 	assert.Equal(t, pager.lineIndex().Index(), 90, "The last lines should now be visible")
 	assert.Equal(t, "match 99", rowToString(rendered[len(rendered)-1]))
 }
+
+func TestFilterContextSeparators(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("test", "a\nmatch\nb\nc\nd\nmatch\ne\n"))
+	pager.screen = twin.NewFakeScreen(20, 10)
+	pager.ShowLineNumbers = false
+	pager.FilterContext = 1
+	pager.filterPattern = regexp.MustCompile("match")
+
+	rendered, _ := pager.renderScreenLines()
+	renderedStrings := []string{}
+	for _, row := range rendered {
+		renderedStrings = append(renderedStrings, rowToString(row))
+	}
+	assert.DeepEqual(t, []string{"a", "match", "b", "--", "d", "match", "e"}, renderedStrings)
+}
@@ -50,9 +50,9 @@ type scrollPositionCanonical struct {
 }
 
 func canonicalFromPager(pager *Pager) scrollPositionCanonical {
-	width, height := pager.screen.Size()
+	_, height := pager.screen.Size()
 	return scrollPositionCanonical{
-		width:           width,
+		width:           pager.contentsWidth(),
 		height:          height,
 		showLineNumbers: pager.ShowLineNumbers,
 		showStatusBar:   pager.ShowStatusBar,
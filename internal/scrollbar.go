@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"sort"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/twin"
+)
+
+// Draw the scrollbar in the rightmost screen column
+func (p *Pager) drawScrollbar() {
+	p.updateSearchHits()
+	hits := p.searchHits.lines()
+	lineCount := p.Reader().GetLineCount()
+
+	firstVisible := 0
+	visibleCount := 0
+	if lineIndex := p.lineIndex(); lineIndex != nil {
+		firstVisible = lineIndex.Index()
+		lastVisible := p.getLastVisiblePosition()
+		if lastVisible != nil {
+			if lastIndex := lastVisible.lineIndex(p); lastIndex != nil {
+				visibleCount = lastIndex.Index() - firstVisible + 1
+			}
+		}
+	}
+
+	width, _ := p.screen.Size()
+	cells := scrollbarCells(p.visibleHeight(), lineCount, firstVisible, visibleCount, hits)
+	for row, cell := range cells {
+		p.screen.SetCell(width-1, row, cell)
+	}
+}
+
+// Render a scrollbar of the given height, one cell per row.
+//
+// The thumb shows which lines are visible, and rows with search hits get tick
+// marks.
+func scrollbarCells(height int, lineCount int, firstVisible int, visibleCount int, hits []int) []twin.StyledRune {
+	cells := make([]twin.StyledRune, height)
+	if height <= 0 {
+		return cells
+	}
+
+	thumbStart, thumbEnd := 0, height
+	if lineCount > 0 {
+		thumbStart = firstVisible * height / lineCount
+		thumbEnd = ceilDiv((firstVisible+visibleCount)*height, lineCount)
+		if firstVisible+visibleCount >= lineCount {
+			// Make sure the thumb reaches the bottom when we're at the end
+			thumbEnd = height
+		}
+		thumbStart = min(thumbStart, height-1)
+		thumbEnd = max(thumbEnd, thumbStart+1)
+	}
+
+	for row := range cells {
+		onThumb := row >= thumbStart && row < thumbEnd
+
+		hasHit := false
+		if lineCount > 0 {
+			// Line index i is shown on row i*height/lineCount
+			rowFirstLine := ceilDiv(row*lineCount, height)
+			rowEndLine := ceilDiv((row+1)*lineCount, height)
+			firstHitAtOrAfter := sort.SearchInts(hits, rowFirstLine)
+			hasHit = firstHitAtOrAfter < len(hits) && hits[firstHitAtOrAfter] < rowEndLine
+		}
+
+		switch {
+		case onThumb && hasHit:
+			cells[row] = twin.NewStyledRune('─', statusbarStyle)
+		case onThumb:
+			cells[row] = twin.NewStyledRune(' ', statusbarStyle)
+		case hasHit:
+			cells[row] = twin.NewStyledRune('─', plainTextStyle.WithAttr(twin.AttrBold))
+		default:
+			cells[row] = twin.NewStyledRune('│', lineNumbersStyle)
+		}
+	}
+
+	return cells
+}
+
+func ceilDiv(dividend int, divisor int) int {
+	return (dividend + divisor - 1) / divisor
+}
+
+// Scroll so that the given scrollbar row is at the top of the screen
+func (p *Pager) scrollToScrollbarRow(row int) {
+	lineCount := p.Reader().GetLineCount()
+	height := p.visibleHeight()
+	if lineCount == 0 || height <= 0 {
+		return
+	}
+
+	row = max(0, min(row, height-1))
+	p.scrollPosition = NewScrollPositionFromIndex(linemetadata.IndexFromZeroBased(row*lineCount/height), "Scrollbar")
+	p.setTargetLine(nil)
+}
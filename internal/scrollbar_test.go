@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func scrollbarToString(cells []twin.StyledRune) string {
+	returnMe := strings.Builder{}
+	for _, cell := range cells {
+		switch {
+		case cell.Rune == '─':
+			returnMe.WriteRune('-')
+		case cell.Style == statusbarStyle:
+			returnMe.WriteRune('#')
+		default:
+			returnMe.WriteRune('.')
+		}
+	}
+	return returnMe.String()
+}
+
+func TestScrollbarCells(t *testing.T) {
+	// Everything visible
+	assert.Equal(t, "#####", scrollbarToString(scrollbarCells(5, 3, 0, 3, nil)))
+
+	// Empty input
+	assert.Equal(t, "#####", scrollbarToString(scrollbarCells(5, 0, 0, 0, nil)))
+
+	// At the top, at the bottom and in the middle
+	assert.Equal(t, "##........", scrollbarToString(scrollbarCells(10, 100, 0, 20, nil)))
+	assert.Equal(t, "........##", scrollbarToString(scrollbarCells(10, 100, 80, 20, nil)))
+	assert.Equal(t, "....##....", scrollbarToString(scrollbarCells(10, 100, 40, 20, nil)))
+
+	// The thumb should always be visible, even for huge inputs
+	assert.Equal(t, "#.........", scrollbarToString(scrollbarCells(10, 1_000_000, 0, 10, nil)))
+	assert.Equal(t, ".........#", scrollbarToString(scrollbarCells(10, 1_000_000, 999_990, 10, nil)))
+}
+
+func TestScrollbarCellsHits(t *testing.T) {
+	assert.Equal(t,
+		"#-...-...-",
+		scrollbarToString(scrollbarCells(10, 100, 0, 10, []int{15, 55, 59, 99})))
+
+	// Hits on the thumb
+	assert.Equal(t,
+		"-#........",
+		scrollbarToString(scrollbarCells(10, 100, 0, 20, []int{0})))
+}
+
+func TestScrollbarDrawing(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", strings.Repeat("line\n", 100)))
+	pager.screen = twin.NewFakeScreen(20, 10)
+	pager.ShowScrollbar = true
+
+	pager.redraw("")
+
+	screen := pager.screen.(*twin.FakeScreen)
+	for row := 0; row < 9; row++ {
+		cell := screen.GetRow(row)[19]
+		if row == 0 {
+			assert.Equal(t, statusbarStyle, cell.Style)
+		} else {
+			assert.Equal(t, '│', cell.Rune, "row %d", row)
+		}
+	}
+
+	// Contents should make room for the scrollbar
+	assert.Equal(t, 19, pager.contentsWidth())
+}
+
+func TestScrollbarClick(t *testing.T) {
+	pager := newPagerForMouseTesting(t)
+	pager.ShowScrollbar = true
+
+	// Nine rows of scrollbar for 100 lines, so row 4 is line 44
+	pager.onMousePress(19, 4)
+	assert.Equal(t, 44, pager.lineIndex().Index())
+
+	pager.onMouseDrag(19, 0)
+	assert.Equal(t, 0, pager.lineIndex().Index())
+	assert.Assert(t, pager.mousePress.inScrollbar)
+	assert.Assert(t, pager.selection == nil)
+}
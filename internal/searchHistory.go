@@ -0,0 +1,207 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Don't remember more than this many search and filter expressions
+const searchHistoryMaxEntries = 500
+
+// Search and filter expressions the user has typed, shared between the search
+// and filter prompts.
+type searchHistory struct {
+	// Oldest first, no duplicates
+	entries []string
+
+	// If set, the history is loaded from and saved to this file. If not set,
+	// the history is in-memory only.
+	fileName string
+}
+
+// Where we keep the search history, following the XDG Base Directory
+// Specification:
+// https://specifications.freedesktop.org/basedir-spec/latest/#variables
+//
+// Returns an empty string if we can't figure out a good location.
+func searchHistoryFileName() string {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" || !filepath.IsAbs(stateHome) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			log.Debug("No home directory for the search history: ", err)
+			return ""
+		}
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+
+	return filepath.Join(stateHome, "moor", "search_history")
+}
+
+// Load the search history from the given file. A missing file just means the
+// history is empty.
+func loadSearchHistory(fileName string) *searchHistory {
+	history := &searchHistory{fileName: fileName}
+	if fileName == "" {
+		return history
+	}
+
+	contents, err := os.ReadFile(fileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Info("Failed to read search history from ", fileName, ": ", err)
+		}
+		return history
+	}
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		history.addEntryInMemory(line)
+	}
+
+	return history
+}
+
+// LoadSearchHistory makes the pager remember search and filter expressions
+// between runs. Without this call, the history lives only as long as the
+// pager.
+func (p *Pager) LoadSearchHistory() {
+	p.searchHistory = loadSearchHistory(searchHistoryFileName())
+}
+
+func (h *searchHistory) addEntryInMemory(entry string) {
+	if entry == "" {
+		return
+	}
+
+	// De-duplicate, only keep the most recent copy of each entry
+	for i, existing := range h.entries {
+		if existing == entry {
+			h.entries = append(h.entries[:i], h.entries[i+1:]...)
+			break
+		}
+	}
+
+	h.entries = append(h.entries, entry)
+
+	if len(h.entries) > searchHistoryMaxEntries {
+		h.entries = h.entries[len(h.entries)-searchHistoryMaxEntries:]
+	}
+}
+
+// Add an entry to the history, and save the history to disk if we have a file
+// for it.
+func (h *searchHistory) addEntry(entry string) {
+	if h == nil || entry == "" {
+		return
+	}
+
+	if h.fileName == "" {
+		h.addEntryInMemory(entry)
+		return
+	}
+
+	// Re-read the history before adding to it, in case some other moor
+	// instance has updated it since we started.
+	onDisk := loadSearchHistory(h.fileName)
+	onDisk.addEntryInMemory(entry)
+	h.entries = onDisk.entries
+
+	err := h.save()
+	if err != nil {
+		log.Info("Failed to save search history to ", h.fileName, ": ", err)
+	}
+}
+
+func (h *searchHistory) save() error {
+	err := os.MkdirAll(filepath.Dir(h.fileName), 0o700)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file and rename it into place, so that a concurrent
+	// reader never sees a half written history
+	tempFile, err := os.CreateTemp(filepath.Dir(h.fileName), ".search_history-*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tempFile.WriteString(strings.Join(h.entries, "\n") + "\n")
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempFile.Name())
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), h.fileName)
+}
+
+// Browses the search history from inside of a prompt. Only entries starting
+// with whatever the user had typed when starting to browse are shown.
+type historyRecall struct {
+	browsing bool
+
+	// What the user had typed before starting to browse the history
+	prefix string
+
+	// Index into the history entries of the entry being shown
+	index int
+}
+
+// Returns the closest older history entry matching our prefix. If there is
+// none, current is returned.
+func (r *historyRecall) older(history *searchHistory, current string) string {
+	if history == nil {
+		return current
+	}
+
+	if !r.browsing {
+		r.browsing = true
+		r.prefix = current
+		r.index = len(history.entries)
+	}
+
+	for i := r.index - 1; i >= 0; i-- {
+		entry := history.entries[i]
+		if entry == current || !strings.HasPrefix(entry, r.prefix) {
+			continue
+		}
+
+		r.index = i
+		return entry
+	}
+
+	return current
+}
+
+// Returns the closest newer history entry matching our prefix. After the
+// newest entry, whatever the user had typed before starting to browse is
+// returned.
+func (r *historyRecall) newer(history *searchHistory, current string) string {
+	if history == nil || !r.browsing {
+		return current
+	}
+
+	for i := r.index + 1; i < len(history.entries); i++ {
+		entry := history.entries[i]
+		if entry == current || !strings.HasPrefix(entry, r.prefix) {
+			continue
+		}
+
+		r.index = i
+		return entry
+	}
+
+	r.browsing = false
+	return r.prefix
+}
+
+// Call this when the user edits the prompt contents
+func (r *historyRecall) reset() {
+	r.browsing = false
+}
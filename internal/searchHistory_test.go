@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func TestSearchHistoryDeduplicates(t *testing.T) {
+	history := &searchHistory{}
+	history.addEntry("a")
+	history.addEntry("b")
+	history.addEntry("a")
+	history.addEntry("")
+
+	assert.DeepEqual(t, []string{"b", "a"}, history.entries)
+}
+
+func TestSearchHistoryMaxEntries(t *testing.T) {
+	history := &searchHistory{}
+	for i := 0; i < searchHistoryMaxEntries+10; i++ {
+		history.addEntry(strconv.Itoa(i))
+	}
+
+	assert.Equal(t, searchHistoryMaxEntries, len(history.entries))
+	assert.Equal(t, "10", history.entries[0])
+	assert.Equal(t, strconv.Itoa(searchHistoryMaxEntries+9), history.entries[len(history.entries)-1])
+}
+
+func TestSearchHistoryPersistence(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "moor", "search_history")
+
+	history := loadSearchHistory(fileName)
+	assert.Equal(t, 0, len(history.entries))
+
+	history.addEntry("first")
+	history.addEntry("second")
+
+	// Simulate some other moor instance adding an entry
+	other := loadSearchHistory(fileName)
+	other.addEntry("third")
+
+	history.addEntry("first")
+	assert.DeepEqual(t, []string{"second", "third", "first"}, history.entries)
+
+	reloaded := loadSearchHistory(fileName)
+	assert.DeepEqual(t, []string{"second", "third", "first"}, reloaded.entries)
+
+	contents, err := os.ReadFile(fileName)
+	assert.NilError(t, err)
+	assert.Equal(t, "second\nthird\nfirst\n", string(contents))
+}
+
+func TestSearchHistoryFileName(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/tmp/state")
+	assert.Equal(t, "/tmp/state/moor/search_history", searchHistoryFileName())
+
+	// Relative paths should be ignored according to the XDG spec
+	t.Setenv("XDG_STATE_HOME", "relative")
+	t.Setenv("HOME", "/home/johan")
+	assert.Equal(t, "/home/johan/.local/state/moor/search_history", searchHistoryFileName())
+}
+
+func TestHistoryRecallPrefix(t *testing.T) {
+	history := &searchHistory{}
+	history.addEntry("apa")
+	history.addEntry("bepa")
+	history.addEntry("apelsin")
+
+	recall := historyRecall{}
+	assert.Equal(t, "apelsin", recall.older(history, "ap"))
+	assert.Equal(t, "apa", recall.older(history, "apelsin"))
+	assert.Equal(t, "apa", recall.older(history, "apa"), "No more matches, stay put")
+	assert.Equal(t, "apelsin", recall.newer(history, "apa"))
+	assert.Equal(t, "ap", recall.newer(history, "apelsin"), "Back to what the user typed")
+	assert.Equal(t, "ap", recall.newer(history, "ap"))
+}
+
+func TestHistoryRecallNilHistory(t *testing.T) {
+	recall := historyRecall{}
+	assert.Equal(t, "x", recall.older(nil, "x"))
+	assert.Equal(t, "x", recall.newer(nil, "x"))
+}
+
+func TestSearchPromptRecallsHistory(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "a\nb\nc\n"))
+	pager.screen = twin.NewFakeScreen(20, 5)
+
+	// Search for "b" and then for "c"
+	for _, searchString := range []string{"b", "c"} {
+		pager.mode.onRune('/')
+		pager.mode.onRune(rune(searchString[0]))
+		pager.mode.onKey(twin.KeyEnter)
+		assert.Equal(t, "Viewing", modeName(pager))
+	}
+
+	pager.mode.onRune('/')
+	pager.mode.onKey(twin.KeyUp)
+	assert.Equal(t, "c", pager.searchString)
+	pager.mode.onKey(twin.KeyUp)
+	assert.Equal(t, "b", pager.searchString)
+	assert.Equal(t, "(?i)b", pager.searchPattern.String())
+	pager.mode.onKey(twin.KeyDown)
+	assert.Equal(t, "c", pager.searchString)
+	pager.mode.onKey(twin.KeyDown)
+	assert.Equal(t, "", pager.searchString)
+}
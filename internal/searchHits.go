@@ -0,0 +1,249 @@
+package internal
+
+import (
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+)
+
+// Posted when more search hits have been found
+type eventSearchHitsFound struct{}
+
+// Report progress after this many lines, so that the match count and the
+// scrollbar update while we're still searching a large input
+const searchHitsChunkSize = 100_000
+
+// If any of these change, the hits need to be searched for again
+type searchHitsKey struct {
+	reader        reader.Reader
+	backingReader *reader.ReaderImpl
+	searchPattern *regexp.Regexp
+	filterPattern *regexp.Regexp
+	filters       string
+}
+
+// Lines with search hits, and how many hits there are on each of those lines.
+// Used both for the "match 17/342" status bar text and for the scrollbar tick
+// marks.
+//
+// Searching is done in the background so that huge inputs don't slow down
+// redrawing.
+type _SearchHits struct {
+	lock sync.Mutex
+
+	key searchHitsKey
+
+	// Bumped on every restart, so that searches for old keys can tell their
+	// results aren't wanted any more
+	generation int
+
+	// Sorted zero based indices of lines with hits
+	hitLines []int
+
+	// Number of matches on all lines up to and including the corresponding
+	// hitLines entry
+	matchesUpTo []int
+
+	// Lines before this index have been searched
+	searchedLines int
+
+	searching bool
+}
+
+// Lines with matches in one part of the input, and how many matches there are
+// on each of those lines
+type lineMatches struct {
+	hitLines   []int
+	matchCount []int
+}
+
+// Start searching any lines that haven't been searched yet. When more hits are
+// found, an eventSearchHitsFound is posted on the events channel.
+//
+// Returns true if all lines have been searched.
+func (h *_SearchHits) update(key searchHitsKey, lineCount int, events chan twin.Event) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if key != h.key || lineCount < h.searchedLines {
+		// Something changed, start over
+		h.key = key
+		h.generation++
+		h.hitLines = nil
+		h.matchesUpTo = nil
+		h.searchedLines = 0
+		h.searching = false
+	}
+
+	if key.searchPattern == nil {
+		return true
+	}
+
+	if !h.searching && h.searchedLines < lineCount {
+		h.searching = true
+		go h.search(h.generation, h.searchedLines, lineCount, events)
+	}
+
+	return !h.searching
+}
+
+// Sorted zero based indices of the lines with hits found so far. Don't modify
+// the returned slice.
+func (h *_SearchHits) lines() []int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.hitLines
+}
+
+// The number of matches found so far
+func (h *_SearchHits) total() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.totalUnlocked()
+}
+
+func (h *_SearchHits) totalUnlocked() int {
+	if len(h.matchesUpTo) == 0 {
+		return 0
+	}
+	return h.matchesUpTo[len(h.matchesUpTo)-1]
+}
+
+// How many matches there are on the lines before the given line index
+func (h *_SearchHits) matchesBefore(lineIndex int) int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	hitsBefore := sort.SearchInts(h.hitLines, lineIndex)
+	if hitsBefore == 0 {
+		return 0
+	}
+	return h.matchesUpTo[hitsBefore-1]
+}
+
+// Search lines from firstIndex up to (but not including) lastIndex
+func (h *_SearchHits) search(generation int, firstIndex int, lastIndex int, events chan twin.Event) {
+	defer func() {
+		PanicHandler("searchHits.search()", recover(), debug.Stack())
+	}()
+
+	h.lock.Lock()
+	key := h.key
+	h.lock.Unlock()
+
+	log.Debugf("Searching lines %d-%d for hits...", firstIndex, lastIndex)
+	for chunkStart := firstIndex; chunkStart < lastIndex; chunkStart += searchHitsChunkSize {
+		chunkEnd := min(chunkStart+searchHitsChunkSize, lastIndex)
+		matches := countMatchesInParallel(key.reader, *key.searchPattern, chunkStart, chunkEnd)
+
+		h.lock.Lock()
+		if h.generation != generation {
+			// Nobody wants these hits any more
+			h.lock.Unlock()
+			return
+		}
+		total := h.totalUnlocked()
+		for i, hitLine := range matches.hitLines {
+			total += matches.matchCount[i]
+			h.hitLines = append(h.hitLines, hitLine)
+			h.matchesUpTo = append(h.matchesUpTo, total)
+		}
+		h.searchedLines = chunkEnd
+		if chunkEnd == lastIndex {
+			h.searching = false
+		}
+		h.lock.Unlock()
+
+		// Don't block if the events queue is full. In that case there will be
+		// a redraw soon anyway.
+		select {
+		case events <- eventSearchHitsFound{}:
+		default:
+		}
+	}
+}
+
+// Like findFirstHit(), divide the lines between the available cores
+func countMatchesInParallel(reader reader.Reader, pattern regexp.Regexp, firstIndex int, lastIndex int) lineMatches {
+	partCount := runtime.NumCPU()
+	if lastIndex-firstIndex < partCount {
+		partCount = 1
+	}
+	partSize := (lastIndex - firstIndex) / partCount
+
+	results := make([]chan lineMatches, partCount)
+	for i := range results {
+		results[i] = make(chan lineMatches, 1)
+
+		partStart := firstIndex + i*partSize
+		partEnd := partStart + partSize
+		if i == partCount-1 {
+			partEnd = lastIndex
+		}
+
+		go func(i int, partStart int, partEnd int) {
+			defer func() {
+				PanicHandler("countMatchesInParallel()/part", recover(), debug.Stack())
+			}()
+
+			results[i] <- countMatches(reader, pattern, partStart, partEnd)
+		}(i, partStart, partEnd)
+	}
+
+	// Concatenate the results in order
+	returnMe := lineMatches{}
+	for _, result := range results {
+		part := <-result
+		returnMe.hitLines = append(returnMe.hitLines, part.hitLines...)
+		returnMe.matchCount = append(returnMe.matchCount, part.matchCount...)
+	}
+
+	return returnMe
+}
+
+// Count matches from firstIndex up to (but not including) lastIndex
+func countMatches(reader reader.Reader, pattern regexp.Regexp, firstIndex int, lastIndex int) lineMatches {
+	returnMe := lineMatches{}
+	for index := firstIndex; index < lastIndex; index++ {
+		line := reader.GetLine(linemetadata.IndexFromZeroBased(index))
+		if line == nil {
+			// Input got shorter
+			break
+		}
+
+		matches := pattern.FindAllStringIndex(line.Plain(), -1)
+		if len(matches) == 0 {
+			continue
+		}
+
+		returnMe.hitLines = append(returnMe.hitLines, index)
+		returnMe.matchCount = append(returnMe.matchCount, len(matches))
+	}
+
+	return returnMe
+}
+
+// Start searching for hits for the current search, if needed. Returns true if
+// all lines have been searched.
+func (p *Pager) updateSearchHits() bool {
+	if p.searchHits == nil {
+		p.searchHits = &_SearchHits{}
+	}
+
+	return p.searchHits.update(searchHitsKey{
+		reader:        p.Reader(),
+		backingReader: p.reader,
+		searchPattern: p.searchPattern,
+		filterPattern: p.filterPattern,
+		filters:       filtersKey(p.filters()),
+	}, p.Reader().GetLineCount(), p.screen.Events())
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func TestCountMatches(t *testing.T) {
+	reader := reader.NewFromTextForTesting("", "apa apa\nbepa\ncepa apa\n")
+	pattern := regexp.MustCompile("apa")
+
+	matches := countMatches(reader, *pattern, 0, 3)
+	assert.DeepEqual(t, []int{0, 2}, matches.hitLines)
+	assert.DeepEqual(t, []int{2, 1}, matches.matchCount)
+
+	// Past the end
+	matches = countMatches(reader, *pattern, 1, 10)
+	assert.DeepEqual(t, []int{2}, matches.hitLines)
+}
+
+func TestCountMatchesInParallel(t *testing.T) {
+	reader := reader.NewFromTextForTesting("", strings.Repeat("apa\nbepa\napa apa\n", 1000))
+	pattern := regexp.MustCompile("apa")
+
+	parallel := countMatchesInParallel(reader, *pattern, 10, 2990)
+	sequential := countMatches(reader, *pattern, 10, 2990)
+	assert.DeepEqual(t, sequential.hitLines, parallel.hitLines)
+	assert.DeepEqual(t, sequential.matchCount, parallel.matchCount)
+}
+
+func TestSearchHits(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "apa\nbepa\ncepa apa\napa\n"))
+	pager.screen = twin.NewFakeScreen(20, 10)
+	pager.searchPattern = regexp.MustCompile("apa")
+
+	for !pager.updateSearchHits() {
+		// Wait for the search to finish
+	}
+	assert.DeepEqual(t, []int{0, 2, 3}, pager.searchHits.lines())
+	assert.Equal(t, 3, pager.searchHits.total())
+	assert.Equal(t, 1, pager.searchHits.matchesBefore(2))
+
+	// A new search should start over
+	pager.searchPattern = regexp.MustCompile("cepa")
+	for !pager.updateSearchHits() {
+		// Wait for the search to finish
+	}
+	assert.DeepEqual(t, []int{2}, pager.searchHits.lines())
+	assert.Equal(t, 1, pager.searchHits.total())
+}
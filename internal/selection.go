@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"math"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/twin"
+)
+
+// A position in the text, for selecting
+type selectionPoint struct {
+	lineIndex linemetadata.Index
+
+	// Index into the highlighted runes of the line. Might be past the end of
+	// the line.
+	runeIndex int
+}
+
+func (s selectionPoint) isBefore(other selectionPoint) bool {
+	if s.lineIndex != other.lineIndex {
+		return s.lineIndex.IsBefore(other.lineIndex)
+	}
+	return s.runeIndex < other.runeIndex
+}
+
+// Selected text, made either by dragging the mouse or in select mode.
+//
+// Both ends are inclusive, and the anchor can be either before or after the
+// cursor.
+type _Selection struct {
+	anchor selectionPoint
+	cursor selectionPoint
+
+	// Select whole lines, no matter the rune indices
+	lineWise bool
+}
+
+// Returns the first and last selected points, in that order
+func (s *_Selection) bounds() (selectionPoint, selectionPoint) {
+	first, last := s.anchor, s.cursor
+	if last.isBefore(first) {
+		first, last = last, first
+	}
+
+	if s.lineWise {
+		first.runeIndex = 0
+		last.runeIndex = math.MaxInt
+	}
+
+	return first, last
+}
+
+// Returns the runes selected in the given line as a from-to range. The to
+// index is exclusive, and might be past the end of the line. If nothing is
+// selected, from and to are equal.
+func (s *_Selection) selectedRange(lineIndex linemetadata.Index) (from int, to int) {
+	first, last := s.bounds()
+	if lineIndex.IsBefore(first.lineIndex) || last.lineIndex.IsBefore(lineIndex) {
+		return 0, 0
+	}
+
+	from = 0
+	if lineIndex == first.lineIndex {
+		from = first.runeIndex
+	}
+
+	to = math.MaxInt
+	if lineIndex == last.lineIndex && last.runeIndex < math.MaxInt {
+		to = last.runeIndex + 1
+	}
+
+	return from, to
+}
+
+// Returns a copy of the runes with the selected ones in reverse video. A nil
+// selection returns the runes unchanged.
+func (s *_Selection) highlight(lineIndex linemetadata.Index, runes []twin.StyledRune) []twin.StyledRune {
+	if s == nil {
+		return runes
+	}
+
+	from, to := s.selectedRange(lineIndex)
+	if from >= to || from >= len(runes) {
+		return runes
+	}
+	to = min(to, len(runes))
+
+	highlighted := make([]twin.StyledRune, len(runes))
+	copy(highlighted, runes)
+	for i := from; i < to; i++ {
+		highlighted[i].Style = highlighted[i].Style.WithAttr(twin.AttrReverse)
+	}
+
+	return highlighted
+}
+
+// The selected text, with lines separated by newlines
+func (p *Pager) selectedText() string {
+	if p.selection == nil {
+		return ""
+	}
+
+	first, last := p.selection.bounds()
+	lines := []string{}
+	for lineIndex := first.lineIndex; !last.lineIndex.IsBefore(lineIndex); lineIndex = lineIndex.NonWrappingAdd(1) {
+		line := p.Reader().GetLine(lineIndex)
+		if line == nil {
+			// Past the end of the input
+			break
+		}
+
+		// The plain text is what we want, but the selection is in highlighted
+		// runes. Those are usually the same, but if they aren't we copy what's
+		// on screen.
+		plain := []rune(line.Plain())
+		highlighted := line.HighlightedTokens(twin.StyleDefault, nil, nil, nil).StyledRunes
+
+		from, to := p.selection.selectedRange(lineIndex)
+		if from == 0 && to >= len(highlighted) {
+			lines = append(lines, string(plain))
+			continue
+		}
+
+		from = min(from, len(highlighted))
+		to = min(to, len(highlighted))
+		if len(plain) == len(highlighted) {
+			lines = append(lines, string(plain[from:to]))
+			continue
+		}
+
+		onScreen := strings.Builder{}
+		for _, styledRune := range highlighted[from:to] {
+			onScreen.WriteRune(styledRune.Rune)
+		}
+		lines = append(lines, onScreen.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Move the selection cursor, scrolling as needed to keep it visible
+func (p *Pager) moveSelectionCursor(delta int) {
+	lineCount := p.Reader().GetLineCount()
+	if lineCount == 0 {
+		return
+	}
+
+	cursor := p.selection.cursor.lineIndex.NonWrappingAdd(delta)
+	lastLine := linemetadata.IndexFromLength(lineCount)
+	if lastLine.IsBefore(cursor) {
+		cursor = *lastLine
+	}
+	p.selection.cursor.lineIndex = cursor
+
+	if cursor.IsBefore(*p.lineIndex()) {
+		p.scrollPosition = NewScrollPositionFromIndex(cursor, "Select cursor")
+		return
+	}
+
+	lastVisible := p.getLastVisiblePosition()
+	if lastVisible != nil && lastVisible.lineIndex(p).IsBefore(cursor) {
+		p.scrollPosition = p.scrollPosition.NextLine(lastVisible.lineIndex(p).CountLinesTo(cursor))
+	}
+}
+
+// Move the selection cursor sideways, scrolling as needed to keep it visible.
+// This switches from selecting whole lines to selecting characters.
+func (p *Pager) moveSelectionColumn(delta int) {
+	line := p.Reader().GetLine(p.selection.cursor.lineIndex)
+	if line == nil {
+		return
+	}
+
+	p.selection.lineWise = false
+	length := len(line.HighlightedTokens(twin.StyleDefault, nil, nil, nil).StyledRunes)
+	cursor := max(0, min(p.selection.cursor.runeIndex+delta, length-1))
+	p.selection.cursor.runeIndex = cursor
+
+	if p.WrapLongLines {
+		// Everything is already visible
+		return
+	}
+
+	visibleWidth := p.contentsWidth() - p.getLineNumberPrefixLength(line.Number)
+	if cursor < p.leftColumnZeroBased {
+		p.leftColumnZeroBased = cursor
+	} else if cursor >= p.leftColumnZeroBased+visibleWidth {
+		p.leftColumnZeroBased = cursor - visibleWidth + 1
+	}
+}
+
+// Put the selected text on the system clipboard
+func (p *Pager) copySelection() {
+	text := p.selectedText()
+	if text == "" {
+		return
+	}
+
+	clipboard, ok := p.screen.(twin.Clipboard)
+	if !ok {
+		log.Info("Screen has no clipboard support, not copying")
+		return
+	}
+
+	log.Debugf("Copying %d characters to the clipboard", len(text))
+	clipboard.SetClipboard(text)
+}
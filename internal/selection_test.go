@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+func TestSelectedText(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "abc\ndef\nghi"))
+	pager.screen = twin.NewFakeScreen(20, 10)
+
+	point := func(line int, runeIndex int) selectionPoint {
+		return selectionPoint{lineIndex: linemetadata.IndexFromZeroBased(line), runeIndex: runeIndex}
+	}
+
+	pager.selection = &_Selection{anchor: point(0, 1), cursor: point(0, 1)}
+	assert.Equal(t, "b", pager.selectedText())
+
+	// Backwards, from past the end of the last line
+	pager.selection = &_Selection{anchor: point(2, 10), cursor: point(0, 2)}
+	assert.Equal(t, "c\ndef\nghi", pager.selectedText())
+
+	pager.selection = &_Selection{anchor: point(1, 1), cursor: point(2, 0), lineWise: true}
+	assert.Equal(t, "def\nghi", pager.selectedText())
+}
+
+func TestSelectModeCopiesLines(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "one\ntwo\nthree\nfour"))
+	screen := twin.NewFakeScreen(20, 3)
+	pager.screen = screen
+
+	pager.mode.onRune('V')
+	pager.mode.onKey(twin.KeyDown)
+	pager.mode.onKey(twin.KeyDown)
+	pager.mode.onRune('k')
+	pager.mode.onRune('j')
+
+	// Three lines selected, with room for only two on screen. We should have
+	// scrolled to show the cursor.
+	assert.Equal(t, 1, pager.lineIndex().Index())
+
+	pager.mode.onRune('y')
+	assert.Equal(t, "one\ntwo\nthree", screen.Clipboard())
+	assert.Assert(t, pager.isViewing())
+	assert.Assert(t, pager.selection == nil)
+}
+
+func TestSelectModeCopiesCharacters(t *testing.T) {
+	pager := NewPager(reader.NewFromTextForTesting("", "one\ntwo\nthree"))
+	screen := twin.NewFakeScreen(20, 5)
+	pager.screen = screen
+
+	pager.mode.onRune('V')
+	pager.mode.onKey(twin.KeyRight)
+	pager.mode.onKey(twin.KeyDown)
+	pager.mode.onKey(twin.KeyDown)
+	pager.mode.onRune('l')
+	pager.mode.onRune('l')
+	pager.mode.onRune('h')
+	assert.Assert(t, !pager.selection.lineWise)
+
+	pager.mode.onKey(twin.KeyEnter)
+	assert.Equal(t, "one\ntwo\nthr", screen.Clipboard())
+}
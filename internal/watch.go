@@ -0,0 +1,100 @@
+package internal
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/twin"
+)
+
+// Follow the end of the input, and stop at the first new line matching the
+// current search
+func (p *Pager) startWatching() {
+	if p.searchPattern == nil {
+		p.showMessage("Search for something first, then watch for it")
+		return
+	}
+
+	p.watching = true
+	p.watchedPattern = p.searchPattern
+	p.watchedLineCount = p.Reader().GetLineCount()
+	p.watchedLastLine = p.lastWatchedLinePlain()
+
+	// Streams have no initial contents to wait for, everything arriving on
+	// them is new
+	p.watchCaughtUp = p.reader.FileName == nil || p.reader.Done.Load()
+
+	// Follow from the end, even if we were on our way somewhere else
+	p.setTargetLine(nil)
+	p.scrollToEnd()
+}
+
+// Watching stops when the user scrolls away or searches for something else
+func (p *Pager) isWatching() bool {
+	following := p.TargetLine != nil && *p.TargetLine == linemetadata.IndexMax()
+	return p.watching && following && p.searchPattern == p.watchedPattern && !p.isShowingHelp
+}
+
+// Look for search hits on lines that arrived since we last looked. On a hit,
+// stop following and show the hit at the bottom of the screen.
+func (p *Pager) checkWatchedLines() {
+	if !p.isWatching() {
+		p.watching = false
+		return
+	}
+
+	if !p.watchCaughtUp {
+		// Lines from the initial read of the file are not new, start
+		// watching after the last of them. Check for done before counting,
+		// so that the count includes all of them.
+		p.watchCaughtUp = p.reader.Done.Load()
+		p.watchedLineCount = p.Reader().GetLineCount()
+		p.watchedLastLine = p.lastWatchedLinePlain()
+		return
+	}
+
+	lineCount := p.Reader().GetLineCount()
+
+	firstIndex := min(p.watchedLineCount, lineCount)
+	if firstIndex > 0 && p.lastWatchedLinePlain() != p.watchedLastLine {
+		// The last line had no newline, and more text was appended to it
+		firstIndex--
+	}
+
+	for index := firstIndex; index < lineCount; index++ {
+		line := p.Reader().GetLine(linemetadata.IndexFromZeroBased(index))
+		if line == nil {
+			// Input got shorter
+			break
+		}
+
+		if !p.searchPattern.MatchString(line.Plain()) {
+			continue
+		}
+
+		log.Debug("Watched search hit found at index ", index)
+		p.watching = false
+		p.setTargetLine(nil)
+		p.scrollPosition = NewScrollPositionFromIndex(line.Index, "watchHit").PreviousLine(p.visibleHeight() - 1)
+		if bell, ok := p.screen.(twin.Bell); ok && p.WatchBell {
+			bell.Bell()
+		}
+		return
+	}
+
+	p.watchedLineCount = lineCount
+	p.watchedLastLine = p.lastWatchedLinePlain()
+}
+
+// The contents of the last line we have checked, for telling whether it has
+// changed since
+func (p *Pager) lastWatchedLinePlain() string {
+	if p.watchedLineCount == 0 {
+		return ""
+	}
+
+	line := p.Reader().GetLine(linemetadata.IndexFromZeroBased(p.watchedLineCount - 1))
+	if line == nil {
+		return ""
+	}
+	return line.Plain()
+}
@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/walles/moor/v2/internal/linemetadata"
+	"github.com/walles/moor/v2/internal/reader"
+	"github.com/walles/moor/v2/twin"
+	"gotest.tools/v3/assert"
+)
+
+// Write some lines to the pipe, and wait for the reader to get them
+func writeLines(t *testing.T, pipe *io.PipeWriter, r *reader.ReaderImpl, lines ...string) {
+	t.Helper()
+
+	expectedCount := r.GetLineCount() + len(lines)
+	_, err := pipe.Write([]byte(strings.Join(lines, "\n") + "\n"))
+	assert.NilError(t, err)
+
+	for r.GetLineCount() < expectedCount {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Create a pager on a five lines screen, reading eleven lines from a pipe that
+// more lines can be written to
+func newWatchedPager(t *testing.T) (*Pager, *io.PipeWriter, *twin.FakeScreen) {
+	pipeReader, pipeWriter := io.Pipe()
+	t.Cleanup(func() { _ = pipeWriter.Close() })
+
+	// Written in the background, since creating the reader waits for the
+	// first bytes
+	go func() {
+		_, _ = pipeWriter.Write([]byte(strings.Repeat("old line\n", 10) + "old ERROR\n"))
+	}()
+
+	r, err := reader.NewFromStream("", pipeReader, nil, reader.ReaderOptions{})
+	assert.NilError(t, err)
+	for r.GetLineCount() < 11 {
+		time.Sleep(time.Millisecond)
+	}
+
+	screen := twin.NewFakeScreen(20, 5)
+	pager := NewPager(r)
+	pager.ShowLineNumbers = false
+	pager.screen = screen
+	pager.mode = PagerModeViewing{pager: pager}
+
+	return pager, pipeWriter, screen
+}
+
+func TestWatchStopsAtNewHit(t *testing.T) {
+	pager, pipe, screen := newWatchedPager(t)
+	pager.WatchBell = true
+	pager.searchString = "ERROR"
+	pager.searchPattern = toPattern(pager.searchString)
+
+	pager.startWatching()
+	assert.Assert(t, pager.watching)
+
+	// No hits, keep following
+	writeLines(t, pipe, pager.reader, "new line")
+	pager.checkWatchedLines()
+	assert.Assert(t, pager.watching)
+	assert.Assert(t, pager.TargetLine != nil)
+
+	// The first new hit should be the last line on screen
+	writeLines(t, pipe, pager.reader, "new line", "new ERROR 1", "new line", "new ERROR 2")
+	pager.checkWatchedLines()
+	assert.Assert(t, !pager.watching)
+	assert.Assert(t, pager.TargetLine == nil)
+	assert.Equal(t, 1, screen.Bells())
+
+	pager.redraw("")
+	assert.Equal(t, "new ERROR 1", rowToString(screen.GetRow(3)))
+}
+
+func TestWatchWithoutSearch(t *testing.T) {
+	pager, _, screen := newWatchedPager(t)
+
+	pager.startWatching()
+	assert.Assert(t, !pager.watching)
+
+	pager.redraw("")
+	assert.Equal(t, "Search for something", rowToString(screen.GetRow(4)))
+}
+
+func TestWatchStopsWhenScrollingUp(t *testing.T) {
+	pager, pipe, screen := newWatchedPager(t)
+	pager.searchString = "ERROR"
+	pager.searchPattern = toPattern(pager.searchString)
+
+	pager.startWatching()
+	pager.handleScrolledUp()
+
+	writeLines(t, pipe, pager.reader, "new ERROR")
+	pager.checkWatchedLines()
+	assert.Assert(t, !pager.watching)
+	assert.Equal(t, 0, screen.Bells())
+}
+
+// A hit arriving in two parts, with the first part on a last line without any
+// newline, should be found once the line is complete
+func TestWatchSplitLine(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "split.log")
+	file, err := os.Create(fileName)
+	assert.NilError(t, err)
+	defer file.Close() //nolint:errcheck
+
+	_, err = file.WriteString("old line\nnew ERR")
+	assert.NilError(t, err)
+
+	r, err := reader.NewFromFilename(fileName, nil, reader.ReaderOptions{Style: styles.Get("native")})
+	assert.NilError(t, err)
+	assert.NilError(t, r.Wait())
+
+	screen := twin.NewFakeScreen(20, 5)
+	pager := NewPager(r)
+	pager.screen = screen
+	pager.WatchBell = true
+	pager.searchString = "ERROR"
+	pager.searchPattern = toPattern(pager.searchString)
+
+	pager.startWatching()
+	pager.checkWatchedLines()
+	assert.Assert(t, pager.watching)
+
+	_, err = file.WriteString("OR\n")
+	assert.NilError(t, err)
+	for r.GetLine(linemetadata.IndexFromZeroBased(1)).Plain() != "new ERROR" {
+		time.Sleep(time.Millisecond)
+	}
+
+	pager.checkWatchedLines()
+	assert.Assert(t, !pager.watching)
+	assert.Equal(t, 1, screen.Bells())
+}
+
+// Filtering changes the search, that should stop watching
+func TestWatchStopsOnNewSearch(t *testing.T) {
+	pager, pipe, _ := newWatchedPager(t)
+	pager.searchString = "ERROR"
+	pager.searchPattern = toPattern(pager.searchString)
+
+	pager.startWatching()
+	assert.Assert(t, pager.isWatching())
+
+	pager.searchPattern = toPattern("WARN")
+	assert.Assert(t, !pager.isWatching())
+
+	writeLines(t, pipe, pager.reader, "new ERROR")
+	pager.checkWatchedLines()
+	assert.Assert(t, !pager.watching)
+	assert.Assert(t, pager.TargetLine != nil, "Should still be following")
+}
+
+// Lines read from the file after watching started, but that were in the file
+// all along, are not new
+func TestWatchWhileReadingFile(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "reading.log")
+	file, err := os.Create(fileName)
+	assert.NilError(t, err)
+	defer file.Close() //nolint:errcheck
+
+	_, err = file.WriteString("old line\nold ERROR\n")
+	assert.NilError(t, err)
+
+	// Without a style, the reader won't be done until we set one
+	r, err := reader.NewFromFilename(fileName, nil, reader.ReaderOptions{})
+	assert.NilError(t, err)
+
+	screen := twin.NewFakeScreen(20, 5)
+	pager := NewPager(r)
+	pager.screen = screen
+	pager.searchString = "ERROR"
+	pager.searchPattern = toPattern(pager.searchString)
+
+	for r.GetLineCount() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	pager.startWatching()
+
+	// Pretend watching started when only the first line had been read
+	pager.watchedLineCount = 1
+	pager.watchedLastLine = "old line"
+
+	pager.checkWatchedLines()
+	assert.Assert(t, pager.watching, "Old hit should not stop watching")
+
+	r.SetStyleForHighlighting(*styles.Get("native"))
+	assert.NilError(t, r.Wait())
+	pager.checkWatchedLines()
+	assert.Assert(t, pager.watching)
+
+	_, err = file.WriteString("new ERROR\n")
+	assert.NilError(t, err)
+	for r.GetLineCount() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	pager.checkWatchedLines()
+	assert.Assert(t, !pager.watching)
+}
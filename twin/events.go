@@ -24,10 +24,40 @@ const (
 	MouseWheelDown
 	MouseWheelLeft
 	MouseWheelRight
+	MouseButtonLeft
+	MouseButtonMiddle
+	MouseButtonRight
+)
+
+type MouseModifierMask uint8
+
+const (
+	MouseModifierShift MouseModifierMask = 1 << iota
+	MouseModifierAlt
+	MouseModifierCtrl
+)
+
+type MouseAction int
+
+const (
+	// Buttons were pressed, or a wheel was turned
+	MousePress MouseAction = iota
+
+	// Buttons were released
+	MouseRelease
+
+	// The mouse was moved while buttons were held down
+	MouseDrag
 )
 
 type EventMouse struct {
-	buttons MouseButtonMask
+	buttons   MouseButtonMask
+	action    MouseAction
+	modifiers MouseModifierMask
+
+	// Zero based screen coordinates
+	column int
+	row    int
 }
 
 // After you get this, query Screen.Size() to get the new size
@@ -54,3 +84,16 @@ func (eventKeyCode *EventKeyCode) KeyCode() KeyCode {
 func (eventMouse *EventMouse) Buttons() MouseButtonMask {
 	return eventMouse.buttons
 }
+
+func (eventMouse *EventMouse) Action() MouseAction {
+	return eventMouse.action
+}
+
+func (eventMouse *EventMouse) Modifiers() MouseModifierMask {
+	return eventMouse.modifiers
+}
+
+// Zero based screen coordinates of the mouse pointer
+func (eventMouse *EventMouse) Position() (column int, row int) {
+	return eventMouse.column, eventMouse.row
+}
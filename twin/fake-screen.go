@@ -7,6 +7,9 @@ type FakeScreen struct {
 	width  int
 	height int
 	cells  [][]StyledRune
+
+	clipboard string
+	bells     int
 }
 
 func NewFakeScreen(width int, height int) *FakeScreen {
@@ -84,6 +87,24 @@ func (screen *FakeScreen) RequestTerminalBackgroundColor() {
 	// This method intentionally left blank
 }
 
+func (screen *FakeScreen) SetClipboard(text string) {
+	screen.clipboard = text
+}
+
+// Whatever was last passed to SetClipboard()
+func (screen *FakeScreen) Clipboard() string {
+	return screen.clipboard
+}
+
+func (screen *FakeScreen) Bell() {
+	screen.bells++
+}
+
+// How many times Bell() has been called
+func (screen *FakeScreen) Bells() int {
+	return screen.bells
+}
+
 func (screen *FakeScreen) ShowCursorAt(_ int, _ int) {
 	// This method intentionally left blank
 }
@@ -23,6 +23,10 @@ const (
 	KeyEnd
 	KeyPgUp
 	KeyPgDown
+
+	KeyAltC
+	KeyAltR
+	KeyAltW
 )
 
 // Map incoming escape keystrokes to keycodes, used in consumeEncodedEvent() in
@@ -74,4 +78,8 @@ var escapeSequenceToKeyCode = map[string]KeyCode{
 	"\x1b[4~": KeyEnd,
 	"\x1b[5~": KeyPgUp,
 	"\x1b[6~": KeyPgDown,
+
+	"\x1bc": KeyAltC,
+	"\x1br": KeyAltR,
+	"\x1bw": KeyAltW,
 }
@@ -2,6 +2,7 @@
 package twin
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
 	"regexp"
@@ -76,6 +77,25 @@ type Screen interface {
 	Events() chan Event
 }
 
+// Screens that can put text on the system clipboard implement this. Check for
+// it using a type assertion:
+//
+//	if clipboard, ok := screen.(twin.Clipboard); ok { ... }
+type Clipboard interface {
+	// SetClipboard() asks the terminal to put some text on the system
+	// clipboard, using OSC 52. This works over SSH as well, but some terminals
+	// don't support it or have it disabled.
+	SetClipboard(text string)
+}
+
+// Screens that can ring a bell implement this. Check for it using a type
+// assertion, like with Clipboard.
+type Bell interface {
+	// Bell() rings the terminal bell. Depending on the terminal, that could
+	// mean a sound, a flash or a notification.
+	Bell()
+}
+
 type interruptableReader interface {
 	Read(p []byte) (n int, err error)
 
@@ -106,15 +126,19 @@ type UnixScreen struct {
 	terminalColorCount ColorCount
 }
 
-// Example event: "\x1b[<65;127;41M"
+// SGR encoded mouse event. Example event: "\x1b[<65;127;41M"
 //
 // Where:
 //   - "\x1b[<" says this is a mouse event
-//   - "65" says this is Wheel Up. "64" would be Wheel Down.
+//   - "65" says this is Wheel Down. "64" would be Wheel Up. See
+//     decodeMouseEvent() for details.
 //   - "127" is the column number on screen, "1" is the first column.
 //   - "41" is the row number on screen, "1" is the first row.
-//   - "M" marks the end of the mouse event.
-var mouseEventRegex = regexp.MustCompile("^\x1b\\[<([0-9]+);([0-9]+);([0-9]+)M")
+//   - "M" marks the end of a press or drag event, "m" marks the end of a
+//     release event.
+//
+// Ref: https://invisible-island.net/xterm/ctlseqs/ctlseqs.html#h2-Extended-coordinates
+var mouseEventRegex = regexp.MustCompile("^\x1b\\[<([0-9]+);([0-9]+);([0-9]+)([Mm])")
 
 // NewScreen() requires Close() to be called after you are done with your new
 // screen, most likely somewhere in your shutdown code.
@@ -376,11 +400,13 @@ func terminalHasArrowKeysEmulation() bool {
 	return false
 }
 
+// 1000 = report button presses and releases, 1002 = also report moves while a
+// button is held down, 1006 = SGR encoding, see mouseEventRegex.
 func (screen *UnixScreen) enableMouseTracking(enable bool) {
 	if enable {
-		screen.write("\x1b[?1006;1000h")
+		screen.write("\x1b[?1006;1000;1002h")
 	} else {
-		screen.write("\x1b[?1006;1000l")
+		screen.write("\x1b[?1006;1000;1002l")
 	}
 }
 
@@ -528,12 +554,9 @@ func consumeEncodedEvent(encodedEventSequences string) (*Event, string) {
 
 	mouseMatch := mouseEventRegex.FindStringSubmatch(encodedEventSequences)
 	if mouseMatch != nil {
-		if mouseMatch[1] == "64" {
-			var event Event = EventMouse{buttons: MouseWheelUp}
-			return &event, strings.TrimPrefix(encodedEventSequences, mouseMatch[0])
-		}
-		if mouseMatch[1] == "65" {
-			var event Event = EventMouse{buttons: MouseWheelDown}
+		mouseEvent := decodeMouseEvent(mouseMatch[1], mouseMatch[2], mouseMatch[3], mouseMatch[4] == "m")
+		if mouseEvent != nil {
+			var event Event = *mouseEvent
 			return &event, strings.TrimPrefix(encodedEventSequences, mouseMatch[0])
 		}
 
@@ -577,6 +600,66 @@ func consumeEncodedEvent(encodedEventSequences string) (*Event, string) {
 	return &event, string(runes[1:])
 }
 
+// Decode the numbers of an SGR mouse event, see mouseEventRegex.
+//
+// The low two bits of the button code say which button, 4 is Shift, 8 is Alt,
+// 16 is Ctrl, 32 means the mouse moved and 64 means this is a wheel event.
+//
+// Returns nil if we don't understand the event.
+func decodeMouseEvent(buttonCode string, column string, row string, released bool) *EventMouse {
+	code, err := strconv.Atoi(buttonCode)
+	if err != nil {
+		return nil
+	}
+	columnNumber, err := strconv.Atoi(column)
+	if err != nil || columnNumber < 1 {
+		return nil
+	}
+	rowNumber, err := strconv.Atoi(row)
+	if err != nil || rowNumber < 1 {
+		return nil
+	}
+
+	event := EventMouse{
+		action: MousePress,
+		column: columnNumber - 1,
+		row:    rowNumber - 1,
+	}
+
+	if code&4 != 0 {
+		event.modifiers |= MouseModifierShift
+	}
+	if code&8 != 0 {
+		event.modifiers |= MouseModifierAlt
+	}
+	if code&16 != 0 {
+		event.modifiers |= MouseModifierCtrl
+	}
+
+	if code&64 != 0 {
+		if code&128 != 0 || code&32 != 0 {
+			// Extra buttons or moving with a wheel, we don't support those
+			return nil
+		}
+		event.buttons = []MouseButtonMask{MouseWheelUp, MouseWheelDown, MouseWheelLeft, MouseWheelRight}[code&3]
+		return &event
+	}
+
+	if code&3 == 3 {
+		// Moving without any button held down, we didn't ask for those
+		return nil
+	}
+	event.buttons = []MouseButtonMask{MouseButtonLeft, MouseButtonMiddle, MouseButtonRight}[code&3]
+
+	if released {
+		event.action = MouseRelease
+	} else if code&32 != 0 {
+		event.action = MouseDrag
+	}
+
+	return &event
+}
+
 // Returns screen width and height.
 //
 // NOTE: Never cache this response! On window resizes you'll get an EventResize
@@ -633,6 +716,16 @@ func (screen *UnixScreen) RequestTerminalBackgroundColor() {
 	fmt.Println("\x1b]11;?\x07")
 }
 
+func (screen *UnixScreen) SetClipboard(text string) {
+	// "c" is for "clipboard". Ref:
+	// https://invisible-island.net/xterm/ctlseqs/ctlseqs.html#h3-Operating-System-Commands
+	screen.write("\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x07")
+}
+
+func (screen *UnixScreen) Bell() {
+	screen.write("\a")
+}
+
 func parseTerminalBgColorResponse(responseBytes []byte) (*Color, bool) {
 	prefix := "\x1b]11;rgb:"
 	suffix1 := "\x07"
@@ -32,8 +32,11 @@ func TestConsumeEncodedEvent(t *testing.T) {
 	// Implicitly test having a remaining rune at the end
 	assertEncode(t, "\x1b[Ax", EventKeyCode{keyCode: KeyUp}, "x")
 
-	assertEncode(t, "\x1b[<64;127;41M", EventMouse{buttons: MouseWheelUp}, "")
-	assertEncode(t, "\x1b[<65;127;41M", EventMouse{buttons: MouseWheelDown}, "")
+	// Alt + letter
+	assertEncode(t, "\x1brx", EventKeyCode{keyCode: KeyAltR}, "x")
+
+	assertEncode(t, "\x1b[<64;127;41M", EventMouse{buttons: MouseWheelUp, column: 126, row: 40}, "")
+	assertEncode(t, "\x1b[<65;127;41M", EventMouse{buttons: MouseWheelDown, column: 126, row: 40}, "")
 
 	// This happens when users paste.
 	//
@@ -41,6 +44,29 @@ func TestConsumeEncodedEvent(t *testing.T) {
 	assertEncode(t, "1234", EventRune{rune: '1'}, "234")
 }
 
+func TestConsumeEncodedMouseEvent(t *testing.T) {
+	assertEncode(t, "\x1b[<0;1;2Mx", EventMouse{buttons: MouseButtonLeft, column: 0, row: 1}, "x")
+	assertEncode(t, "\x1b[<0;1;2m", EventMouse{buttons: MouseButtonLeft, action: MouseRelease, column: 0, row: 1}, "")
+	assertEncode(t, "\x1b[<2;10;5M", EventMouse{buttons: MouseButtonRight, column: 9, row: 4}, "")
+	assertEncode(t, "\x1b[<32;3;4M", EventMouse{buttons: MouseButtonLeft, action: MouseDrag, column: 2, row: 3}, "")
+
+	// Shift + Ctrl + middle button
+	assertEncode(t, "\x1b[<21;5;6M", EventMouse{
+		buttons:   MouseButtonMiddle,
+		modifiers: MouseModifierShift | MouseModifierCtrl,
+		column:    4,
+		row:       5,
+	}, "")
+
+	// Alt + wheel right
+	assertEncode(t, "\x1b[<75;1;1M", EventMouse{buttons: MouseWheelRight, modifiers: MouseModifierAlt}, "")
+
+	// Moving without any buttons held down, we never asked for those
+	event, remainder := consumeEncodedEvent("\x1b[<35;1;1M")
+	assert.Assert(t, event == nil)
+	assert.Equal(t, remainder, "")
+}
+
 func TestConsumeEncodedEventWithUnsupportedEscapeCode(t *testing.T) {
 	event, remainder := consumeEncodedEvent("\x1bXXXXX")
 	assert.Assert(t, event == nil)